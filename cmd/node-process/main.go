@@ -0,0 +1,112 @@
+//go:generate go run generate_main.go
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"process-exporter/collector"
+	"process-exporter/fullscan"
+	"process-exporter/internal/logging"
+	"process-exporter/internal/web"
+	"process-exporter/remote"
+)
+
+// node-process 已废弃，是 process-exporter -profile=full -names.match-mode=exact -names.normalize 的别名
+// 只保留原有的参数名（-collector.openfiles 对应 -collector.fds），指标由 collector.NewFullProfileHandler 输出，与 full 模式完全相同
+func main() {
+	namesFlag := flag.String("names", "", "comma-separated process names to include")
+	nameModeFlag := flag.String("names.match-mode", "exact", "how -names are matched against process names (lowercased, without .exe): exact, prefix, substring or regex")
+	addrFlag := flag.String("addr", ":9002", "listen address, e.g. :9002, or unix:///run/node-process.sock for a Unix domain socket")
+	webConfigFlag := flag.String("web.config.file", "", "path to web config file enabling TLS and/or authentication")
+	systemdSocketFlag := flag.Bool("web.systemd-socket", false, "use the listening socket(s) passed by systemd socket activation instead of -addr")
+	shutdownTimeoutFlag := flag.Duration("web.shutdown-timeout", 10*time.Second, "on SIGTERM or SIGINT, how long to wait for in-flight scrapes to finish before exiting")
+	var enabled fullscan.Collectors
+	flag.BoolVar(&enabled.CPU, "collector.cpu", true, "enable the CPU usage collector")
+	flag.BoolVar(&enabled.Memory, "collector.memory", true, "enable the memory usage collector")
+	flag.BoolVar(&enabled.OpenFiles, "collector.openfiles", true, "enable the open files collector, expensive for processes with many files")
+	flag.BoolVar(&enabled.IO, "collector.io", true, "enable the disk IO collector")
+	sshConfigFlag := flag.String("ssh.config.file", "", "path to a YAML file listing remote hosts collected over SSH and exposed on /remote/metrics")
+	winrmConfigFlag := flag.String("winrm.config.file", "", "path to a YAML file listing remote Windows hosts collected over WinRM and exposed on /remote/metrics")
+	cmdRulesFlag := flag.String("cmd.rules.file", "", "path to a YAML file with rewrite rules (replace, redact, max_args, max_length, hash) applied to the cmd label, e.g. to strip passwords from command lines")
+	cmdMaxLengthFlag := flag.Int("cmd.max-length", 0, "truncate the cmd label to this many characters, 0 keeps the whole command line; overrides max_length in -cmd.rules.file")
+	cmdHashFlag := flag.Bool("cmd.hash", false, "append a short hash of the full command line to the cmd label so that truncated values still tell invocations apart")
+	staticTTLFlag := flag.Duration("cache.static-ttl", fullscan.DefaultCacheTTLs.Static, "how long the name, command line and executable path of a process are cached between scrapes, keyed by PID and start time; 0 caches them until the process exits, negative disables caching")
+	slowTTLFlag := flag.Duration("cache.slow-ttl", fullscan.DefaultCacheTTLs.Slow, "how long the user and cgroups of a process are cached between scrapes; 0 caches them until the process exits, negative disables caching")
+	userCacheTTLFlag := flag.Duration("user-cache.ttl", 5*time.Minute, "how long a resolved uid to username mapping is cached")
+	selfMetricsFlag := flag.Bool("self-metrics", false, "also expose Go runtime and process metrics of the exporter itself")
+	logConfig := logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if err := logConfig.Setup(); err != nil {
+		logging.Fatal("Invalid logging flags", "err", err)
+	}
+
+	cmdRewriter, err := fullscan.NewCmdRewriter(*cmdRulesFlag, *cmdMaxLengthFlag, *cmdHashFlag)
+	if err != nil {
+		logging.Fatal("Failed to load cmd rewrite rules", "err", err)
+	}
+
+	slog.Warn("node-process is deprecated, use process-exporter -profile=full -names.match-mode=exact -names.normalize instead")
+
+	// node-process 的 -names 总是忽略大小写和 .exe 后缀
+	opts := collector.Options{
+		NameMode:       *nameModeFlag,
+		NormalizeNames: true,
+		Collectors: map[string]bool{
+			"cpu":    enabled.CPU,
+			"memory": enabled.Memory,
+			"fds":    enabled.OpenFiles,
+			"io":     enabled.IO,
+		},
+	}
+	for _, name := range strings.Split(*namesFlag, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			opts.Names = append(opts.Names, name)
+		}
+	}
+	reloader, err := collector.NewReloader(opts)
+	if err != nil {
+		logging.Fatal("Invalid -names", "err", err)
+	}
+	handler, _, err := collector.NewFullProfileHandler(reloader, opts, collector.FullProfileOptions{
+		UserCacheTTL: *userCacheTTLFlag,
+		CacheTTLs:    fullscan.CacheTTLs{Static: *staticTTLFlag, Slow: *slowTTLFlag},
+		SelfMetrics:  *selfMetricsFlag,
+		Cmd:          cmdRewriter,
+	})
+	if err != nil {
+		logging.Fatal("Error creating metrics handler", "err", err)
+	}
+
+	http.Handle("/metrics", handler)
+
+	// 远程主机的指标带有 host 标签，与本机指标的标签不同，单独通过 /remote/metrics 输出
+	if *sshConfigFlag != "" || *winrmConfigFlag != "" {
+		remoteCollector, err := remote.Load(*sshConfigFlag, *winrmConfigFlag)
+		if err != nil {
+			logging.Fatal("Failed to prepare remote collection", "err", err)
+		}
+		remoteRegistry := prometheus.NewRegistry()
+		remoteCollector.SetCmdRewriter(cmdRewriter)
+		remoteRegistry.MustRegister(remoteCollector)
+		http.Handle("/remote/metrics", promhttp.HandlerFor(remoteRegistry, promhttp.HandlerOpts{
+			ErrorHandling: promhttp.ContinueOnError,
+		}))
+	}
+
+	addr := *addrFlag
+	slog.Info("Service started", "addr", addr)
+
+	// 启动 HTTP 服务，配置了 TLS 时使用 HTTPS
+	server := &http.Server{Addr: addr}
+	if err := web.ServeUntilSignal(server, *webConfigFlag, *systemdSocketFlag, *shutdownTimeoutFlag, nil); err != nil {
+		logging.Fatal("HTTP server failed", "err", err)
+	}
+	slog.Info("Service stopped")
+}
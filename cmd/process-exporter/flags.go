@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+// repeatedFlag 可以重复指定的字符串参数，例如 -pidfile=a.pid -pidfile=b.pid
+type repeatedFlag []string
+
+func (f *repeatedFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *repeatedFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// flagSet 判断命令行中是否显式指定了参数，用于按 ncabatoff 的习惯调整默认值
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"process-exporter/internal/procfs"
+	"process-exporter/internal/sysfs"
+)
+
+// setHostPaths 设置 procfs、sysfs 和宿主机根文件系统的挂载位置
+// rootfs 不是 / 时，未显式指定的 procfs 和 sysfs 默认为其下的 proc 和 sys，
+// 同时设置 gopsutil 读取 /etc、/var、/run、/dev 使用的 HOST_* 环境变量
+func setHostPaths(procfsPath, sysfsPath, rootfsPath string, procfsSet, sysfsSet bool) error {
+	if rootfsPath != "/" {
+		if !procfsSet {
+			procfsPath = filepath.Join(rootfsPath, "proc")
+		}
+		if !sysfsSet {
+			sysfsPath = filepath.Join(rootfsPath, "sys")
+		}
+		for env, dir := range map[string]string{"HOST_ROOT": "", "HOST_ETC": "etc", "HOST_VAR": "var", "HOST_RUN": "run", "HOST_DEV": "dev"} {
+			if err := os.Setenv(env, filepath.Join(rootfsPath, dir)); err != nil {
+				return err
+			}
+		}
+	}
+	// gopsutil 和 exporter 自己读取的 /proc、/sys 文件都以 HOST_PROC、HOST_SYS 为根目录
+	if procfsPath != "/proc" {
+		if err := procfs.SetRoot(procfsPath); err != nil {
+			return err
+		}
+	}
+	if sysfsPath != "/sys" {
+		if err := sysfs.SetRoot(sysfsPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inContainer 粗略判断 exporter 是否运行在 Docker 或 Podman 容器中
+func inContainer() bool {
+	for _, path := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"process-exporter/collector"
+	"process-exporter/fullscan"
+	"process-exporter/internal/logging"
+	"process-exporter/internal/remotewrite"
+	"process-exporter/internal/web"
+	"process-exporter/remote"
+)
+
+func main() {
+	defaults := collector.DefaultOptions()
+	addr := flag.String("addr", ":9002", "The address to listen on for HTTP requests. Use unix:///path/to/socket to listen on a Unix domain socket instead of a TCP port.")
+	procNames := flag.String("names", "", "Comma separated list of process names to monitor.")
+	nameMode := flag.String("names.match-mode", defaults.NameMode, "How process names from -names, names and group names in -config.file are matched: exact, prefix, substring or regex. Rules in a group's match can override it with name_mode.")
+	normalizeNames := flag.Bool("names.normalize", false, "Compare process names case-insensitively and ignore a trailing .exe, like node-process does, so that the same -names value matches on Linux and Windows.")
+	excludeNames := flag.String("exclude.names", "", "Comma separated list of values; processes matched by -names, -config.file or -config.path whose name or command line contains any of them are not monitored, e.g. -names=python -exclude.names=some-vendor-agent. Compared case-insensitively and ignoring a trailing .exe with -names.normalize.")
+	var pidFiles repeatedFlag
+	flag.Var(&pidFiles, "pidfile", "Path to a PID file written by a daemon, e.g. /var/run/myapp.pid. The process whose PID it contains is monitored as a group named after the file without the .pid suffix; the file is re-read on every refresh. Can be repeated.")
+	services := flag.String("services", "", "Comma separated list of Windows service names (e.g. MSSQLSERVER,W3SVC) to monitor. The service process is looked up in the service control manager on every refresh. Windows only.")
+	configFile := flag.String("config.file", "", "Path to a YAML config file with process names to monitor. Reloaded on SIGHUP.")
+	// 以下三个参数与 ncabatoff/process-exporter 同名同义，可以直接替换其二进制而不修改部署参数
+	configPath := flag.String("config.path", "", "Path to a config file in the ncabatoff/process-exporter format (process_names with comm/exe/cmdline matchers and name templates). Reloaded on SIGHUP.")
+	procfsPath := flag.String("procfs", "/proc", "Path to read proc data from, e.g. the host's /proc mounted into a container.")
+	flag.StringVar(procfsPath, "path.procfs", "/proc", "Alias of -procfs, like node_exporter.")
+	children := flag.Bool("children", false, "Count processes that match no group as part of the group of their nearest matched ancestor. Defaults to true when -config.path is set, like ncabatoff/process-exporter.")
+	webConfig := flag.String("web.config.file", "", "Path to a web config file that enables TLS and/or authentication.")
+	systemdSocket := flag.Bool("web.systemd-socket", false, "Use the listening socket(s) passed by systemd socket activation (LISTEN_FDS) instead of -addr.")
+	shutdownTimeout := flag.Duration("web.shutdown-timeout", 10*time.Second, "On SIGTERM or SIGINT, how long to wait for in-flight scrapes to finish before exiting.")
+	flappingRestarts := flag.Int("flapping.restarts", defaults.FlappingRestarts, "Number of restarts within -flapping.window after which a process group is reported as flapping.")
+	flappingWindow := flag.Duration("flapping.window", defaults.FlappingWindow, "Time window used for flapping detection. Should be several times the refresh interval.")
+	cpuRecWindow := flag.Duration("cpu-recommendation.window", defaults.CPURecommendationWindow, "Sliding window of per-group CPU usage samples used for process_cpu_recommendation_cores.")
+	workingSet := flag.Bool("memory.working-set", false, "Estimate working set size by clearing referenced page bits (/proc/pid/clear_refs) on every refresh. Linux only; affects kernel page reclaim decisions, use with care.")
+	shardCount := flag.Int("shard.count", defaults.ShardCount, "Total number of exporter instances sharing this host. PIDs are split by hash modulo this count.")
+	constLabelsFlag := flag.String("labels", "", "Comma separated name=value labels (e.g. env=prod,dc=eu1) attached to every exported series, also settable as labels in -config.file. For exporters scraped outside Prometheus-managed scrape configs.")
+	shardIndex := flag.Int("shard.index", 0, "Index of this instance among -shard.count instances, starting from 0. Exported as the shard label.")
+	concurrency := flag.Int("collect-concurrency", defaults.Concurrency, "Number of workers collecting per-process metrics in parallel during a scrape.")
+	excludeUIDs := flag.String("exclude.uids", "", "Comma separated list of UIDs whose processes are skipped entirely during refresh.")
+	excludeCgroups := flag.String("exclude.cgroups", "", "Comma separated list of cgroup path prefixes (e.g. /kubepods) whose processes are skipped entirely during refresh. Linux only.")
+	timeoutOffset := flag.Duration("scrape-timeout-offset", 500*time.Millisecond, "Offset to subtract from the Prometheus scrape timeout when bounding collection time.")
+	auditLog := flag.String("audit.log", "", "Path to the auditd log (e.g. /var/log/audit/audit.log). When set, monitored processes are correlated with execve records and exported as process_exec_info. Requires an audit rule for execve, e.g. auditctl -a always,exit -F arch=b64 -S execve.")
+	annotationsDir := flag.String("annotations.dir", "", "Directory of JSON files mapping PIDs or process name substrings to extra labels, merged on every refresh and exported as process_annotation_info.")
+	procEvents := flag.Bool("proc-events", false, "Subscribe to fork/exec/exit events via the Linux proc connector and update the process cache incrementally between full scans. Requires CAP_NET_ADMIN.")
+	refreshInterval := flag.Duration("refresh-interval", defaults.RefreshInterval, "Interval to refresh process list (scan all processes).")
+	warmupDelay := flag.Duration("startup.warmup-delay", defaults.WarmupDelay, "Delay between the initial cache refresh and a second one at startup, so CPU usage baselines exist before the first scrape. 0 skips the second refresh (the warm-up collection still runs).")
+	fdsExhaustionWindow := flag.Duration("fds.exhaustion-window", defaults.FDsExhaustionWindow, "Window of open file descriptor samples (one per cache refresh) used to project process_fds_exhaustion_seconds (fdexhaustion collector).")
+	topN := flag.Int("top.n", 0, "Also export the N processes using the most CPU and the N using the most memory on the host, whether or not they match a configured group (process_top_*). Every process is read on each cache refresh. 0 disables.")
+	pidLabel := flag.String("pid-label", collector.PIDLabelPID, "How processes are identified on per-process series: \"pid\" keeps the pid label, \"ordinal\" replaces it with an id label holding the index of the process among processes of the same name (reused after a restart, so counters continue in the same series), \"starttime\" with a hash of the PID and start time.")
+	maxProcsPerGroup := flag.Int("max-procs-per-group", 0, "Maximum number of processes per group exported with per-process series. Larger groups (e.g. a fork bomb matching a pattern) are exported aggregated without the pid label and flagged by process_group_truncated. 0 disables the limit.")
+	backgroundInterval := flag.Duration("collect.background-interval", 0, "Collect all metrics in the background at this interval and serve only the cached samples (with timestamps) on scrapes, so scrape latency no longer depends on /proc. 0 collects on every scrape.")
+	aggregate := flag.Bool("metrics.aggregate", false, "Serve group-level series without pid labels on /metrics (per-process series stay available on /metrics/detailed).")
+	snapshotsKeep := flag.Int("snapshots.keep", 0, "Number of recent scrape snapshots kept in memory for /debug/snapshots/diff. 0 disables snapshots.")
+	enableLifecycle := flag.Bool("web.enable-lifecycle", false, "Enable the POST /-/reload endpoint for reloading the config.")
+	selfMetrics := flag.Bool("self-metrics", false, "Also expose Go runtime and process metrics of the exporter itself.")
+	profile := flag.String("profile", profileCached, "Collection profile: \"cached\" refreshes a process cache in the background and exports process_* metrics for configured processes, \"full\" scans all processes on every scrape like node-process and exports node_process_* metrics.")
+	sshConfig := flag.String("ssh.config.file", "", "Path to a YAML file listing remote hosts collected over SSH and exposed on /remote/metrics.")
+	winrmConfig := flag.String("winrm.config.file", "", "Path to a YAML file listing remote Windows hosts collected over WinRM and exposed on /remote/metrics.")
+	pathWritePaths := flag.String("path-writes.paths", "", "Comma separated list of directories (e.g. /var/log) whose per-process written bytes are exported as process_path_write_estimated_bytes_total (pathwrites collector).")
+	mmapMinSize := flag.Uint64("mmaps.min-size", defaults.MMapMinSize, "Minimum size in MiB of a memory-mapped file to be listed in process_mmap_file_bytes (mmaps collector).")
+	idleThresholds := flag.String("connections.idle-thresholds", defaults.IdleThresholds, "Comma separated list of idle durations for process_network_idle_connections (idleconns collector).")
+	remoteWriteURL := flag.String("remote-write.url", "", "Push all metrics of /metrics to this Prometheus remote-write endpoint (e.g. Mimir or VictoriaMetrics) instead of or in addition to being scraped.")
+	remoteWriteInterval := flag.Duration("remote-write.interval", 15*time.Second, "Interval between remote-write pushes.")
+	remoteWriteTimeout := flag.Duration("remote-write.timeout", 10*time.Second, "Timeout of a single remote-write request.")
+	remoteWriteLabels := flag.String("remote-write.external-labels", "", "Comma separated name=value labels added to every pushed series, e.g. instance=web-1,env=prod.")
+	remoteWriteUsername := flag.String("remote-write.username", "", "Username for basic auth on the remote-write endpoint.")
+	remoteWritePasswordFile := flag.String("remote-write.password-file", "", "File containing the password for basic auth on the remote-write endpoint.")
+	cmdRulesFile := flag.String("cmd.rules.file", "", "Path to a YAML file with rewrite rules (replace, redact, max_args, max_length, hash) applied to the cmd label of node_process_* metrics (full profile and /remote/metrics), e.g. to strip passwords from command lines.")
+	cmdMaxLength := flag.Int("cmd.max-length", 0, "Truncate the cmd label of node_process_* metrics to this many characters, 0 keeps the whole command line. Overrides max_length in -cmd.rules.file.")
+	cmdHash := flag.Bool("cmd.hash", false, "Append a short hash of the full command line to the cmd label of node_process_* metrics, so that truncated values still tell invocations apart.")
+	userCacheTTL := flag.Duration("user-cache.ttl", 5*time.Minute, "How long a resolved uid to username mapping is cached (full profile).")
+	staticTTL := flag.Duration("cache.static-ttl", fullscan.DefaultCacheTTLs.Static, "How long the name, command line and executable path of a process are cached between scrapes, keyed by PID and start time (full profile). 0 caches them until the process exits, negative disables caching.")
+	slowTTL := flag.Duration("cache.slow-ttl", fullscan.DefaultCacheTTLs.Slow, "How long the user and cgroups of a process are cached between scrapes (full profile). 0 caches them until the process exits, negative disables caching.")
+	sysfsPath := flag.String("path.sysfs", "/sys", "Path to read sys data (cpufreq, cgroup) from, e.g. the host's /sys mounted into a container.")
+	rootfsPath := flag.String("path.rootfs", "/", "Path to the host's root filesystem mounted into a container. -procfs and -path.sysfs default to its proc and sys directories.")
+	once := flag.Bool("once", false, "Refresh the process cache, collect all metrics once, write them to stdout and exit, e.g. to debug match rules or feed node_exporter's textfile collector from cron. -startup.warmup-delay applies between the two refreshes.")
+	onceFormat := flag.String("once.format", "prometheus", "Output format of -once: prometheus, influx or jsonl.")
+	collectorFlags := collector.RegisterCollectorFlags(flag.CommandLine)
+	logConfig := logging.RegisterFlags(flag.CommandLine)
+
+	var cmd string
+	cmd, os.Args = subcommand(os.Args)
+	selfTest, check := cmd == "selftest", cmd == "check"
+	flag.Parse()
+	if err := logConfig.Setup(); err != nil {
+		logging.Fatal("Invalid logging flags", "err", err)
+	}
+
+	procfsSet := flagSet("procfs") || flagSet("path.procfs")
+	if err := setHostPaths(*procfsPath, *sysfsPath, *rootfsPath, procfsSet, flagSet("path.sysfs")); err != nil {
+		logging.Fatal("Invalid -procfs, -path.sysfs or -path.rootfs", "err", err)
+	}
+	if !procfsSet && *rootfsPath == "/" && inContainer() {
+		// 容器有自己的 PID 命名空间，不挂载宿主机的 /proc 时只能看到容器内的进程
+		slog.Warn("Running in a container without -path.procfs or -path.rootfs, only processes inside the container are visible")
+	}
+
+	if selfTest {
+		os.Exit(collector.RunSelfTest(os.Stdout, collectorFlags(), *workingSet))
+	}
+	if *configPath != "" && !flagSet("children") {
+		*children = true
+	}
+
+	if err := validateProfile(*profile); err != nil {
+		logging.Fatal("Invalid -profile", "err", err)
+	}
+	if *profile == profileFull {
+		if err := validateFullProfileFlags(flag.CommandLine); err != nil {
+			logging.Fatal("Invalid flags", "err", err)
+		}
+	}
+	if *profile == profileCached && *procNames == "" && *configFile == "" && *configPath == "" && *services == "" && len(pidFiles) == 0 && *topN == 0 {
+		logging.Fatal("Please provide -names (e.g., -names=nginx,mysql), -services, -pidfile, -top.n, -config.file or -config.path")
+	}
+
+	opts := collector.Options{
+		NameMode:                *nameMode,
+		NormalizeNames:          *normalizeNames,
+		ExcludeNames:            strings.Split(*excludeNames, ","),
+		PIDFiles:                pidFiles,
+		ConfigFile:              *configFile,
+		ConfigPath:              *configPath,
+		Children:                *children,
+		TopN:                    *topN,
+		Labels:                  *constLabelsFlag,
+		Collectors:              collectorFlags(),
+		Concurrency:             *concurrency,
+		RefreshInterval:         *refreshInterval,
+		WarmupDelay:             *warmupDelay,
+		BackgroundInterval:      *backgroundInterval,
+		ProcEvents:              *procEvents,
+		FlappingRestarts:        *flappingRestarts,
+		FlappingWindow:          *flappingWindow,
+		CPURecommendationWindow: *cpuRecWindow,
+		WorkingSet:              *workingSet,
+		ShardIndex:              *shardIndex,
+		ShardCount:              *shardCount,
+		ExcludeUIDs:             *excludeUIDs,
+		ExcludeCgroups:          *excludeCgroups,
+		AuditLog:                *auditLog,
+		AnnotationsDir:          *annotationsDir,
+		FDsExhaustionWindow:     *fdsExhaustionWindow,
+		MaxProcsPerGroup:        *maxProcsPerGroup,
+		PathWritePaths:          *pathWritePaths,
+		MMapMinSize:             *mmapMinSize,
+		IdleThresholds:          *idleThresholds,
+	}
+	if *procNames != "" {
+		opts.Names = strings.Split(*procNames, ",")
+	}
+	if *services != "" {
+		opts.Services = strings.Split(*services, ",")
+	}
+
+	if check {
+		os.Exit(collector.CheckConfig(os.Stdout, opts))
+	}
+
+	cmdRewriter, err := fullscan.NewCmdRewriter(*cmdRulesFile, *cmdMaxLength, *cmdHash)
+	if err != nil {
+		logging.Fatal("Error loading cmd label rewrite rules", "err", err)
+	}
+	// 远程主机的指标带有 host 标签，单独通过 /remote/metrics 输出，两种模式都支持
+	var links []string
+	if *sshConfig != "" || *winrmConfig != "" {
+		links = append(links, "/remote/metrics")
+		remoteCollector, err := remote.Load(*sshConfig, *winrmConfig)
+		if err != nil {
+			logging.Fatal("Error preparing remote collection", "err", err)
+		}
+		remoteCollector.SetCmdRewriter(cmdRewriter)
+		remoteRegistry := prometheus.NewRegistry()
+		remoteRegistry.MustRegister(remoteCollector)
+		http.Handle("/remote/metrics", promhttp.HandlerFor(remoteRegistry, promhttp.HandlerOpts{
+			ErrorLog:      logging.ErrorLogger(),
+			ErrorHandling: promhttp.ContinueOnError,
+		}))
+	}
+
+	// 主动推送，推送内容与 /metrics 一致，两种模式都支持
+	var remoteWrite *remotewrite.Client
+	if *remoteWriteURL != "" {
+		remoteWrite, err = newRemoteWriteClient(*remoteWriteURL, *remoteWriteInterval, *remoteWriteTimeout, *remoteWriteLabels, *remoteWriteUsername, *remoteWritePasswordFile)
+		if err != nil {
+			logging.Fatal("Error configuring remote write", "err", err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if *profile == profileFull {
+		reloader, err := collector.NewReloader(opts)
+		if err != nil {
+			logging.Fatal("Invalid process selection", "err", err)
+		}
+		if *enableLifecycle {
+			http.Handle("/-/reload", reloader)
+		}
+		handler, gatherer, err := collector.NewFullProfileHandler(reloader, opts, collector.FullProfileOptions{
+			UserCacheTTL: *userCacheTTL,
+			CacheTTLs:    fullscan.CacheTTLs{Static: *staticTTL, Slow: *slowTTL},
+			SelfMetrics:  *selfMetrics,
+			Cmd:          cmdRewriter,
+		})
+		if err != nil {
+			logging.Fatal("Error creating metrics handler", "err", err)
+		}
+		http.Handle("/metrics", handler)
+		http.HandleFunc("/-/healthy", collector.Healthy)
+		http.Handle("/", collector.LandingPage(buildVersion(), nil, append([]string{"/metrics", "/-/healthy"}, links...)))
+		reloader.WatchSIGHUP()
+		if remoteWrite != nil {
+			go remoteWrite.Run(ctx, gatherer)
+		}
+
+		slog.Info("Starting Process Exporter", "profile", *profile, "addr", *addr)
+		server := &http.Server{Addr: *addr}
+		if err := web.ServeUntilSignal(server, *webConfig, *systemdSocket, *shutdownTimeout, cancel); err != nil {
+			logging.Fatal("Error running server", "err", err)
+		}
+		slog.Info("Server stopped")
+		return
+	}
+
+	pc, err := collector.New(opts)
+	if err != nil {
+		logging.Fatal("Error creating collector", "err", err)
+	}
+	handlerOpts := collector.HandlerOptions{
+		TimeoutOffset: *timeoutOffset,
+		PIDLabel:      *pidLabel,
+		Aggregate:     *aggregate,
+		SnapshotsKeep: *snapshotsKeep,
+		SelfMetrics:   *selfMetrics,
+		Version:       buildVersion(),
+		Links:         links,
+	}
+	if *once {
+		if err := pc.Once(os.Stdout, *onceFormat, handlerOpts); err != nil {
+			logging.Fatal("Error writing metrics", "err", err)
+		}
+		return
+	}
+	if *enableLifecycle {
+		http.Handle("/-/reload", pc.Reloader())
+	}
+	pc.Reloader().WatchSIGHUP()
+
+	// 启动后台刷新协程
+	if err := pc.Start(ctx); err != nil {
+		logging.Fatal("Error starting collector", "err", err)
+	}
+
+	gatherer, err := pc.RegisterHandlers(http.DefaultServeMux, handlerOpts)
+	if err != nil {
+		logging.Fatal("Error registering handlers", "err", err)
+	}
+
+	if remoteWrite != nil {
+		go remoteWrite.Run(ctx, gatherer)
+	}
+
+	slog.Info("Starting Optimized Process Exporter", "profile", *profile, "addr", *addr,
+		"monitoring", pc.TargetNames(), "refresh_interval", *refreshInterval)
+
+	// 退出时先停止缓存刷新、后台采集和推送，再等待进行中的抓取完成
+	server := &http.Server{Addr: *addr}
+	if err := web.ServeUntilSignal(server, *webConfig, *systemdSocket, *shutdownTimeout, cancel); err != nil {
+		logging.Fatal("Error running server", "err", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// subcommand 取出 args[1] 中的子命令，返回子命令和去掉子命令后的参数，其余参数照常解析
+// selftest：对自身进程执行一次所有采集项后退出；check：校验参数和配置文件后退出
+// 没有子命令时返回空字符串和原参数
+func subcommand(args []string) (string, []string) {
+	if len(args) > 1 && (args[1] == "selftest" || args[1] == "check") {
+		return args[1], append(args[:1:1], args[2:]...)
+	}
+	return "", args
+}
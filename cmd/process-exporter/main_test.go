@@ -0,0 +1,34 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSubcommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCmd  string
+		wantArgs []string
+	}{
+		{"no arguments", []string{"process-exporter"}, "", []string{"process-exporter"}},
+		{"flags only", []string{"process-exporter", "-names", "nginx"}, "", []string{"process-exporter", "-names", "nginx"}},
+		{"selftest", []string{"process-exporter", "selftest"}, "selftest", []string{"process-exporter"}},
+		{"selftest with flags", []string{"process-exporter", "selftest", "-collector.io=false"}, "selftest", []string{"process-exporter", "-collector.io=false"}},
+		{"check with flags", []string{"process-exporter", "check", "-config", "a.yml"}, "check", []string{"process-exporter", "-config", "a.yml"}},
+		{"subcommand only as first argument", []string{"process-exporter", "-names", "check"}, "", []string{"process-exporter", "-names", "check"}},
+		{"unknown subcommand is left to flag parsing", []string{"process-exporter", "serve"}, "", []string{"process-exporter", "serve"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, args := subcommand(slices.Clone(tt.args))
+			if cmd != tt.wantCmd {
+				t.Errorf("subcommand(%q) cmd = %q, want %q", tt.args, cmd, tt.wantCmd)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("subcommand(%q) args = %q, want %q", tt.args, args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"process-exporter/collector"
+)
+
+// 采集模式，通过 -profile 选择
+const (
+	// profileCached 定时刷新进程缓存，抓取时只采集缓存中匹配的进程，导出 process_* 指标
+	profileCached = "cached"
+	// profileFull 每次抓取都扫描全部进程，与 node-process 相同，导出 node_process_* 指标
+	profileFull = "full"
+)
+
+// cachedOnlyFlags 只在 cached 模式下生效的参数，-profile=full 时显式指定直接报错，不静默忽略
+// -exclude.names 是否可用取决于有没有匹配规则，由 collector.NewFullProfileHandler 检查
+var cachedOnlyFlags = []string{
+	"pidfile", "services", "top.n", "exclude.uids", "exclude.cgroups",
+	"children", "refresh-interval", "startup.warmup-delay", "collect.background-interval", "proc-events",
+	"flapping.restarts", "flapping.window", "cpu-recommendation.window", "memory.working-set",
+	"shard.count", "shard.index", "collect-concurrency", "audit.log", "annotations.dir", "fds.exhaustion-window",
+	"pid-label", "max-procs-per-group", "metrics.aggregate", "snapshots.keep", "scrape-timeout-offset",
+	"path-writes.paths", "mmaps.min-size", "connections.idle-thresholds", "once", "once.format",
+}
+
+func validateProfile(profile string) error {
+	switch profile {
+	case profileCached, profileFull:
+		return nil
+	}
+	return fmt.Errorf("unknown profile %q, must be %q or %q", profile, profileCached, profileFull)
+}
+
+// validateFullProfileFlags 检查显式指定的参数，只在 cached 模式下生效的参数和 full 模式不支持的采集项返回错误
+func validateFullProfileFlags(fs *flag.FlagSet) error {
+	var unsupported []string
+	fs.Visit(func(f *flag.Flag) {
+		name, isCollector := strings.CutPrefix(f.Name, "collector.")
+		switch {
+		case slices.Contains(cachedOnlyFlags, f.Name):
+		case isCollector && f.Value.String() == "true" && !slices.Contains(collector.FullProfileCollectors, name):
+		default:
+			return
+		}
+		unsupported = append(unsupported, "-"+f.Name)
+	})
+	if len(unsupported) > 0 {
+		return fmt.Errorf("not supported with -profile=full: %s", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"process-exporter/internal/remotewrite"
+)
+
+// newRemoteWriteClient 根据 -remote-write.* 参数创建推送客户端
+func newRemoteWriteClient(url string, interval, timeout time.Duration, externalLabels, username, passwordFile string) (*remotewrite.Client, error) {
+	labels, err := remotewrite.ParseLabels(externalLabels)
+	if err != nil {
+		return nil, err
+	}
+	cfg := remotewrite.Config{
+		URL:            url,
+		Interval:       interval,
+		Timeout:        timeout,
+		ExternalLabels: labels,
+		Username:       username,
+	}
+	if passwordFile != "" {
+		password, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Password = strings.TrimSpace(string(password))
+	}
+	return remotewrite.New(cfg)
+}
@@ -0,0 +1,27 @@
+package main
+
+import "runtime/debug"
+
+// version 构建时通过 -ldflags "-X main.version=v1.2.3" 设置，未设置时使用 Go 模块信息中的版本和 VCS 提交
+var version string
+
+// buildVersion 返回首页显示的版本
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	// go build 在 Go 1.24 之前以及没有 VCS 信息时版本为 (devel)，附上提交便于区分
+	v := info.Main.Version
+	if v == "(devel)" {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" && len(s.Value) >= 12 {
+				v += " " + s.Value[:12]
+			}
+		}
+	}
+	return v
+}
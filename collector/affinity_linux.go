@@ -0,0 +1,12 @@
+package collector
+
+import "golang.org/x/sys/unix"
+
+// readAffinityCPUs 通过 sched_getaffinity 读取进程（主线程）允许运行的 CPU 数量
+func readAffinityCPUs(pid int32) (int, error) {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(int(pid), &set); err != nil {
+		return 0, err
+	}
+	return set.Count(), nil
+}
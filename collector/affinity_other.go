@@ -0,0 +1,9 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readAffinityCPUs(pid int32) (int, error) {
+	return 0, errors.ErrUnsupported
+}
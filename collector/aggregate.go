@@ -0,0 +1,306 @@
+package collector
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// perProcessLabels 聚合时去掉的标签，其余标签（process_name 等）保留
+var perProcessLabels = map[string]struct{}{"pid": {}, "tid": {}, "thread_name": {}}
+
+// aggregateOp 合并同一分组中两个进程的值
+type aggregateOp func(a, b float64) float64
+
+func sum(a, b float64) float64 { return a + b }
+
+// aggregateOps 各指标的聚合方式，值为 nil 的指标聚合后不输出
+// 没有列出的 counter 按分组求和；没有列出的 gauge 不输出，新增 gauge 时需要在这里明确聚合方式，避免把不能相加的值加起来
+// process_up 求和后即分组的进程数；同一个 cgroup 下的进程读到的是同一个 cgroup 的值，取最大值而不是重复累加
+var aggregateOps = map[string]aggregateOp{
+	"process_up":                          sum,
+	"process_memory_rss_bytes":            sum,
+	"process_memory_vms_bytes":            sum,
+	"process_memory_pss_bytes":            sum,
+	"process_memory_uss_bytes":            sum,
+	"process_memory_private_bytes":        sum,
+	"process_memory_swap_bytes":           sum,
+	"process_memory_working_set_bytes":    sum,
+	"process_mmap_file_bytes":             sum,
+	"process_num_threads":                 sum,
+	"process_open_fds":                    sum,
+	"process_open_handles":                sum,
+	"process_fds":                         sum,
+	"process_network_connections":         sum,
+	"process_network_idle_connections":    sum,
+	"process_listen_ports":                sum,
+	"process_state":                       sum,
+	"process_thread_state":                sum,
+	"process_orphaned":                    sum,
+	"process_cmdline_mismatch":            sum,
+	"process_collect_duration_seconds":    sum,
+	"process_tree_memory_rss_bytes":       sum,
+	"process_tree_num_procs":              sum,
+	"process_annotation_info":             math.Max,
+	"process_exec_info":                   math.Max,
+	"process_start_time_seconds":          math.Min,
+	"process_rlimit_soft":                 math.Min,
+	"process_rlimit_hard":                 math.Min,
+	"process_memory_rss_peak_bytes":       math.Max,
+	"process_parent_pid":                  math.Min,
+	"process_collect_success":             math.Min,
+	"process_fds_exhaustion_seconds":      math.Min,
+	"process_cpu_affinity_cpus":           math.Min,
+	"process_io_priority":                 math.Min,
+	"process_cgroup_memory_max_bytes":     math.Min,
+	"process_cgroup_cpu_limit_cpus":       math.Min,
+	"process_cgroup_memory_current_bytes": math.Max,
+	// cgroup 的计数器同样按 cgroup 去重
+	"process_cgroup_cpu_periods_total":           math.Max,
+	"process_cgroup_cpu_throttled_periods_total": math.Max,
+	"process_cgroup_cpu_throttled_seconds_total": math.Max,
+	// 排行榜本身就是进程级别的，聚合后没有意义
+	"process_top_cpu_usage_cores":  nil,
+	"process_top_memory_rss_bytes": nil,
+}
+
+// aggregatingGatherer 将带 pid 标签的指标按去掉进程级标签后的分组聚合
+// 中心 Prometheus 只抓取聚合后的序列，序列数量与进程数量无关
+// carry 不为 nil 时计入分组中已退出进程的计数器值
+type aggregatingGatherer struct {
+	gatherer prometheus.Gatherer
+	carry    *counterCarry
+}
+
+func (a aggregatingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := a.gatherer.Gather()
+	pass := a.carry.begin()
+	for _, mf := range families {
+		if hasPIDLabel(mf) {
+			aggregateFamily(mf, nil, pass)
+		}
+	}
+	return dropEmpty(families), err
+}
+
+// dropEmpty 去掉聚合后没有序列的指标族，编码器不接受空的指标族
+func dropEmpty(families []*dto.MetricFamily) []*dto.MetricFamily {
+	kept := families[:0]
+	for _, mf := range families {
+		if len(mf.Metric) > 0 {
+			kept = append(kept, mf)
+		}
+	}
+	return kept
+}
+
+func hasPIDLabel(mf *dto.MetricFamily) bool {
+	if len(mf.Metric) == 0 {
+		return false
+	}
+	for _, lp := range mf.Metric[0].Label {
+		if lp.GetName() == "pid" {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregateFamily 原地聚合一个指标族，只处理 counter 和 gauge
+// include 不为 nil 时只聚合其返回 true 的序列，其余序列原样保留在聚合结果之前
+// 聚合方式见 aggregateOps，不输出的指标只保留 include 之外的序列
+// pass 不为 nil 时按分组求和的 counter 加上已退出进程最后一次的值，进程退出不会让聚合值下降
+func aggregateFamily(mf *dto.MetricFamily, include func(*dto.Metric) bool, pass *carryPass) {
+	if mf.GetType() != dto.MetricType_COUNTER && mf.GetType() != dto.MetricType_GAUGE {
+		return
+	}
+	op, ok := aggregateOps[mf.GetName()]
+	if !ok && mf.GetType() == dto.MetricType_COUNTER {
+		op = sum
+	}
+	// members 分组 -> pid -> 值，只记录需要延续的 counter
+	var members map[string]map[string]float64
+	if pass != nil && !ok && mf.GetType() == dto.MetricType_COUNTER {
+		members = make(map[string]map[string]float64)
+	}
+
+	groups := make(map[string]*dto.Metric)
+	var keys []string
+	var kept []*dto.Metric
+	for _, m := range mf.Metric {
+		if include != nil && !include(m) {
+			kept = append(kept, m)
+			continue
+		}
+		if op == nil {
+			continue
+		}
+		labels := make([]*dto.LabelPair, 0, len(m.Label))
+		var key strings.Builder
+		var pid string
+		for _, lp := range m.Label {
+			if lp.GetName() == "pid" {
+				pid = lp.GetValue()
+			}
+			if _, drop := perProcessLabels[lp.GetName()]; drop {
+				continue
+			}
+			labels = append(labels, lp)
+			key.WriteString(lp.GetName())
+			key.WriteByte('=')
+			key.WriteString(lp.GetValue())
+			key.WriteByte(0)
+		}
+		k := key.String()
+		value := metricValue(m)
+		if members != nil {
+			if members[k] == nil {
+				members[k] = make(map[string]float64)
+			}
+			members[k][pid] = value
+		}
+		g, ok := groups[k]
+		if !ok {
+			g = &dto.Metric{Label: labels, TimestampMs: m.TimestampMs}
+			setMetricValue(g, mf.GetType(), value)
+			groups[k] = g
+			keys = append(keys, k)
+			continue
+		}
+		setMetricValue(g, mf.GetType(), op(metricValue(g), value))
+	}
+
+	if members != nil {
+		for k, v := range pass.observe(mf.GetName(), members) {
+			setMetricValue(groups[k], mf.GetType(), v)
+		}
+	}
+
+	sort.Strings(keys)
+	mf.Metric = append(mf.Metric[:0], kept...)
+	for _, k := range keys {
+		mf.Metric = append(mf.Metric, groups[k])
+	}
+}
+
+// counterCarry 记录聚合 counter 中每个进程最近一次的值
+// 进程退出（或 PID 被复用）后它的值计入所在分组的 exited，聚合后的 _total 不会因为进程退出而下降，
+// 分组中的进程全部退出后分组的序列不再输出，同时丢弃记录
+type counterCarry struct {
+	mu sync.Mutex
+	// starts 返回当前存活进程的 PID（标签值）-> 启动时间
+	starts func() map[string]int64
+	// families 指标名 -> 分组 -> 状态
+	families map[string]map[string]*carriedGroup
+}
+
+type carriedGroup struct {
+	members map[string]carriedMember
+	exited  float64
+}
+
+type carriedMember struct {
+	start int64
+	value float64
+}
+
+func newCounterCarry(starts func() map[string]int64) *counterCarry {
+	return &counterCarry{starts: starts, families: make(map[string]map[string]*carriedGroup)}
+}
+
+// carryPass 一次 Gather 使用的存活进程快照
+type carryPass struct {
+	carry  *counterCarry
+	starts map[string]int64
+}
+
+// begin 读取存活进程，carry 为 nil 时返回 nil
+func (c *counterCarry) begin() *carryPass {
+	if c == nil {
+		return nil
+	}
+	return &carryPass{carry: c, starts: c.starts()}
+}
+
+// observe 记录 family 中各分组成员本次的值，返回各分组加上已退出进程后的值
+// 本次没有输出但仍存活的进程（超时未采集、读取失败）沿用上一次的值；
+// 并发抓取的结果可能晚于更新的结果到达，同一进程取较大的值
+func (p *carryPass) observe(family string, members map[string]map[string]float64) map[string]float64 {
+	c := p.carry
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	groups := c.families[family]
+	if groups == nil {
+		groups = make(map[string]*carriedGroup)
+		c.families[family] = groups
+	}
+	for k, g := range groups {
+		for pid, m := range g.members {
+			if start, ok := p.starts[pid]; !ok || start != m.start {
+				g.exited += m.value
+				delete(g.members, pid)
+			}
+		}
+		if len(g.members) == 0 && members[k] == nil {
+			delete(groups, k)
+		}
+	}
+
+	totals := make(map[string]float64, len(members))
+	for k, values := range members {
+		g := groups[k]
+		if g == nil {
+			g = &carriedGroup{members: make(map[string]carriedMember)}
+			groups[k] = g
+		}
+		for pid, v := range values {
+			// 采集后、聚合前已经退出的进程无法区分是否计入过 exited，不记录
+			start, ok := p.starts[pid]
+			if !ok {
+				continue
+			}
+			if m, seen := g.members[pid]; seen {
+				v = math.Max(v, m.value)
+			}
+			g.members[pid] = carriedMember{start: start, value: v}
+		}
+		total := g.exited
+		for _, m := range g.members {
+			total += m.value
+		}
+		totals[k] = total
+	}
+	return totals
+}
+
+// liveStarts 返回缓存中进程的 PID（标签值）-> 启动时间
+func (c *ProcessCollector) liveStarts() map[string]int64 {
+	c.rwMutex.RLock()
+	defer c.rwMutex.RUnlock()
+	starts := make(map[string]int64, len(c.cachedProcs))
+	for pid, cached := range c.cachedProcs {
+		starts[strconv.Itoa(int(pid))] = cached.StartTime
+	}
+	return starts
+}
+
+func metricValue(m *dto.Metric) float64 {
+	if m.Counter != nil {
+		return m.Counter.GetValue()
+	}
+	return m.Gauge.GetValue()
+}
+
+func setMetricValue(m *dto.Metric, typ dto.MetricType, v float64) {
+	if typ == dto.MetricType_COUNTER {
+		m.Counter = &dto.Counter{Value: &v}
+	} else {
+		m.Gauge = &dto.Gauge{Value: &v}
+	}
+}
@@ -0,0 +1,154 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// sample 测试用的一条进程级序列
+type sample struct {
+	pid, name string
+	value     float64
+}
+
+// family 构造带 pid 和 process_name 标签的指标族
+func family(name string, typ dto.MetricType, samples ...sample) *dto.MetricFamily {
+	mf := &dto.MetricFamily{Name: proto.String(name), Type: typ.Enum()}
+	for _, s := range samples {
+		m := &dto.Metric{Label: []*dto.LabelPair{
+			{Name: proto.String("pid"), Value: proto.String(s.pid)},
+			{Name: proto.String("process_name"), Value: proto.String(s.name)},
+		}}
+		setMetricValue(m, typ, s.value)
+		mf.Metric = append(mf.Metric, m)
+	}
+	return mf
+}
+
+// values 按 process_name 取出聚合后的值
+func values(mf *dto.MetricFamily) map[string]float64 {
+	got := make(map[string]float64)
+	for _, m := range mf.Metric {
+		for _, lp := range m.Label {
+			if lp.GetName() == "process_name" {
+				got[lp.GetValue()] = metricValue(m)
+			}
+		}
+	}
+	return got
+}
+
+func TestAggregatingGatherer(t *testing.T) {
+	nginx := []sample{{"1", "nginx", 10}, {"2", "nginx", 30}, {"3", "mysql", 5}}
+	tests := []struct {
+		name   string
+		metric string
+		typ    dto.MetricType
+		want   map[string]float64 // nil 表示聚合后不输出
+	}{
+		{"additive gauge summed", "process_memory_rss_bytes", dto.MetricType_GAUGE, map[string]float64{"nginx": 40, "mysql": 5}},
+		{"process_up counts processes", "process_up", dto.MetricType_GAUGE, map[string]float64{"nginx": 40, "mysql": 5}},
+		{"start time takes oldest", "process_start_time_seconds", dto.MetricType_GAUGE, map[string]float64{"nginx": 10, "mysql": 5}},
+		{"limit takes smallest", "process_rlimit_soft", dto.MetricType_GAUGE, map[string]float64{"nginx": 10, "mysql": 5}},
+		{"collect success is all or nothing", "process_collect_success", dto.MetricType_GAUGE, map[string]float64{"nginx": 10, "mysql": 5}},
+		{"peak takes largest", "process_memory_rss_peak_bytes", dto.MetricType_GAUGE, map[string]float64{"nginx": 30, "mysql": 5}},
+		{"cgroup gauge not double counted", "process_cgroup_memory_current_bytes", dto.MetricType_GAUGE, map[string]float64{"nginx": 30, "mysql": 5}},
+		{"cgroup counter not double counted", "process_cgroup_cpu_throttled_seconds_total", dto.MetricType_COUNTER, map[string]float64{"nginx": 30, "mysql": 5}},
+		{"unlisted counter summed", "process_cpu_user_seconds_total", dto.MetricType_COUNTER, map[string]float64{"nginx": 40, "mysql": 5}},
+		{"unlisted gauge dropped", "process_cpu_recommendation_cores", dto.MetricType_GAUGE, nil},
+		{"top list dropped", "process_top_cpu_usage_cores", dto.MetricType_GAUGE, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := &dto.MetricFamily{
+				Name:   proto.String("process_exporter_build_info"),
+				Type:   dto.MetricType_GAUGE.Enum(),
+				Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(1)}}},
+			}
+			g := aggregatingGatherer{gatherer: prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+				return []*dto.MetricFamily{family(tt.metric, tt.typ, nginx...), other}, nil
+			})}
+			families, err := g.Gather()
+			if err != nil {
+				t.Fatalf("Gather() error = %v", err)
+			}
+
+			// 不带 pid 标签的指标族原样保留
+			if last := families[len(families)-1]; last != other || len(last.Metric) != 1 {
+				t.Errorf("family without pid label changed")
+			}
+			if tt.want == nil {
+				if len(families) != 1 {
+					t.Errorf("Gather() returned %d families, want %s dropped", len(families), tt.metric)
+				}
+				return
+			}
+			if len(families) != 2 {
+				t.Fatalf("Gather() returned %d families, want 2", len(families))
+			}
+			mf := families[0]
+			for _, m := range mf.Metric {
+				for _, lp := range m.Label {
+					if lp.GetName() == "pid" {
+						t.Errorf("pid label kept after aggregation")
+					}
+				}
+			}
+			got := values(mf)
+			if len(got) != len(tt.want) || len(mf.Metric) != len(tt.want) {
+				t.Fatalf("aggregated series = %v, want %v", got, tt.want)
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("%s = %v, want %v", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregatingGathererCarriesExited(t *testing.T) {
+	const metric = "process_cpu_user_seconds_total"
+	steps := []struct {
+		name    string
+		starts  map[string]int64 // 存活进程
+		samples []sample
+		want    map[string]float64
+	}{
+		{"both running", map[string]int64{"1": 100, "2": 200}, []sample{{"1", "nginx", 10}, {"2", "nginx", 30}}, map[string]float64{"nginx": 40}},
+		{"member exited", map[string]int64{"1": 100}, []sample{{"1", "nginx", 12}}, map[string]float64{"nginx": 42}},
+		{"alive but not collected", map[string]int64{"1": 100, "3": 300}, []sample{{"3", "nginx", 1}}, map[string]float64{"nginx": 43}},
+		{"stale scrape", map[string]int64{"1": 100, "3": 300}, []sample{{"1", "nginx", 11}, {"3", "nginx", 1}}, map[string]float64{"nginx": 43}},
+		{"pid reused", map[string]int64{"1": 150, "3": 300}, []sample{{"1", "nginx", 2}, {"3", "nginx", 1}}, map[string]float64{"nginx": 45}},
+		{"exited before aggregation", map[string]int64{"3": 300}, []sample{{"1", "nginx", 3}, {"3", "nginx", 1}}, map[string]float64{"nginx": 45}},
+		{"group gone", map[string]int64{"5": 500}, []sample{{"5", "mysql", 7}}, map[string]float64{"mysql": 7}},
+		{"group restarted", map[string]int64{"4": 400}, []sample{{"4", "nginx", 5}}, map[string]float64{"nginx": 5}},
+	}
+	var starts map[string]int64
+	carry := newCounterCarry(func() map[string]int64 { return starts })
+	for _, step := range steps {
+		starts = step.starts
+		g := aggregatingGatherer{carry: carry, gatherer: prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return []*dto.MetricFamily{family(metric, dto.MetricType_COUNTER, step.samples...)}, nil
+		})}
+		families, err := g.Gather()
+		if err != nil {
+			t.Fatalf("%s: Gather() error = %v", step.name, err)
+		}
+		if len(families) != 1 {
+			t.Fatalf("%s: Gather() returned %d families, want 1", step.name, len(families))
+		}
+		got := values(families[0])
+		if len(got) != len(step.want) {
+			t.Fatalf("%s: aggregated series = %v, want %v", step.name, got, step.want)
+		}
+		for name, want := range step.want {
+			if got[name] != want {
+				t.Errorf("%s: %s = %v, want %v", step.name, name, got[name], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 标签名只允许 Prometheus 传统的字符集
+var annotationLabelRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// 这些标签由 exporter 自己输出，标注文件不能覆盖
+var reservedAnnotationLabels = map[string]struct{}{
+	"process_name": {},
+	"pid":          {},
+	"shard":        {},
+}
+
+// annotationSet 从 drop-in 目录加载的进程标注
+// 目录下每个 *.json 文件是一个对象，键为 PID 或进程名称子串，值为要附加的标签，例如：
+//
+//	{"1234": {"deploy": "v2"}, "nginx": {"team": "web"}}
+//
+// 部署工具、作业调度器等可以直接写文件给进程打标签，不需要修改主配置
+type annotationSet struct {
+	byPID   map[int32]map[string]string
+	byMatch []annotationMatch
+	// 所有标注出现过的标签名，排序后作为 process_annotation_info 的标签
+	keys []string
+}
+
+type annotationMatch struct {
+	match  string
+	labels map[string]string
+}
+
+// loadAnnotations 读取目录下所有 *.json 文件，按文件名顺序合并，后面的文件覆盖前面的同名标签
+func loadAnnotations(dir string) (*annotationSet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	set := &annotationSet{byPID: make(map[int32]map[string]string)}
+	keys := make(map[string]struct{})
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var entries map[string]map[string]string
+		if err := json.Unmarshal(content, &entries); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		// 同一个文件内按键排序，保证合并结果稳定
+		matches := make([]string, 0, len(entries))
+		for match := range entries {
+			matches = append(matches, match)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			labels := entries[match]
+			for k := range labels {
+				if !annotationLabelRE.MatchString(k) || strings.HasPrefix(k, "__") {
+					return nil, fmt.Errorf("%s: invalid label name %q", file, k)
+				}
+				if _, ok := reservedAnnotationLabels[k]; ok {
+					return nil, fmt.Errorf("%s: label %q is reserved", file, k)
+				}
+				keys[k] = struct{}{}
+			}
+			if pid, err := strconv.ParseInt(match, 10, 32); err == nil {
+				merged := set.byPID[int32(pid)]
+				if merged == nil {
+					merged = make(map[string]string)
+					set.byPID[int32(pid)] = merged
+				}
+				for k, v := range labels {
+					merged[k] = v
+				}
+				continue
+			}
+			set.byMatch = append(set.byMatch, annotationMatch{match: match, labels: labels})
+		}
+	}
+	for k := range keys {
+		set.keys = append(set.keys, k)
+	}
+	sort.Strings(set.keys)
+	return set, nil
+}
+
+// labelsFor 返回进程的标注，先合并名称匹配的标注，PID 精确匹配的优先级最高
+func (a *annotationSet) labelsFor(pid int32, name string) map[string]string {
+	if a == nil {
+		return nil
+	}
+	var labels map[string]string
+	merge := func(src map[string]string) {
+		if labels == nil {
+			labels = make(map[string]string, len(src))
+		}
+		for k, v := range src {
+			labels[k] = v
+		}
+	}
+	for _, m := range a.byMatch {
+		if strings.Contains(name, m.match) {
+			merge(m.labels)
+		}
+	}
+	if byPID, ok := a.byPID[pid]; ok {
+		merge(byPID)
+	}
+	return labels
+}
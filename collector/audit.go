@@ -0,0 +1,215 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unsetAUID 登录 UID 未设置时的值（(uint32)-1），例如由 systemd 启动的服务
+const unsetAUID = "4294967295"
+
+// execRecord 从 auditd 日志中提取的一次 execve 记录
+type execRecord struct {
+	time      time.Time
+	pid, ppid int32
+	// loginUser 登录用户（auid 对应的用户名），经过 su/sudo 也不会改变
+	loginUser string
+	uid       string
+	session   string
+	tty       string
+	comm      string
+}
+
+// auditIndex 增量读取 auditd 日志，按 PID 保存最近一次 execve 记录
+// 只识别带有 EXECVE 记录的事件，不依赖具体架构的系统调用号
+type auditIndex struct {
+	path string
+
+	mu      sync.Mutex
+	offset  int64
+	partial []byte
+	// pending 当前事件的 SYSCALL 记录，遇到同一事件的 EXECVE 记录后才加入 records
+	pendingID string
+	pending   *execRecord
+	records   map[int32]execRecord
+	users     map[string]string
+}
+
+func newAuditIndex(path string) *auditIndex {
+	return &auditIndex{path: path, records: make(map[int32]execRecord), users: make(map[string]string)}
+}
+
+// update 读取上次读取位置之后新增的日志，文件变小时认为已经轮转，从头读取
+// 第一次读取整个文件，以便为 exporter 启动前已经运行的进程找到记录
+func (a *auditIndex) update() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < a.offset {
+		a.offset = 0
+		a.partial = nil
+	}
+	if _, err := f.Seek(a.offset, io.SeekStart); err != nil {
+		return err
+	}
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	a.offset += int64(len(content))
+
+	content = append(a.partial, content...)
+	// 最后一行可能还没写完，留到下一次
+	last := bytes.LastIndexByte(content, '\n')
+	a.partial = append([]byte(nil), content[last+1:]...)
+	scanner := bufio.NewScanner(bytes.NewReader(content[:last+1]))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		a.parseLine(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// parseLine 解析一行 "type=SYSCALL msg=audit(1700000000.123:456): key=value ..."
+func (a *auditIndex) parseLine(line string) {
+	// 开启 log_format=ENRICHED 时 \x1d 之后是翻译后的字段，不需要
+	line, _, _ = strings.Cut(line, "\x1d")
+	typ, rest, ok := strings.Cut(strings.TrimPrefix(line, "type="), " msg=audit(")
+	if !ok {
+		return
+	}
+	id, rest, ok := strings.Cut(rest, "):")
+	if !ok {
+		return
+	}
+
+	switch typ {
+	case "SYSCALL":
+		fields := parseAuditFields(rest)
+		if fields["success"] != "yes" {
+			a.pending = nil
+			return
+		}
+		pid, err1 := strconv.ParseInt(fields["pid"], 10, 32)
+		ppid, err2 := strconv.ParseInt(fields["ppid"], 10, 32)
+		if err1 != nil || err2 != nil {
+			a.pending = nil
+			return
+		}
+		a.pendingID = id
+		a.pending = &execRecord{
+			time:      parseAuditTime(id),
+			pid:       int32(pid),
+			ppid:      int32(ppid),
+			loginUser: a.lookupUser(fields["auid"]),
+			uid:       fields["uid"],
+			session:   fields["ses"],
+			tty:       fields["tty"],
+			comm:      fields["comm"],
+		}
+	case "EXECVE":
+		if a.pending != nil && a.pendingID == id {
+			a.records[a.pending.pid] = *a.pending
+			a.pending = nil
+		}
+	}
+}
+
+// lookupUser 将 auid 解析为用户名，结果缓存，找不到时使用数字
+func (a *auditIndex) lookupUser(auid string) string {
+	if auid == "" || auid == unsetAUID {
+		return "unset"
+	}
+	if name, ok := a.users[auid]; ok {
+		return name
+	}
+	name := auid
+	if u, err := user.LookupId(auid); err == nil {
+		name = u.Username
+	}
+	a.users[auid] = name
+	return name
+}
+
+// lookup 返回 PID 最近一次 execve 记录，记录早于进程启动时间说明 PID 已经被复用
+func (a *auditIndex) lookup(pid int32, startMillis int64) (execRecord, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rec, ok := a.records[pid]
+	// 审计时间和进程启动时间精度不同，允许 1 秒误差
+	if !ok || rec.time.Before(time.UnixMilli(startMillis).Add(-time.Second)) {
+		return execRecord{}, false
+	}
+	return rec, true
+}
+
+// parent 返回父进程的名称，优先使用父进程自己的 execve 记录
+func (a *auditIndex) parent(rec execRecord) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p, ok := a.records[rec.ppid]
+	if !ok || p.time.After(rec.time) {
+		return "", false
+	}
+	return p.comm, true
+}
+
+// prune 删除已经不存在的进程的记录，避免长期运行时无限增长
+func (a *auditIndex) prune(alive map[int32]struct{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for pid := range a.records {
+		if _, ok := alive[pid]; !ok {
+			delete(a.records, pid)
+		}
+	}
+}
+
+// parseAuditFields 解析空格分隔的 key=value，值可能带引号，也可能是十六进制编码（包含空格等字符时）
+func parseAuditFields(s string) map[string]string {
+	fields := make(map[string]string)
+	for _, kv := range strings.Fields(s) {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+			v = v[1 : len(v)-1]
+		} else if k == "comm" || k == "exe" {
+			if decoded, err := hex.DecodeString(v); err == nil {
+				v = string(decoded)
+			}
+		}
+		fields[k] = v
+	}
+	return fields
+}
+
+// parseAuditTime 解析事件 ID "1700000000.123:456" 中的时间戳
+func parseAuditTime(id string) time.Time {
+	ts, _, _ := strings.Cut(id, ":")
+	sec, frac, _ := strings.Cut(ts, ".")
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	ms, _ := strconv.ParseInt(frac, 10, 64)
+	return time.Unix(s, ms*int64(time.Millisecond))
+}
@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// availabilityTracker 按分组累计运行时长，用于计算本机的服务可用率
+// 在 exporter 内部累计，即使 Prometheus 抓取有中断，两个计数器的增量仍然覆盖中断期间
+type availabilityTracker struct {
+	mu   sync.Mutex
+	last time.Time
+	// up 上一次刷新时分组运行的进程数是否达到期望值
+	up map[string]bool
+	// available 进程数达到期望值的累计秒数，observed 观测的累计秒数
+	available map[string]float64
+	observed  map[string]float64
+}
+
+func newAvailabilityTracker() *availabilityTracker {
+	return &availabilityTracker{
+		up:        make(map[string]bool),
+		available: make(map[string]float64),
+		observed:  make(map[string]float64),
+	}
+}
+
+// Observe 记录 at 时刻每个分组是否可用
+// 两次刷新之间的时长按上一次刷新时的状态计入，分组第一次出现时只记录状态
+func (t *availabilityTracker) Observe(at time.Time, up map[string]bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.last.IsZero() {
+		elapsed := at.Sub(t.last).Seconds()
+		if elapsed > 0 {
+			for group, wasUp := range t.up {
+				t.observed[group] += elapsed
+				if wasUp {
+					t.available[group] += elapsed
+				}
+			}
+		}
+	}
+	t.last = at
+	t.up = up
+}
+
+// Seconds 返回分组可用和观测的累计秒数，分组还没有完整的观测间隔时返回 false
+func (t *availabilityTracker) Seconds(group string) (available, observed float64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	observed, ok = t.observed[group]
+	return t.available[group], observed, ok
+}
+
+// recordAvailability 按本次刷新的进程数更新每个分组的可用状态
+func (c *ProcessCollector) recordAvailability(newCache map[int32]CachedProcess) {
+	present := make(map[string]int)
+	for _, cached := range newCache {
+		present[cached.Group]++
+	}
+	groups := c.targets.Load().groups(present)
+	up := make(map[string]bool, len(groups))
+	for _, t := range groups {
+		up[t.Name] = present[t.Name] >= t.minInstances()
+	}
+	c.availability.Observe(time.Now(), up)
+}
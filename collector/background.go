@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sampleSet 后台采集得到的一批指标，抓取时原样返回
+type sampleSet struct {
+	metrics []prometheus.Metric
+	at      time.Time
+}
+
+// StartBackgroundCollection 按 interval 在后台采集所有指标，之后的抓取只返回最近一次采集的结果
+// 抓取耗时只与序列数量有关，不再受 /proc 读取速度影响，代价是数据最多延迟一个 interval
+// 指标带有采集时间作为时间戳，Prometheus 按实际采集时间存储
+func (c *ProcessCollector) StartBackgroundCollection(ctx context.Context, interval time.Duration) {
+	c.collectSamples()
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.collectSamples()
+			}
+		}
+	}()
+}
+
+// collectSamples 执行一次完整采集并替换缓存的指标
+func (c *ProcessCollector) collectSamples() {
+	start := time.Now()
+	ch := make(chan prometheus.Metric, 256)
+	go func() {
+		c.collectNow(ch, time.Time{}, c.collectors)
+		close(ch)
+	}()
+
+	set := &sampleSet{at: start}
+	for m := range ch {
+		set.metrics = append(set.metrics, prometheus.NewMetricWithTimestamp(start, m))
+	}
+	c.samples.Store(set)
+	slog.Debug("Background collection finished", "series", len(set.metrics), "duration", time.Since(start))
+}
@@ -0,0 +1,15 @@
+package collector
+
+// cgroupStats 进程所在 cgroup 的资源限制和 CPU 限流统计
+// 没有设置限制时对应字段为 NaN
+type cgroupStats struct {
+	// path 进程所在的 cgroup 路径，cgroup v1 时取 memory 控制器的路径
+	path string
+	// memoryMax 内存上限，memoryCurrent 当前用量（含 page cache），单位字节
+	memoryMax, memoryCurrent float64
+	// cpuLimit CPU 配额折算的核数，即 quota / period
+	cpuLimit float64
+	// 发生过的调度周期数、被限流的周期数及限流总时长（秒）
+	periods, throttledPeriods uint64
+	throttledSeconds          float64
+}
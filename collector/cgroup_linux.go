@@ -0,0 +1,159 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"process-exporter/internal/procfs"
+	"process-exporter/internal/sysfs"
+)
+
+// cgroupRoot cgroup 文件系统的挂载位置，默认 /sys/fs/cgroup
+func cgroupRoot() string {
+	return sysfs.Path("fs", "cgroup")
+}
+
+// cgroupV1Unlimited cgroup v1 memory.limit_in_bytes 不低于该值时视为没有限制（实际值为按页对齐的 LONG_MAX）
+const cgroupV1Unlimited = 1 << 62
+
+// readCgroupStats 根据 /proc/pid/cgroup 找到进程所在的 cgroup 并读取其资源限制和限流统计
+// 同时支持 cgroup v2（统一层级）和 v1（memory、cpu 控制器）；进程在 cgroup 命名空间中时路径相对于命名空间，
+// 与 exporter 不在同一个命名空间的进程可能找不到对应的目录
+func readCgroupStats(pid int32) (cgroupStats, error) {
+	content, err := os.ReadFile(procfs.PID(pid, "cgroup"))
+	if err != nil {
+		return cgroupStats{}, err
+	}
+
+	var unified, memoryPath, cpuPath string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		// 每行格式为 hierarchy-ID:controller-list:cgroup-path，v2 的 controller-list 为空
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			unified = parts[2]
+			continue
+		}
+		controllers := strings.Split(parts[1], ",")
+		if slices.Contains(controllers, "memory") {
+			memoryPath = parts[2]
+		}
+		if slices.Contains(controllers, "cpu") {
+			cpuPath = parts[2]
+		}
+	}
+
+	root := cgroupRoot()
+	var s cgroupStats
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil && unified != "" {
+		s, err = readCgroupV2Stats(filepath.Join(root, unified), unified)
+	} else if memoryPath != "" && cpuPath != "" {
+		s, err = readCgroupV1Stats(filepath.Join(root, "memory", memoryPath), filepath.Join(root, "cpu", cpuPath), memoryPath)
+	} else {
+		return cgroupStats{}, errors.New("memory or cpu cgroup controller not found")
+	}
+	// cgroup 目录不存在说明进程在其他 cgroup 命名空间中，不能当作进程已退出忽略
+	if errors.Is(err, os.ErrNotExist) {
+		return cgroupStats{}, fmt.Errorf("cgroup of the process is not visible from the exporter: %v", err)
+	}
+	return s, err
+}
+
+// readCgroupV2Stats 读取 cgroup v2 的 memory.max、memory.current、cpu.max 和 cpu.stat
+func readCgroupV2Stats(dir, path string) (cgroupStats, error) {
+	s := cgroupStats{path: path, memoryMax: math.NaN(), cpuLimit: math.NaN()}
+	current, err := readCgroupUint(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return cgroupStats{}, err
+	}
+	s.memoryCurrent = float64(current)
+	// 根 cgroup 没有 memory.max 和 cpu.max，值为 max 表示没有限制
+	if limit, err := readCgroupUint(filepath.Join(dir, "memory.max")); err == nil {
+		s.memoryMax = float64(limit)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "cpu.max")); err == nil {
+		// 格式为 "$MAX $PERIOD"，单位微秒
+		fields := strings.Fields(string(content))
+		if len(fields) == 2 {
+			quota, err1 := strconv.ParseUint(fields[0], 10, 64)
+			period, err2 := strconv.ParseUint(fields[1], 10, 64)
+			if err1 == nil && err2 == nil && period > 0 {
+				s.cpuLimit = float64(quota) / float64(period)
+			}
+		}
+	}
+	stat, err := readCgroupKeyValues(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return cgroupStats{}, err
+	}
+	s.periods = stat["nr_periods"]
+	s.throttledPeriods = stat["nr_throttled"]
+	s.throttledSeconds = float64(stat["throttled_usec"]) / 1e6
+	return s, nil
+}
+
+// readCgroupV1Stats 读取 cgroup v1 的 memory.limit_in_bytes、memory.usage_in_bytes、cpu.cfs_quota_us、cpu.cfs_period_us 和 cpu.stat
+func readCgroupV1Stats(memoryDir, cpuDir, path string) (cgroupStats, error) {
+	s := cgroupStats{path: path, memoryMax: math.NaN(), cpuLimit: math.NaN()}
+	usage, err := readCgroupUint(filepath.Join(memoryDir, "memory.usage_in_bytes"))
+	if err != nil {
+		return cgroupStats{}, err
+	}
+	s.memoryCurrent = float64(usage)
+	if limit, err := readCgroupUint(filepath.Join(memoryDir, "memory.limit_in_bytes")); err == nil && limit < cgroupV1Unlimited {
+		s.memoryMax = float64(limit)
+	}
+	// 没有限制时 cfs_quota_us 为 -1，解析失败即视为没有限制
+	quota, err1 := readCgroupUint(filepath.Join(cpuDir, "cpu.cfs_quota_us"))
+	period, err2 := readCgroupUint(filepath.Join(cpuDir, "cpu.cfs_period_us"))
+	if err1 == nil && err2 == nil && period > 0 {
+		s.cpuLimit = float64(quota) / float64(period)
+	}
+	stat, err := readCgroupKeyValues(filepath.Join(cpuDir, "cpu.stat"))
+	if err != nil {
+		return cgroupStats{}, err
+	}
+	s.periods = stat["nr_periods"]
+	s.throttledPeriods = stat["nr_throttled"]
+	s.throttledSeconds = float64(stat["throttled_time"]) / 1e9
+	return s, nil
+}
+
+// readCgroupUint 读取只有一个无符号整数的 cgroup 文件，值为 max 时返回错误
+func readCgroupUint(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(bytes.TrimSpace(content)), 10, 64)
+}
+
+// readCgroupKeyValues 读取 cpu.stat 这类每行为 "key value" 的 cgroup 文件
+func readCgroupKeyValues(path string) (map[string]uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(string(fields[1]), 10, 64); err == nil {
+			values[string(fields[0])] = v
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readCgroupStats(pid int32) (cgroupStats, error) {
+	return cgroupStats{}, errors.ErrUnsupported
+}
@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// CheckConfig 按 New 的方式解析并校验 opts 和配置文件，输出解析后的分组和匹配规则，不扫描进程也不启动 HTTP 服务
+// 用于在 CI 中上线前检查配置，校验失败时返回非零退出码
+func CheckConfig(w io.Writer, opts Options) int {
+	c, err := New(opts)
+	if err != nil {
+		fmt.Fprintf(w, "FAIL: %v\n", err)
+		return 1
+	}
+
+	targets := c.targets.Load().list
+	fmt.Fprintf(w, "%d process groups\n", len(targets))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, t := range targets {
+		fmt.Fprintf(tw, "%s\t%s\n", t.Name, describeTarget(t))
+	}
+	tw.Flush()
+
+	if len(c.constLabels) > 0 {
+		labels := make([]string, 0, len(c.constLabels))
+		for _, name := range slices.Sorted(maps.Keys(c.constLabels)) {
+			labels = append(labels, name+"="+c.constLabels[name])
+		}
+		fmt.Fprintf(w, "Labels: %s\n", strings.Join(labels, ","))
+	}
+	var enabled []string
+	for _, name := range slices.Sorted(maps.Keys(c.collectors)) {
+		if c.collectors[name] {
+			enabled = append(enabled, name)
+		}
+	}
+	fmt.Fprintf(w, "Collectors: %s\n", strings.Join(enabled, ","))
+	fmt.Fprintln(w, "Config OK")
+	return 0
+}
+
+// describeTarget 分组的选择方式和附加配置，例如 match: name~nginx(substring) priority=1
+func describeTarget(t Target) string {
+	var parts []string
+	switch {
+	case t.Service != "":
+		parts = append(parts, "service: "+t.Service)
+	case t.PIDFile != "":
+		parts = append(parts, "pidfile: "+t.PIDFile)
+	default:
+		parts = append(parts, "match: "+t.Rule.String())
+	}
+	if t.NameTemplate != nil {
+		parts = append(parts, "name_template")
+	}
+	if t.Priority != 0 {
+		parts = append(parts, fmt.Sprintf("priority=%d", t.Priority))
+	}
+	if t.MinInstances > 0 {
+		parts = append(parts, fmt.Sprintf("min_instances=%d", t.MinInstances))
+	}
+	if t.Parent != "" {
+		parts = append(parts, "parent="+t.Parent)
+	}
+	if len(t.DependsOn) > 0 {
+		parts = append(parts, "depends_on="+strings.Join(t.DependsOn, ","))
+	}
+	if t.ExpectedCmdline != nil {
+		parts = append(parts, fmt.Sprintf("expected_cmdline=%q", t.ExpectedCmdline.String()))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// adoptChildren 将不属于任何分组的进程计入最近的已匹配祖先进程所在的分组
+// 与 ncabatoff/process-exporter 的 -children 一致：子进程自己匹配了分组时以自己的分组为准
+func (c *ProcessCollector) adoptChildren(cache map[int32]CachedProcess, unmatched []*process.Process) {
+	if len(unmatched) == 0 || len(cache) == 0 {
+		return
+	}
+	parents := make(map[int32]int32, len(unmatched))
+	procs := make(map[int32]*process.Process, len(unmatched))
+	for _, p := range unmatched {
+		if ppid, err := p.Ppid(); err == nil {
+			parents[p.Pid] = ppid
+			procs[p.Pid] = p
+		}
+	}
+
+	for pid, p := range procs {
+		// 沿父进程链向上找到第一个已匹配的祖先，链上都是未匹配的进程
+		ancestor, ok := int32(0), false
+		for cur, depth := parents[pid], 0; cur > 0 && depth < len(parents); depth++ {
+			if _, matched := cache[cur]; matched {
+				ancestor, ok = cur, true
+				break
+			}
+			next, unmatchedParent := parents[cur]
+			if !unmatchedParent {
+				break
+			}
+			cur = next
+		}
+		if !ok {
+			continue
+		}
+		if cached, ok := c.childOf(p, cache[ancestor]); ok {
+			cache[pid] = cached
+		}
+	}
+}
+
+// adoptChild 处理进程事件中新出现的进程，父进程已在缓存中时计入父进程的分组
+func (c *ProcessCollector) adoptChild(p *process.Process, cache map[int32]CachedProcess) (CachedProcess, bool) {
+	ppid, err := p.Ppid()
+	if err != nil {
+		return CachedProcess{}, false
+	}
+	parent, ok := cache[ppid]
+	if !ok {
+		return CachedProcess{}, false
+	}
+	return c.childOf(p, parent)
+}
+
+// childOf 读取计入 parent 所在分组的子进程
+func (c *ProcessCollector) childOf(p *process.Process, parent CachedProcess) (CachedProcess, bool) {
+	name, err := p.Name()
+	if err != nil {
+		c.telemetry.observeError(err)
+		return CachedProcess{}, false
+	}
+	target, ok := c.targets.Load().byName[parent.Group]
+	if !ok {
+		// 名称模板生成的分组
+		target = Target{Name: parent.Group}
+	}
+	return c.buildCachedProcess(p, name, target)
+}
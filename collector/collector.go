@@ -0,0 +1,1822 @@
+// Package collector 定时刷新进程缓存、只采集匹配进程的采集器（cached 模式），导出 process_* 指标
+// process-exporter 的命令行只是它的一层包装，其他程序可以通过 New 和 Options 直接嵌入
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// noProcessPID 没有进程的分组输出 process_up 0 时的 pid 标签值
+// Prometheus 不保存空标签，用 0（不会是用户进程）保持与有进程时相同的标签
+const noProcessPID = "0"
+
+// CachedProcess 包装进程对象和预先获取的静态信息（如名称）
+// 避免每次采集都去读 /proc/pid/comm
+type CachedProcess struct {
+	Proc *process.Process
+	Name string
+
+	// Group 匹配到的目标名称，即进程所属的分组
+	Group string
+	// StartTime 进程启动时间（毫秒），与 PID 一起唯一标识一次进程运行
+	StartTime int64
+
+	// CPUTime 刷新缓存时读取的累计 CPU 时间（user+system 秒），用于计算两次刷新之间的使用量
+	CPUTime float64
+	// SampledAt CPUTime 的采样时间
+	SampledAt time.Time
+
+	// CmdlineMismatch 命令行不匹配分组配置的 expected_cmdline，CmdlineChecked 为 false 时表示未检查
+	CmdlineMismatch bool
+	CmdlineChecked  bool
+
+	// WorkingSet 上一次刷新以来被访问过的内存，WorkingSetValid 为 false 时无意义
+	WorkingSet      uint64
+	WorkingSetValid bool
+	// RefsCleared 本次刷新是否已清除 referenced 标记，下次刷新才能得到有效的工作集
+	RefsCleared bool
+
+	// Labels 标注目录中匹配到该进程的标签
+	Labels map[string]string
+	// Env 从环境变量读取的标签（配置文件的 env_labels），未配置或没有设置对应变量时为空
+	Env map[string]string
+
+	// PPID 父进程，只在分组配置了 parent 或开启 tree 采集项时读取
+	PPID int32
+	// TreeRoot 父进程不在缓存中，TreeCPU、TreeRSS 和 TreeProcs 为以该进程为根的整棵进程树的汇总，只在开启 tree 采集项时计算
+	TreeRoot  bool
+	TreeCPU   float64
+	TreeRSS   uint64
+	TreeProcs int
+	// Orphaned 父进程不属于分组配置的 parent 分组，OrphanChecked 为 false 时表示未检查
+	Orphaned      bool
+	OrphanChecked bool
+	// ZombieChildren 未被回收的子进程数量，只在开启 zombies 采集项时读取
+	ZombieChildren int
+
+	// LastCPU 刷新时进程最后运行所在的 CPU，-1 表示未读取，只在开启 cpufreq 采集项时读取
+	LastCPU int
+	// FreqCPUTime 按频率加权的累计 CPU 时间（Hz·秒），FreqSampledTime 为参与加权的 CPU 时间
+	// 两者的 rate 相除得到进程运行期间的平均频率
+	FreqCPUTime     float64
+	FreqSampledTime float64
+
+	// RSS 刷新时读取的 VmRSS，用于统计分组 RSS 峰值，只在开启 rsspeak 采集项时读取
+	RSS uint64
+
+	// Exec auditd 日志中该进程的 execve 记录，ExecParent 为执行 execve 时父进程的名称
+	Exec       *execRecord
+	ExecParent string
+}
+
+// ProcessCollector 定时刷新匹配目标的进程缓存，抓取时只采集缓存中的进程，由 New 创建
+type ProcessCollector struct {
+	// 目标列表和匹配器，配置重载时整体原子替换
+	targets atomic.Pointer[targetSet]
+	// 创建时的参数，Start 和 RegisterHandlers 使用
+	opts Options
+	// 重载配置文件后替换 targets，New 创建时不为 nil
+	reloader *Reloader
+	// 附加在所有指标上的常量标签（Options.Labels、配置文件中的 labels 和分片标签）
+	constLabels prometheus.Labels
+
+	// 缓存相关
+	cachedProcs map[int32]CachedProcess // PID -> Process 映射
+	rwMutex     sync.RWMutex            // 读写锁保护 cachedProcs
+
+	// 串行化缓存刷新（定时刷新和配置重载可能同时触发）
+	refreshMu sync.Mutex
+	// 已经由退出事件记录过存活时间的进程，PID -> 启动时间，由 refreshMu 保护
+	// 内核在进程变为僵尸时就发出退出事件，回收之前全量扫描仍能看到它，据此避免重新加入缓存后重复记录
+	exited map[int32]int64
+	// 曾经出现过进程的分组，分组第一次出现进程不算重启
+	seenGroups map[string]struct{}
+	restarts   *restartTracker
+	cpuRecs    *cpuRecommender
+	cpuLoads   *cpuLoadTracker
+	// 分组进程数达到期望值的累计时长
+	availability *availabilityTracker
+	rssPeaks     *rssPeakTracker
+	// 进程树的 CPU 时间，由 refreshMu 保护
+	treeCPUs  *treeCPUTracker
+	telemetry *telemetry
+	// 已退出进程的存活时间分布，按分组统计
+	lifetimes *prometheus.HistogramVec
+	// 按进程名称和采集项统计的读取失败次数
+	collectErrors *prometheus.CounterVec
+
+	// 最近一次刷新时 Windows 服务的进程，PID -> 服务名称，由 refreshMu 保护
+	servicePIDs map[int32]string
+	// 最近一次刷新时 PID 文件中的进程，PID -> 分组名称，由 refreshMu 保护
+	pidFilePIDs map[int32]string
+	// 最近一次刷新时各 PID 文件的状态，分组名称 -> 状态
+	pidFiles atomic.Pointer[map[string]pidFileState]
+	// 不属于任何分组的子进程是否计入父进程（或更上层祖先进程）所在的分组
+	children bool
+	// 是否开启工作集估算（需要写 /proc/pid/clear_refs，默认关闭）
+	workingSet bool
+	// 当前实例负责的 PID 分片
+	shard shard
+	// 扫描时直接跳过的 UID 和 cgroup
+	exclusions scanExclusions
+	// 进程标注的 drop-in 目录，为空时不加载
+	annotationsDir string
+	// 最近一次成功加载的标注，刷新时更新，采集时读取
+	annotations atomic.Pointer[annotationSet]
+	// auditd 日志中的 execve 记录，为 nil 时不关联
+	audit *auditIndex
+	// 后台采集的指标，为 nil 时每次抓取立即采集
+	samples atomic.Pointer[sampleSet]
+	// 按刷新时的 fd 数推算耗尽 RLIMIT_NOFILE 的时间，未开启 fdexhaustion 采集项时为 nil
+	fdExhaustion *fdExhaustionTracker
+	// CPU 和内存占用最高的进程，未开启 -top.n 时为 nil
+	top *topTracker
+	// 每个分组最多输出多少个进程的进程级序列，0 表示不限制
+	maxProcsPerGroup int
+	// 最近一次采集中属于超过上限的分组的进程 PID，由 truncatingGatherer 聚合
+	truncated atomic.Pointer[map[string]struct{}]
+	// 开启的采集项
+	collectors enabledCollectors
+	// 采集时并发的 worker 数量
+	concurrency int
+	// gopsutil 的 Process 不能被并发使用，多个抓取同时到达时串行采集
+	// 用容量为 1 的信号量而不是 Mutex，排队等待的抓取可以在截止时间到达时放弃
+	collectSem chan struct{}
+	// 写入监控路径的字节数，未配置监控路径时为 nil
+	pathWrites *pathWriteTracker
+	// TCP 收发字节数，未开启 netbytes 采集项时为 nil
+	netBytes *netBytesTracker
+	// 按 CPU 拆分的 CPU 时间，未开启 percpu 采集项时为 nil
+	perCPU *perCPUTracker
+	// 空闲连接的统计阈值，从小到大排序
+	idleThresholds []time.Duration
+	// 映射文件大小不小于该值才输出 process_mmap_file_bytes
+	mmapMinBytes uint64
+	// envLabels 标签名 -> 环境变量名，新进程加入缓存时读取
+	envLabels map[string]string
+	// groupEnv 分组 -> 最近一个带环境变量标签的进程的标签，没有进程的分组输出 process_up 0 时使用，由 rwMutex 保护
+	groupEnv map[string]map[string]string
+	// 本次采集通过 inet_diag 读取的 TCP 连接及读取错误，由 collectSem 保护
+	tcpSockets    map[uint64]tcpSocket
+	tcpSocketsErr error
+
+	// 指标描述符
+	up, cpuUser, cpuSystem, memoryRSS, memoryVMS, numThreads, openFDs, startTime *prometheus.Desc
+	flapping, cpuRecommendation, memoryWorkingSet, cmdlineMismatch               *prometheus.Desc
+	dependencySatisfied, majorPageFaults, minorPageFaults                        *prometheus.Desc
+	ioReadBytes, ioWriteBytes, ioReadSyscalls, ioWriteSyscalls                   *prometheus.Desc
+	memoryPSS, memoryUSS, memorySwap, networkConnections, ioPriority             *prometheus.Desc
+	zombies, orphaned, cpuFrequencyCycles, cpuFrequencySampled                   *prometheus.Desc
+	rlimitSoft, rlimitHard, execInfo, restartsTotal                              *prometheus.Desc
+	scrapeComplete, collectorSuccess, cpuLoad, memoryRSSPeak, groupRSSPeak       *prometheus.Desc
+	idleConnections, availableSeconds, observedSeconds, pathWriteBytes           *prometheus.Desc
+	openHandles, memoryPrivate, ioOtherBytes, ioOtherOperations                  *prometheus.Desc
+	mmapFileBytes, nodeLoad1, nodeMemoryAvailable, nodeCPUs                      *prometheus.Desc
+	fdsByType, networkReceiveBytes, networkTransmitBytes                         *prometheus.Desc
+	runDelay, timeslices, affinityCPUs, cpuByCore, blkioDelay                    *prometheus.Desc
+	cgroupMemoryMax, cgroupMemoryCurrent, cgroupCPULimit                         *prometheus.Desc
+	cgroupPeriods, cgroupThrottledPeriods, cgroupThrottledSeconds                *prometheus.Desc
+	collectDuration, collectSuccess                                              *prometheus.Desc
+	groupTruncated, fdsExhaustion, pidFileStale, topCPU, topRSS                  *prometheus.Desc
+	parentPID, treeCPU, treeRSS, treeProcs, groupNumProcs                        *prometheus.Desc
+	listenPorts, contextSwitches, threadCPU, threadState, state                  *prometheus.Desc
+}
+
+func newProcessCollector(targets []Target, restarts *restartTracker, cpuRecs *cpuRecommender) *ProcessCollector {
+	c := &ProcessCollector{
+		cachedProcs:  make(map[int32]CachedProcess),
+		seenGroups:   make(map[string]struct{}),
+		restarts:     restarts,
+		concurrency:  1,
+		collectSem:   make(chan struct{}, 1),
+		collectors:   defaultCollectors(),
+		cpuRecs:      cpuRecs,
+		cpuLoads:     newCPULoadTracker(),
+		availability: newAvailabilityTracker(),
+		rssPeaks:     newRSSPeakTracker(),
+		treeCPUs:     newTreeCPUTracker(),
+		telemetry:    newTelemetry(),
+		lifetimes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "process_lifetime_seconds",
+			Help: "How long processes of the group lived before they exited.",
+			// 1s 到 1 周
+			Buckets: []float64{1, 5, 30, 60, 300, 1800, 3600, 6 * 3600, 24 * 3600, 7 * 24 * 3600},
+		}, []string{"name"}),
+		collectErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "process_collect_errors_total",
+			Help: "Errors while reading process information, by process name and collector, e.g. permission denied for /proc/pid/fd. Processes exiting during the scrape are not counted.",
+		}, []string{"process_name", "collector"}),
+		collectSuccess: prometheus.NewDesc(
+			"process_collect_success", "Whether every enabled collector succeeded for the process in this scrape (1) or at least one failed (0), see process_collect_errors_total for which one.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		up: prometheus.NewDesc(
+			"process_up", "Whether the process is running (1) or not (0). Configured groups without any process are exported as 0 with the group name as process_name and pid 0.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		cpuUser: prometheus.NewDesc(
+			"process_cpu_user_seconds_total", "Total user CPU time spent in seconds.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		cpuSystem: prometheus.NewDesc(
+			"process_cpu_system_seconds_total", "Total system CPU time spent in seconds.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		memoryRSS: prometheus.NewDesc(
+			"process_memory_rss_bytes", "Resident memory size in bytes.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		memoryVMS: prometheus.NewDesc(
+			"process_memory_vms_bytes", "Virtual memory size in bytes.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		numThreads: prometheus.NewDesc(
+			"process_num_threads", "Total number of threads.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		openFDs: prometheus.NewDesc(
+			"process_open_fds", "Number of open file descriptors.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		openHandles: prometheus.NewDesc(
+			"process_open_handles", "Number of open handles of the process. Windows only, where process_open_fds is not meaningful.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		fdsExhaustion: prometheus.NewDesc(
+			"process_fds_exhaustion_seconds", "Projected seconds until the process reaches its soft RLIMIT_NOFILE, extrapolating the linear trend of open file descriptors over -fds.exhaustion-window. Only exported while the count is growing.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		groupNumProcs: prometheus.NewDesc(
+			"process_namegroup_num_procs", "Number of live processes in the group at the last cache refresh, 0 when none match. Alert on it instead of counting per-process series.",
+			[]string{"name"}, nil,
+		),
+		parentPID: prometheus.NewDesc(
+			"process_parent_pid", "PID of the parent process at the last cache refresh.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		treeCPU: prometheus.NewDesc(
+			"process_tree_cpu_seconds_total", "CPU time (user+system) of the process and all of its descendants, matched or not, at the last cache refresh, plus the CPU time descendants had used when they exited or left the tree, so it only goes up. Only exported for processes whose parent is not monitored.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		treeRSS: prometheus.NewDesc(
+			"process_tree_memory_rss_bytes", "Resident set size of the process and all of its running descendants, matched or not, at the last cache refresh. Only exported for processes whose parent is not monitored.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		treeProcs: prometheus.NewDesc(
+			"process_tree_num_procs", "Number of processes in the tree rooted at the process, including itself, at the last cache refresh. Only exported for processes whose parent is not monitored.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		topCPU: prometheus.NewDesc(
+			"process_top_cpu_usage_cores", "CPU usage in cores between the last two cache refreshes of one of the -top.n processes using the most CPU (reason=\"top_cpu\") or memory (reason=\"top_mem\"), whether or not it matches a configured group.",
+			[]string{"reason", "process_name", "pid"}, nil,
+		),
+		topRSS: prometheus.NewDesc(
+			"process_top_memory_rss_bytes", "Resident set size at the last cache refresh of one of the -top.n processes using the most CPU (reason=\"top_cpu\") or memory (reason=\"top_mem\"), whether or not it matches a configured group.",
+			[]string{"reason", "process_name", "pid"}, nil,
+		),
+		pidFileStale: prometheus.NewDesc(
+			"process_pidfile_stale", "Whether the PID file of the group is missing, invalid, or points to a process that no longer exists or was started after the file was written (1). Only exported for groups selected by -pidfile.",
+			[]string{"name"}, nil,
+		),
+		groupTruncated: prometheus.NewDesc(
+			"process_group_truncated", "Whether the group has more processes than -max-procs-per-group (1), in which case its per-process series are replaced by series aggregated without the pid label.",
+			[]string{"name"}, nil,
+		),
+		nodeLoad1: prometheus.NewDesc(
+			"process_node_load1", "1 minute load average of the host.",
+			nil, nil,
+		),
+		nodeMemoryAvailable: prometheus.NewDesc(
+			"process_node_memory_available_bytes", "Memory available for starting new applications without swapping (MemAvailable on Linux).",
+			nil, nil,
+		),
+		nodeCPUs: prometheus.NewDesc(
+			"process_node_cpus", "Number of logical CPUs of the host.",
+			nil, nil,
+		),
+		mmapFileBytes: prometheus.NewDesc(
+			"process_mmap_file_bytes", "Address space mapped from the file by the process, only files of at least -mmaps.min-size are listed.",
+			[]string{"process_name", "pid", "path"}, nil,
+		),
+		fdsByType: prometheus.NewDesc(
+			"process_fds", "Open file descriptors of the process by type (socket, pipe, file, anon_inode or other).",
+			[]string{"process_name", "pid", "type"}, nil,
+		),
+		memoryPrivate: prometheus.NewDesc(
+			"process_memory_private_bytes", "Committed memory that cannot be shared with other processes (Private Bytes). Windows only.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		ioOtherBytes: prometheus.NewDesc(
+			"process_io_other_bytes_total", "Total bytes transferred by IO operations other than read and write, such as device control. Windows only.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		ioOtherOperations: prometheus.NewDesc(
+			"process_io_other_operations_total", "Total number of IO operations other than read and write. Windows only.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		startTime: prometheus.NewDesc(
+			"process_start_time_seconds", "Start time of the process since unix epoch in seconds.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		majorPageFaults: prometheus.NewDesc(
+			"process_major_page_faults_total", "Total number of major page faults, which required loading a page from disk.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		minorPageFaults: prometheus.NewDesc(
+			"process_minor_page_faults_total", "Total number of minor page faults, which did not require loading a page from disk.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		ioReadBytes: prometheus.NewDesc(
+			"process_io_read_bytes_total", "Total number of bytes read from the storage layer.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		ioWriteBytes: prometheus.NewDesc(
+			"process_io_write_bytes_total", "Total number of bytes written to the storage layer.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		ioReadSyscalls: prometheus.NewDesc(
+			"process_io_read_syscalls_total", "Total number of read syscalls.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		ioWriteSyscalls: prometheus.NewDesc(
+			"process_io_write_syscalls_total", "Total number of write syscalls.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		flapping: prometheus.NewDesc(
+			"process_flapping", "Whether the process group restarted too often within the flapping window (1) or not (0).",
+			[]string{"name"}, nil,
+		),
+		memoryPSS: prometheus.NewDesc(
+			"process_memory_pss_bytes", "Proportional set size in bytes, shared pages divided among the processes sharing them.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		memoryUSS: prometheus.NewDesc(
+			"process_memory_uss_bytes", "Unique set size in bytes, memory private to the process.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		memorySwap: prometheus.NewDesc(
+			"process_memory_swap_bytes", "Anonymous memory swapped out in bytes (VmSwap).",
+			[]string{"process_name", "pid"}, nil,
+		),
+		memoryRSSPeak: prometheus.NewDesc(
+			"process_memory_rss_peak_bytes", "Peak resident memory size of the process since it started in bytes (VmHWM), including spikes between scrapes.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		groupRSSPeak: prometheus.NewDesc(
+			"process_group_memory_rss_peak_bytes", "Peak total resident memory of the group observed at cache refreshes since the exporter started in bytes.",
+			[]string{"name"}, nil,
+		),
+		networkConnections: prometheus.NewDesc(
+			"process_network_connections", "Number of sockets held by the process by protocol.",
+			[]string{"process_name", "pid", "proto"}, nil,
+		),
+		idleConnections: prometheus.NewDesc(
+			"process_network_idle_connections", "Number of TCP connections of the process with no data sent or received for at least idle_seconds, from inet_diag.",
+			[]string{"process_name", "pid", "idle_seconds"}, nil,
+		),
+		networkReceiveBytes: prometheus.NewDesc(
+			"process_network_receive_bytes_total", "Bytes received on the TCP connections held by the process, from tcpi_bytes_received via inet_diag. Bytes after the last collection of a closed connection are not counted.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		networkTransmitBytes: prometheus.NewDesc(
+			"process_network_transmit_bytes_total", "Bytes sent and acknowledged by the peer on the TCP connections held by the process, from tcpi_bytes_acked via inet_diag. Bytes after the last collection of a closed connection are not counted.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		pathWriteBytes: prometheus.NewDesc(
+			"process_path_write_estimated_bytes_total", "Estimated bytes written by the process to files beneath the watched path, from the growth of file offsets in /proc/pid/fdinfo between collections (suits append-only files such as logs). Not an exact count: files opened and closed between collections, pwrite, and writes through mmap are missed, and a forward seek is counted as written.",
+			[]string{"process_name", "pid", "path"}, nil,
+		),
+		listenPorts: prometheus.NewDesc(
+			"process_listen_ports", "Ports the process is listening on (TCP in LISTEN state, unconnected UDP), always 1.",
+			[]string{"process_name", "pid", "port", "proto"}, nil,
+		),
+		contextSwitches: prometheus.NewDesc(
+			"process_context_switches_total", "Total number of context switches by type. Voluntary switches happen whenever the process blocks (IO, locks, sleeps) and involuntary ones when it is preempted. This is not a wakeup rate: timer and interrupt wakeups are not counted separately.",
+			[]string{"process_name", "pid", "type"}, nil,
+		),
+		runDelay: prometheus.NewDesc(
+			"process_cpu_run_delay_seconds_total", "Total time the threads of the process spent runnable but waiting in the run queue for a CPU, from /proc/pid/task/tid/schedstat. This is run queue delay only, not off-CPU time: time spent sleeping or blocked is not included.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		blkioDelay: prometheus.NewDesc(
+			"process_blkio_delay_seconds_total", "Total time the main thread of the process spent waiting for block IO to complete, from delayacct_blkio_ticks in /proc/pid/stat. Requires kernel delay accounting.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		timeslices: prometheus.NewDesc(
+			"process_cpu_timeslices_total", "Total number of times the threads of the process were scheduled onto a CPU. The rate of process_cpu_run_delay_seconds_total divided by its rate is the average wait per timeslice.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		affinityCPUs: prometheus.NewDesc(
+			"process_cpu_affinity_cpus", "Number of CPUs the process (main thread) is allowed to run on, from sched_getaffinity.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		cpuByCore: prometheus.NewDesc(
+			"process_cpu_core_seconds_total", "CPU time of the process split by CPU, attributing the CPU time each thread used between collections to the CPU it last ran on.",
+			[]string{"process_name", "pid", "cpu"}, nil,
+		),
+		collectDuration: prometheus.NewDesc(
+			"process_collect_duration_seconds", "Time spent reading the process in the current scrape by collector. Reads shared by several collectors are attributed to the first enabled one.",
+			[]string{"process_name", "pid", "collector"}, nil,
+		),
+		cgroupMemoryMax: prometheus.NewDesc(
+			"process_cgroup_memory_max_bytes", "Memory limit of the cgroup the process belongs to (memory.max or memory.limit_in_bytes), absent when unlimited.",
+			[]string{"process_name", "pid", "cgroup"}, nil,
+		),
+		cgroupMemoryCurrent: prometheus.NewDesc(
+			"process_cgroup_memory_current_bytes", "Memory usage including page cache of the cgroup the process belongs to (memory.current or memory.usage_in_bytes).",
+			[]string{"process_name", "pid", "cgroup"}, nil,
+		),
+		cgroupCPULimit: prometheus.NewDesc(
+			"process_cgroup_cpu_limit_cpus", "CPU quota of the cgroup the process belongs to divided by its period (cpu.max or cpu.cfs_quota_us), absent when unlimited.",
+			[]string{"process_name", "pid", "cgroup"}, nil,
+		),
+		cgroupPeriods: prometheus.NewDesc(
+			"process_cgroup_cpu_periods_total", "Number of enforcement periods elapsed in the cgroup the process belongs to, from cpu.stat.",
+			[]string{"process_name", "pid", "cgroup"}, nil,
+		),
+		cgroupThrottledPeriods: prometheus.NewDesc(
+			"process_cgroup_cpu_throttled_periods_total", "Number of enforcement periods in which the cgroup the process belongs to was throttled, from cpu.stat.",
+			[]string{"process_name", "pid", "cgroup"}, nil,
+		),
+		cgroupThrottledSeconds: prometheus.NewDesc(
+			"process_cgroup_cpu_throttled_seconds_total", "Total time the cgroup the process belongs to was throttled, from cpu.stat.",
+			[]string{"process_name", "pid", "cgroup"}, nil,
+		),
+		cpuFrequencyCycles: prometheus.NewDesc(
+			"process_cpu_frequency_hertz_seconds_total", "CPU time multiplied by the frequency of the core the process last ran on, sampled at each cache refresh. This is an approximation from /proc CPU time deltas, not perf sampling: frequency changes between refreshes and time spent on other cores are not seen. Divide its rate by the rate of process_cpu_frequency_sampled_seconds_total to get the average frequency while running.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		cpuFrequencySampled: prometheus.NewDesc(
+			"process_cpu_frequency_sampled_seconds_total", "CPU time for which a core frequency sample was available.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		rlimitSoft: prometheus.NewDesc(
+			"process_rlimit_soft", "Soft resource limit of the process, +Inf when unlimited. Compare with process_open_fds for resource=\"nofile\".",
+			[]string{"process_name", "pid", "resource"}, nil,
+		),
+		rlimitHard: prometheus.NewDesc(
+			"process_rlimit_hard", "Hard resource limit of the process, +Inf when unlimited.",
+			[]string{"process_name", "pid", "resource"}, nil,
+		),
+		execInfo: prometheus.NewDesc(
+			"process_exec_info", "Who launched the process according to the auditd execve record: login user (auid), uid, audit session, tty and the parent process name. Always 1.",
+			[]string{"process_name", "pid", "login_user", "uid", "session", "tty", "parent"}, nil,
+		),
+		restartsTotal: prometheus.NewDesc(
+			"process_restarts_total", "Number of times a new process (new PID or start time) appeared in the group since the exporter started.",
+			[]string{"name"}, nil,
+		),
+		scrapeComplete: prometheus.NewDesc(
+			"process_exporter_scrape_complete", "Whether every enabled collector succeeded for every process in this scrape (1) or not (0). Processes exiting during the scrape do not count as failures.",
+			nil, nil,
+		),
+		collectorSuccess: prometheus.NewDesc(
+			"process_exporter_collector_success_ratio", "Ratio of processes for which the collector succeeded in this scrape.",
+			[]string{"collector"}, nil,
+		),
+		cpuLoad: prometheus.NewDesc(
+			"process_cpu_usage_ewma_cores", "Exponentially weighted moving average of the CPU usage of the group in cores over the window (1m, 5m, 15m), like the system load average.",
+			[]string{"name", "window"}, nil,
+		),
+		availableSeconds: prometheus.NewDesc(
+			"process_group_available_seconds_total", "Total seconds the group had at least its expected number of processes (min_instances, default 1) running, accumulated at cache refreshes since the exporter started.",
+			[]string{"name"}, nil,
+		),
+		observedSeconds: prometheus.NewDesc(
+			"process_group_observed_seconds_total", "Total seconds the group was observed since the exporter started. Divide the increase of process_group_available_seconds_total by the increase of this counter to get availability.",
+			[]string{"name"}, nil,
+		),
+		zombies: prometheus.NewDesc(
+			"process_zombies", "Number of zombie children of the processes in the group, which exited but were not reaped.",
+			[]string{"name"}, nil,
+		),
+		orphaned: prometheus.NewDesc(
+			"process_orphaned", "Whether the parent of the process is not in the configured parent group (1) or is (0).",
+			[]string{"process_name", "pid"}, nil,
+		),
+		state: prometheus.NewDesc(
+			"process_state", "Current state of the process (running, sleep, blocked, zombie, stop, idle, ...), always 1.",
+			[]string{"process_name", "pid", "state"}, nil,
+		),
+		threadCPU: prometheus.NewDesc(
+			"process_thread_cpu_seconds_total", "Total CPU time spent by the thread in seconds by mode.",
+			[]string{"process_name", "pid", "tid", "thread_name", "mode"}, nil,
+		),
+		threadState: prometheus.NewDesc(
+			"process_thread_state", "Current state of the thread, always 1.",
+			[]string{"process_name", "pid", "tid", "thread_name", "state"}, nil,
+		),
+		ioPriority: prometheus.NewDesc(
+			"process_io_priority", "I/O scheduling priority of the process (0 highest, 7 lowest) by I/O scheduling class.",
+			[]string{"process_name", "pid", "class"}, nil,
+		),
+		memoryWorkingSet: prometheus.NewDesc(
+			"process_memory_working_set_bytes", "Working set size in bytes. On Linux estimated from memory referenced since the previous cache refresh (-working-set), on Windows reported by the system.",
+			[]string{"process_name", "pid"}, nil,
+		),
+		cmdlineMismatch: prometheus.NewDesc(
+			"process_cmdline_mismatch", "Whether the process command line does not match the expected_cmdline of its group (1) or matches (0).",
+			[]string{"process_name", "pid"}, nil,
+		),
+		dependencySatisfied: prometheus.NewDesc(
+			"process_dependency_satisfied", "Whether the dependency group of a process group has at least one running process (1) or not (0).",
+			[]string{"group", "dependency"}, nil,
+		),
+		cpuRecommendation: prometheus.NewDesc(
+			"process_cpu_recommendation_cores", "Recommended CPU quota in cores for the process group, p95 of observed usage over the recommendation window.",
+			[]string{"name"}, nil,
+		),
+	}
+	c.targets.Store(newTargetSet(targets))
+	return c
+}
+
+// SetTargets 替换目标列表，并立即刷新缓存使其生效
+func (c *ProcessCollector) SetTargets(targets []Target) {
+	c.targets.Store(newTargetSet(targets))
+	c.refreshProcessCache()
+}
+
+// StartCacheUpdater 启动后台协程，定时刷新进程列表
+// interval: 全量扫描的间隔，建议 30s - 60s
+func (c *ProcessCollector) StartCacheUpdater(ctx context.Context, interval time.Duration) {
+	// 立即执行一次初始化
+	c.refreshProcessCache()
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.refreshProcessCache()
+			}
+		}
+	}()
+}
+
+// refreshProcessCache 执行全量扫描并更新缓存
+// 这是最耗资源的操作，现在只在后台低频执行
+func (c *ProcessCollector) refreshProcessCache() {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	// 统计这次刷新本身读取 /proc 的开销
+	if io, ok := measureThreadIO(c.refreshLocked); ok {
+		c.telemetry.observeRefreshIO(io)
+	}
+}
+
+// refreshLocked 执行一次全量扫描，调用方需要持有 refreshMu
+func (c *ProcessCollector) refreshLocked() {
+	start := time.Now()
+	if c.annotationsDir != "" {
+		// 加载失败时沿用上一次的标注，避免写了一半的文件导致标签抖动
+		if set, err := loadAnnotations(c.annotationsDir); err == nil {
+			c.annotations.Store(set)
+		} else {
+			slog.Error("Error loading annotations", "err", err)
+		}
+	}
+	if c.audit != nil {
+		if err := c.audit.update(); err != nil {
+			slog.Error("Error reading audit log", "err", err)
+		}
+	}
+	c.servicePIDs = nil
+	if services := c.targets.Load().services; len(services) > 0 {
+		// 部分服务查询失败时仍使用已查询到的 PID
+		pids, err := queryServicePIDs(services)
+		if err != nil {
+			slog.Warn("Error querying service processes", "err", err)
+		}
+		c.servicePIDs = pids
+	}
+	c.pidFilePIDs = nil
+	if pidFiles := c.targets.Load().pidFiles; len(pidFiles) > 0 {
+		c.pidFilePIDs = make(map[int32]string, len(pidFiles))
+		c.refreshPIDFiles(pidFiles)
+	}
+	allProcs, err := c.shard.processes()
+	if err != nil {
+		slog.Error("Error scanning processes", "err", err)
+		return
+	}
+
+	newCache := make(map[int32]CachedProcess)
+	alive := make(map[int32]struct{}, len(allProcs))
+
+	var unmatched []*process.Process
+	for _, p := range allProcs {
+		alive[p.Pid] = struct{}{}
+		if c.exclusions.excluded(p.Pid) {
+			continue
+		}
+		if cached, ok := c.newCachedProcess(p); ok {
+			if start, ok := c.exited[p.Pid]; ok && start == cached.StartTime {
+				continue
+			}
+			newCache[p.Pid] = cached
+		} else if c.children {
+			unmatched = append(unmatched, p)
+		}
+	}
+	if c.children {
+		c.adoptChildren(newCache, unmatched)
+	}
+
+	c.rwMutex.RLock()
+	oldCache := c.cachedProcs
+	c.rwMutex.RUnlock()
+	if c.workingSet {
+		c.estimateWorkingSets(oldCache, newCache)
+	}
+	markOrphans(newCache, c.targets.Load())
+	if c.audit != nil {
+		c.audit.prune(alive)
+	}
+	for pid := range c.exited {
+		if _, ok := alive[pid]; !ok {
+			delete(c.exited, pid)
+		}
+	}
+	c.recordRestarts(oldCache, newCache)
+	c.recordExits(oldCache, newCache, alive)
+	c.recordCPUUsage(oldCache, newCache)
+	if c.collectors.has("cpufreq") {
+		c.recordCPUFrequency(oldCache, newCache)
+	}
+	if c.collectors.has("rsspeak") {
+		c.recordRSSPeaks(newCache)
+	}
+	if c.fdExhaustion != nil {
+		c.recordFDs(newCache)
+	}
+	if c.top != nil {
+		c.top.update(allProcs, c.exclusions)
+	}
+	if c.collectors.has("tree") {
+		c.recordTree(newCache, allProcs)
+	}
+	c.recordAvailability(newCache)
+	if c.pathWrites != nil {
+		c.pathWrites.Prune(newCache)
+	}
+	if c.netBytes != nil {
+		c.netBytes.Prune(newCache)
+	}
+	if c.perCPU != nil {
+		c.perCPU.Prune(newCache)
+	}
+
+	var groupEnv map[string]map[string]string
+	if len(c.envLabels) > 0 {
+		groupEnv = c.recordGroupEnv(newCache)
+	}
+
+	// 只有在构建完新的 map 后才加锁替换，极大减少锁竞争时间
+	c.rwMutex.Lock()
+	c.cachedProcs = newCache
+	c.groupEnv = groupEnv
+	c.rwMutex.Unlock()
+	c.telemetry.observeRefresh(start, len(newCache))
+
+	slog.Debug("Cache refreshed", "processes", len(newCache), "duration", time.Since(start))
+}
+
+// recordGroupEnv 记录每个分组最近一个进程的环境变量标签，进程全部退出后分组的 process_up 0 仍带这些标签
+// 只保留配置中的固定分组和当前有进程的分组，由模板生成的分组没有进程后不再输出，不需要保留
+func (c *ProcessCollector) recordGroupEnv(newCache map[int32]CachedProcess) map[string]map[string]string {
+	c.rwMutex.RLock()
+	old := c.groupEnv
+	c.rwMutex.RUnlock()
+
+	present := make(map[string]int)
+	groupEnv := make(map[string]map[string]string)
+	for _, cached := range newCache {
+		present[cached.Group]++
+		if len(cached.Env) > 0 {
+			groupEnv[cached.Group] = cached.Env
+		}
+	}
+	for _, t := range c.targets.Load().groups(present) {
+		if _, ok := groupEnv[t.Name]; !ok && old[t.Name] != nil {
+			groupEnv[t.Name] = old[t.Name]
+		}
+	}
+	return groupEnv
+}
+
+// newCachedProcess 读取进程的静态信息，进程不属于任何目标或读取失败时返回 false
+func (c *ProcessCollector) newCachedProcess(p *process.Process) (CachedProcess, bool) {
+	// 获取名称可能会失败（权限或进程刚退出），忽略错误
+	name, err := p.Name()
+	if err != nil {
+		c.telemetry.observeError(err)
+		return CachedProcess{}, false
+	}
+
+	targets := c.targets.Load()
+	if service, ok := c.servicePIDs[p.Pid]; ok {
+		return c.buildCachedProcess(p, name, targets.byName[service])
+	}
+	if group, ok := c.pidFilePIDs[p.Pid]; ok {
+		return c.buildCachedProcess(p, name, targets.byName[group])
+	}
+	target, ok := targets.match(p)
+	if !ok {
+		return CachedProcess{}, false
+	}
+	return c.buildCachedProcess(p, name, target)
+}
+
+// buildCachedProcess 读取已确定分组的进程的静态信息，读取失败时返回 false
+func (c *ProcessCollector) buildCachedProcess(p *process.Process, name string, target Target) (CachedProcess, bool) {
+	createTime, err := p.CreateTime()
+	if err != nil {
+		c.telemetry.observeError(err)
+		return CachedProcess{}, false
+	}
+	cached := CachedProcess{
+		Proc:      p,
+		Name:      name,
+		Group:     target.Name,
+		StartTime: createTime,
+		Labels:    c.annotations.Load().labelsFor(p.Pid, name),
+		LastCPU:   -1,
+	}
+	if target.ExpectedCmdline != nil {
+		// 命令行在进程生命周期内不变，只在刷新时检查
+		if cmdline, err := p.Cmdline(); err == nil {
+			cached.CmdlineMismatch = !target.ExpectedCmdline.MatchString(cmdline)
+			cached.CmdlineChecked = true
+		} else {
+			c.telemetry.observeError(err)
+		}
+	}
+	if target.Parent != "" {
+		if ppid, err := p.Ppid(); err == nil {
+			cached.PPID = ppid
+		} else {
+			c.telemetry.observeError(err)
+		}
+	}
+	if len(c.envLabels) > 0 {
+		// 读取其他用户进程的环境变量需要 root 或 CAP_SYS_PTRACE，失败时该进程不带这些标签
+		if env, err := readEnvLabels(p, c.envLabels); err == nil {
+			cached.Env = env
+		} else {
+			c.telemetry.observeError(err)
+		}
+	}
+	if c.collectors.has("zombies") {
+		if n, err := countZombieChildren(p.Pid); err == nil {
+			cached.ZombieChildren = n
+		} else {
+			c.telemetry.observeError(err)
+		}
+	}
+	if c.audit != nil {
+		if rec, ok := c.audit.lookup(p.Pid, createTime); ok {
+			cached.Exec = &rec
+			if parent, ok := c.audit.parent(rec); ok {
+				cached.ExecParent = parent
+			} else if pp, err := process.NewProcess(rec.ppid); err == nil {
+				// 父进程在日志轮转前启动，直接读取，父进程已经退出时留空
+				cached.ExecParent, _ = pp.Name()
+			}
+		}
+	}
+	if c.collectors.has("rsspeak") {
+		if _, rss, err := readRSSPeakBytes(p.Pid); err == nil {
+			cached.RSS = rss
+		} else {
+			c.telemetry.observeError(err)
+		}
+	}
+	if c.collectors.has("cpufreq") {
+		if cpu, err := readLastCPU(p.Pid); err == nil {
+			cached.LastCPU = cpu
+		} else {
+			c.telemetry.observeError(err)
+		}
+	}
+	if times, err := p.Times(); err == nil {
+		cached.CPUTime = times.User + times.System
+		cached.SampledAt = time.Now()
+	}
+	return cached, true
+}
+
+// markOrphans 检查配置了 parent 的分组，父进程需要在本次刷新的缓存中且属于 parent 分组
+func markOrphans(cache map[int32]CachedProcess, targets *targetSet) {
+	for pid, cached := range cache {
+		target, ok := targets.byName[cached.Group]
+		if !ok || target.Parent == "" || cached.PPID == 0 {
+			continue
+		}
+		parent, ok := cache[cached.PPID]
+		cached.Orphaned = !ok || parent.Group != target.Parent
+		cached.OrphanChecked = true
+		cache[pid] = cached
+	}
+}
+
+// trackProcess 进程启动（fork/exec）时增量加入缓存，不必等到下一次全量扫描
+func (c *ProcessCollector) trackProcess(pid int32) {
+	if !c.shard.owns(pid) || c.exclusions.excluded(pid) {
+		return
+	}
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		// 短命进程可能已经退出
+		return
+	}
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	cached, ok := c.newCachedProcess(p)
+
+	c.rwMutex.Lock()
+	defer c.rwMutex.Unlock()
+	if !ok && c.children {
+		cached, ok = c.adoptChild(p, c.cachedProcs)
+	}
+	old, exists := c.cachedProcs[pid]
+	if !ok {
+		// exec 后名称不再匹配任何目标
+		if exists {
+			delete(c.cachedProcs, pid)
+		}
+		return
+	}
+	if exists && old.StartTime == cached.StartTime {
+		return
+	}
+
+	if _, seen := c.seenGroups[cached.Group]; seen {
+		c.restarts.Record(cached.Group, time.Now(), newProcessEvent(cached))
+	}
+	c.seenGroups[cached.Group] = struct{}{}
+	c.cachedProcs[pid] = cached
+}
+
+// untrackProcess 进程退出时从缓存中移除
+// 与全量扫描串行，否则正在进行的扫描可能把它重新加入缓存，下一次扫描再记录一次存活时间
+func (c *ProcessCollector) untrackProcess(pid int32) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.rwMutex.Lock()
+	cached, ok := c.cachedProcs[pid]
+	delete(c.cachedProcs, pid)
+	c.rwMutex.Unlock()
+
+	if ok {
+		if c.exited == nil {
+			c.exited = make(map[int32]int64)
+		}
+		c.exited[pid] = cached.StartTime
+		c.observeLifetime(cached, time.Now())
+	}
+}
+
+// estimateWorkingSets 读取每个进程自上次刷新以来访问过的内存，然后清除 referenced 标记
+// 上次刷新没有成功清除标记的进程（比如新进程），读到的是启动以来的累计值，不作为有效工作集
+func (c *ProcessCollector) estimateWorkingSets(oldCache, newCache map[int32]CachedProcess) {
+	for pid, cached := range newCache {
+		referenced, err := readReferencedBytes(pid)
+		if err != nil {
+			c.telemetry.observeError(err)
+			continue
+		}
+		if old, ok := oldCache[pid]; ok && old.StartTime == cached.StartTime && old.RefsCleared {
+			cached.WorkingSet = referenced
+			cached.WorkingSetValid = true
+		}
+		if err := clearReferenced(pid); err != nil {
+			c.telemetry.observeError(err)
+		} else {
+			cached.RefsCleared = true
+		}
+		newCache[pid] = cached
+	}
+}
+
+// recordRestarts 对比新旧缓存，分组中出现新的进程（PID 或启动时间变化）即视为一次重启
+func (c *ProcessCollector) recordRestarts(oldCache, newCache map[int32]CachedProcess) {
+	now := time.Now()
+	started := make(map[string]struct{})
+	for pid, cached := range newCache {
+		if old, ok := oldCache[pid]; ok && old.StartTime == cached.StartTime {
+			continue
+		}
+		if _, ok := c.seenGroups[cached.Group]; ok {
+			c.restarts.Record(cached.Group, now, newProcessEvent(cached))
+		}
+		started[cached.Group] = struct{}{}
+	}
+	for group := range started {
+		c.seenGroups[group] = struct{}{}
+	}
+}
+
+// recordExits 统计两次刷新之间退出的进程的存活时间
+// 旧缓存中有、新缓存中没有且已经不在进程列表中的视为退出（仍然存活只是不再匹配的不算）
+// 退出时间按本次刷新时间计，因此存活时间最多偏大一个刷新间隔，开启 -proc-events 时更准确
+func (c *ProcessCollector) recordExits(oldCache, newCache map[int32]CachedProcess, alive map[int32]struct{}) {
+	now := time.Now()
+	for pid, old := range oldCache {
+		if cached, ok := newCache[pid]; ok && cached.StartTime == old.StartTime {
+			continue
+		}
+		if _, ok := newCache[pid]; !ok {
+			if _, ok := alive[pid]; ok {
+				continue
+			}
+		}
+		c.observeLifetime(old, now)
+	}
+}
+
+// observeLifetime 记录一个进程从启动到退出的时间
+func (c *ProcessCollector) observeLifetime(cached CachedProcess, exitedAt time.Time) {
+	lifetime := exitedAt.Sub(time.UnixMilli(cached.StartTime)).Seconds()
+	event := newProcessEvent(cached)
+	slog.Debug("Process exited", "group", cached.Group, "pid", event.PID, "event_id", event.ID(), "lifetime", lifetime)
+	// 直方图的 _count 即退出次数，exemplar 指向这次退出的进程
+	c.lifetimes.WithLabelValues(cached.Group).(prometheus.ExemplarObserver).ObserveWithExemplar(max(lifetime, 0), event.labels())
+}
+
+// recordCPUUsage 按分组汇总两次刷新之间的 CPU 使用量（核数），只统计两次都存在的进程
+func (c *ProcessCollector) recordCPUUsage(oldCache, newCache map[int32]CachedProcess) {
+	usage := make(map[string]float64)
+	for pid, cached := range newCache {
+		old, ok := oldCache[pid]
+		if !ok || old.StartTime != cached.StartTime || old.SampledAt.IsZero() || cached.SampledAt.IsZero() {
+			continue
+		}
+		elapsed := cached.SampledAt.Sub(old.SampledAt).Seconds()
+		if elapsed <= 0 {
+			continue
+		}
+		usage[cached.Group] += (cached.CPUTime - old.CPUTime) / elapsed
+	}
+
+	present := make(map[string]int)
+	for _, cached := range newCache {
+		present[cached.Group]++
+	}
+	now := time.Now()
+	for group, cores := range usage {
+		c.cpuRecs.Observe(group, now, cores)
+	}
+	c.cpuRecs.Prune(present)
+	// 没有进程的分组按 0 计入，EWMA 才会随服务停止而衰减
+	// 第一次刷新没有上一次的数据，不计入
+	if len(oldCache) > 0 {
+		for _, t := range c.targets.Load().groups(present) {
+			c.cpuLoads.Observe(t.Name, now, usage[t.Name])
+		}
+	}
+}
+
+// recordCPUFrequency 将两次刷新之间消耗的 CPU 时间按进程最后运行所在 CPU 的当前频率加权累加
+// 每个刷新间隔只采样一次频率，是对进程实际运行频率的近似，刷新间隔越短越准确
+// 同一次刷新中每个 CPU 的频率只读取一次
+func (c *ProcessCollector) recordCPUFrequency(oldCache, newCache map[int32]CachedProcess) {
+	freqs := make(map[int]float64)
+	for pid, cached := range newCache {
+		old, ok := oldCache[pid]
+		if !ok || old.StartTime != cached.StartTime {
+			continue
+		}
+		cached.FreqCPUTime = old.FreqCPUTime
+		cached.FreqSampledTime = old.FreqSampledTime
+		used := cached.CPUTime - old.CPUTime
+		if cached.LastCPU >= 0 && used > 0 && !old.SampledAt.IsZero() && !cached.SampledAt.IsZero() {
+			freq, ok := freqs[cached.LastCPU]
+			if !ok {
+				var err error
+				if freq, err = readCPUFrequency(cached.LastCPU); err != nil {
+					c.telemetry.observeError(err)
+				}
+				freqs[cached.LastCPU] = freq
+			}
+			if freq > 0 {
+				cached.FreqCPUTime += freq * used
+				cached.FreqSampledTime += used
+			}
+		}
+		newCache[pid] = cached
+	}
+}
+
+func (c *ProcessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	if c.collectors.has("cpu") {
+		ch <- c.cpuUser
+		ch <- c.cpuSystem
+	}
+	if c.collectors.has("memory") {
+		ch <- c.memoryRSS
+		ch <- c.memoryVMS
+	}
+	if c.collectors.has("swap") {
+		ch <- c.memorySwap
+	}
+	if c.collectors.has("rsspeak") {
+		ch <- c.memoryRSSPeak
+		ch <- c.groupRSSPeak
+	}
+	if c.collectors.has("state") {
+		ch <- c.state
+	}
+	if c.collectors.has("zombies") {
+		ch <- c.zombies
+	}
+	if c.collectors.has("connections") {
+		ch <- c.networkConnections
+	}
+	if c.collectors.has("ioprio") {
+		ch <- c.ioPriority
+	}
+	if c.collectors.has("listen") {
+		ch <- c.listenPorts
+	}
+	if c.collectors.has("idleconns") {
+		ch <- c.idleConnections
+	}
+	if c.collectors.has("netbytes") {
+		ch <- c.networkReceiveBytes
+		ch <- c.networkTransmitBytes
+	}
+	if c.collectors.has("pathwrites") {
+		ch <- c.pathWriteBytes
+	}
+	if c.collectors.has("ctxswitches") {
+		ch <- c.contextSwitches
+	}
+	if c.collectors.has("schedstat") {
+		ch <- c.runDelay
+		ch <- c.timeslices
+	}
+	if c.collectors.has("blkio") {
+		ch <- c.blkioDelay
+	}
+	if c.collectors.has("affinity") {
+		ch <- c.affinityCPUs
+	}
+	if c.collectors.has("percpu") {
+		ch <- c.cpuByCore
+	}
+	if c.collectors.has("collecttime") {
+		ch <- c.collectDuration
+	}
+	if c.collectors.has("cgroup") {
+		ch <- c.cgroupMemoryMax
+		ch <- c.cgroupMemoryCurrent
+		ch <- c.cgroupCPULimit
+		ch <- c.cgroupPeriods
+		ch <- c.cgroupThrottledPeriods
+		ch <- c.cgroupThrottledSeconds
+	}
+	if c.collectors.has("threadstats") {
+		ch <- c.threadCPU
+		ch <- c.threadState
+	}
+	if c.collectors.has("rlimits") {
+		ch <- c.rlimitSoft
+		ch <- c.rlimitHard
+	}
+	if c.collectors.has("cpufreq") {
+		ch <- c.cpuFrequencyCycles
+		ch <- c.cpuFrequencySampled
+	}
+	if c.collectors.has("smaps") {
+		ch <- c.memoryPSS
+		ch <- c.memoryUSS
+	}
+	if c.collectors.has("mmaps") {
+		ch <- c.mmapFileBytes
+	}
+	if c.collectors.has("fdtypes") {
+		ch <- c.fdsByType
+	}
+	if c.collectors.has("node") {
+		ch <- c.nodeLoad1
+		ch <- c.nodeMemoryAvailable
+		ch <- c.nodeCPUs
+	}
+	if c.collectors.has("threads") {
+		ch <- c.numThreads
+	}
+	if c.collectors.has("fds") {
+		ch <- c.openFDs
+	}
+	if c.collectors.has("fdexhaustion") {
+		ch <- c.fdsExhaustion
+	}
+	if c.collectors.has("tree") {
+		ch <- c.parentPID
+		ch <- c.treeCPU
+		ch <- c.treeRSS
+		ch <- c.treeProcs
+	}
+	if c.collectors.has("windows") {
+		ch <- c.openHandles
+		ch <- c.memoryPrivate
+		ch <- c.ioOtherBytes
+		ch <- c.ioOtherOperations
+	}
+	if c.collectors.has("starttime") {
+		ch <- c.startTime
+	}
+	if c.collectors.has("pagefaults") {
+		ch <- c.majorPageFaults
+		ch <- c.minorPageFaults
+	}
+	if c.collectors.has("io") {
+		ch <- c.ioReadBytes
+		ch <- c.ioWriteBytes
+		ch <- c.ioReadSyscalls
+		ch <- c.ioWriteSyscalls
+	}
+	ch <- c.groupNumProcs
+	ch <- c.flapping
+	ch <- c.restartsTotal
+	ch <- c.availableSeconds
+	ch <- c.observedSeconds
+	ch <- c.cpuRecommendation
+	ch <- c.cpuLoad
+	ch <- c.memoryWorkingSet
+	ch <- c.cmdlineMismatch
+	ch <- c.orphaned
+	if c.audit != nil {
+		ch <- c.execInfo
+	}
+	ch <- c.dependencySatisfied
+	if c.maxProcsPerGroup > 0 {
+		ch <- c.groupTruncated
+	}
+	ch <- c.pidFileStale
+	if c.top != nil {
+		ch <- c.topCPU
+		ch <- c.topRSS
+	}
+	ch <- c.scrapeComplete
+	ch <- c.collectorSuccess
+	ch <- c.collectSuccess
+	c.lifetimes.Describe(ch)
+	c.collectErrors.Describe(ch)
+	c.telemetry.Describe(ch)
+}
+
+func (c *ProcessCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(ch, time.Time{}, c.collectors)
+}
+
+// collect 开启后台采集时返回最近一次后台采集的结果，否则立即采集
+func (c *ProcessCollector) collect(ch chan<- prometheus.Metric, deadline time.Time, enabled enabledCollectors) {
+	if set := c.samples.Load(); set != nil {
+		for _, m := range set.metrics {
+			ch <- m
+		}
+		return
+	}
+	c.collectNow(ch, deadline, enabled)
+}
+
+// collectNow 采集所有缓存进程的指标，deadline 不为零时超过截止时间就停止采集进程级指标
+// 已采集的部分照常返回，分组级别和 exporter 自身的指标总是输出
+// enabled 为本次采集实际需要的采集项，抓取只请求部分指标时可以跳过其余的 /proc 读取
+func (c *ProcessCollector) collectNow(ch chan<- prometheus.Metric, deadline time.Time, enabled enabledCollectors) {
+	start := time.Now()
+	// 1. 获取读锁，复制一份需要采集的列表
+	// 我们不想在持有锁的时候进行网络/IO调用（Collect metrics）
+	c.rwMutex.RLock()
+	// 预分配 slice 提升性能
+	targets := make([]CachedProcess, 0, len(c.cachedProcs))
+	for _, cached := range c.cachedProcs {
+		targets = append(targets, cached)
+	}
+	c.rwMutex.RUnlock()
+
+	// 2. 采集进程级指标，前一次采集还没结束时最多等到截止时间，等不到就跳过，与超时的处理一致
+	status := newCollectStatus()
+	var collected int64
+	if acquire(c.collectSem, deadline) {
+		collected = c.collectTargets(ch, targets, deadline, enabled, status)
+		<-c.collectSem
+	} else {
+		slog.Warn("Timed out waiting for another scrape to finish collecting")
+	}
+	if enabled.has("node") {
+		c.collectNodeContext(ch, status)
+	}
+	complete := status.complete()
+	if collected < int64(len(targets)) {
+		slog.Warn("Scrape deadline exceeded, returning partial metrics", "collected", collected, "total", len(targets))
+		c.telemetry.deadlineExceeded.Inc()
+		complete = false
+	}
+	c.collectScrapeStatus(ch, status, enabled, complete)
+	if c.top != nil {
+		c.collectTop(ch)
+	}
+
+	// 3. 分组级别的指标，每个配置的目标都输出
+	running := make(map[string]int)
+	zombies := make(map[string]int)
+	for _, target := range targets {
+		running[target.Group]++
+		zombies[target.Group] += target.ZombieChildren
+	}
+	if c.maxProcsPerGroup > 0 {
+		pids := truncatedPIDs(targets, running, c.maxProcsPerGroup)
+		c.truncated.Store(&pids)
+	}
+	now := time.Now()
+	pidFiles := c.pidFiles.Load()
+	for _, t := range c.targets.Load().groups(running) {
+		group := t.Name
+		if t.PIDFile != "" && pidFiles != nil {
+			if state, ok := (*pidFiles)[group]; ok {
+				stale := 0.0
+				if state.stale {
+					stale = 1
+				}
+				ch <- prometheus.MustNewConstMetric(c.pidFileStale, prometheus.GaugeValue, stale, group)
+			}
+		}
+		if c.maxProcsPerGroup > 0 {
+			truncated := 0.0
+			if running[group] > c.maxProcsPerGroup {
+				truncated = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.groupTruncated, prometheus.GaugeValue, truncated, group)
+		}
+		ch <- prometheus.MustNewConstMetric(c.groupNumProcs, prometheus.GaugeValue, float64(running[group]), group)
+		if running[group] == 0 {
+			// 没有进程的分组输出 process_up 0，进程消失与从未配置可以区分，可以直接告警
+			// 标签与有进程时相同，pid 为 noProcessPID，环境变量标签沿用分组最近一个进程的值
+			ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0, group, noProcessPID)
+		}
+		flapping := 0.0
+		if c.restarts.Flapping(group, now) {
+			flapping = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.flapping, prometheus.GaugeValue, flapping, group)
+		restarts := prometheus.MustNewConstMetric(c.restartsTotal, prometheus.CounterValue, float64(c.restarts.Total(group)), group)
+		if event, ok := c.restarts.Last(group); ok {
+			restarts = event.exemplar(restarts)
+		}
+		ch <- restarts
+		if available, observed, ok := c.availability.Seconds(group); ok {
+			ch <- prometheus.MustNewConstMetric(c.availableSeconds, prometheus.CounterValue, available, group)
+			ch <- prometheus.MustNewConstMetric(c.observedSeconds, prometheus.CounterValue, observed, group)
+		}
+		if enabled.has("rsspeak") {
+			if peak, ok := c.rssPeaks.Peak(group); ok {
+				ch <- prometheus.MustNewConstMetric(c.groupRSSPeak, prometheus.GaugeValue, float64(peak), group)
+			}
+		}
+		if enabled.has("zombies") {
+			ch <- prometheus.MustNewConstMetric(c.zombies, prometheus.GaugeValue, float64(zombies[group]), group)
+		}
+
+		if loads, ok := c.cpuLoads.Loads(group); ok {
+			for i, w := range cpuLoadWindows {
+				ch <- prometheus.MustNewConstMetric(c.cpuLoad, prometheus.GaugeValue, loads[i], group, w.label)
+			}
+		}
+
+		if cores, ok := c.cpuRecs.Recommendation(group); ok {
+			ch <- prometheus.MustNewConstMetric(c.cpuRecommendation, prometheus.GaugeValue, cores, group)
+		}
+
+		for _, dep := range t.DependsOn {
+			satisfied := 0.0
+			if running[dep] > 0 {
+				satisfied = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.dependencySatisfied, prometheus.GaugeValue, satisfied, group, dep)
+		}
+	}
+
+	c.lifetimes.Collect(ch)
+	c.collectErrors.Collect(ch)
+	c.collectAnnotations(ch, targets)
+
+	// 4. exporter 自身指标
+	c.telemetry.scrapeDuration.Set(time.Since(start).Seconds())
+	c.telemetry.Collect(ch)
+}
+
+// collectTargets 由固定数量的 worker 并发采集 targets 的进程级指标，返回实际采集的进程数，调用方需要持有 collectSem
+// 每个进程需要多次读取 /proc，串行采集在进程多时很慢；同一个进程同一时间只会交给一个 worker
+func (c *ProcessCollector) collectTargets(ch chan<- prometheus.Metric, targets []CachedProcess, deadline time.Time, enabled enabledCollectors, status *collectStatus) int64 {
+	if enabled.has("idleconns") || enabled.has("netbytes") {
+		// 所有进程共用一次 dump，每个进程只需要读取自己的 socket inode
+		c.tcpSockets, c.tcpSocketsErr = readTCPSockets()
+	}
+	queue := make(chan CachedProcess)
+	var wg sync.WaitGroup
+	var collected atomic.Int64
+	for range min(c.concurrency, max(len(targets), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range queue {
+				// 单个进程可能读得很慢，截止时间已过时剩下的只出队不采集
+				if !deadline.IsZero() && time.Now().After(deadline) {
+					continue
+				}
+				c.collectProcess(ch, target, enabled, status)
+				collected.Add(1)
+			}
+		}()
+	}
+	for _, target := range targets {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			break
+		}
+		queue <- target
+	}
+	close(queue)
+	wg.Wait()
+	return collected.Load()
+}
+
+// collectProcess 采集单个进程的指标，可以被多个 worker 并发调用
+func (c *ProcessCollector) collectProcess(ch chan<- prometheus.Metric, target CachedProcess, enabled enabledCollectors, status *collectStatus) {
+	p := target.Proc
+	name := target.Name
+	pidStr := strconv.Itoa(int(p.Pid))
+
+	// 读取失败的采集项计入 process_collect_errors_total，任意一项失败时 process_collect_success 为 0
+	// 进程在采集过程中退出不算失败
+	succeeded := true
+	fail := func(collector string, err error) {
+		c.observeCollectError(status, collector, err)
+		if !vanished(err) {
+			succeeded = false
+			c.collectErrors.WithLabelValues(name, collector).Inc()
+		}
+	}
+
+	// 检查进程是否还存活 (kill signal 0)
+	// 这一步是可选的，因为后续的方法如果不存活会报错
+	// exists, _ := process.PidExists(p.Pid)
+
+	// CPU、内存、状态、线程数、缺页次数共用一次 /proc/pid/stat 读取，swap、RSS 峰值、上下文切换共用一次 /proc/pid/status 读取
+	r := newProcReader(p)
+	timer := newCollectTimer()
+	// 从进程启动开始累计的计数器以启动时间作为创建时间，OpenMetrics 输出 _created
+	created := processCreated(target)
+
+	// 采集 CPU
+	if enabled.has("cpu") {
+		user, system, err := r.Times()
+		if err != nil {
+			// 如果报错，说明进程可能在两次缓存刷新之间退出了
+			// 这里我们选择忽略，等待下一次缓存刷新将其移除
+			fail("cpu", err)
+			if !vanished(err) {
+				ch <- prometheus.MustNewConstMetric(c.collectSuccess, prometheus.GaugeValue, 0, name, pidStr)
+				status.done(enabled)
+			}
+			return
+		}
+		ch <- counterSinceStart(c.cpuUser, user, created, name, pidStr)
+		ch <- counterSinceStart(c.cpuSystem, system, created, name, pidStr)
+	}
+	timer.mark(enabled, "cpu")
+
+	// 采集内存
+	if enabled.has("memory") {
+		if rss, vms, err := r.Memory(); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.memoryRSS, prometheus.GaugeValue, float64(rss), name, pidStr)
+			ch <- prometheus.MustNewConstMetric(c.memoryVMS, prometheus.GaugeValue, float64(vms), name, pidStr)
+		} else {
+			fail("memory", err)
+		}
+	}
+	timer.mark(enabled, "memory")
+	// 换出到 swap 的内存，服务被大量换出时通常已经接近 OOM
+	if enabled.has("swap") {
+		if swap, err := r.Swap(); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.memorySwap, prometheus.GaugeValue, float64(swap), name, pidStr)
+		} else {
+			fail("swap", err)
+		}
+	}
+	timer.mark(enabled, "swap")
+	// RSS 峰值，内核记录，不受抓取间隔影响
+	if enabled.has("rsspeak") {
+		if peak, err := r.RSSPeak(); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.memoryRSSPeak, prometheus.GaugeValue, float64(peak), name, pidStr)
+		} else {
+			fail("rsspeak", err)
+		}
+	}
+	timer.mark(enabled, "rsspeak")
+	// PSS/USS，fork 出来的 worker 共享大量页面时 RSS 会严重高估
+	if enabled.has("smaps") {
+		if fields, err := readSmapsRollup(p.Pid); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.memoryPSS, prometheus.GaugeValue, float64(fields["Pss"]), name, pidStr)
+			ch <- prometheus.MustNewConstMetric(c.memoryUSS, prometheus.GaugeValue, float64(fields["Private_Clean"]+fields["Private_Dirty"]), name, pidStr)
+		} else {
+			fail("smaps", err)
+		}
+	}
+	timer.mark(enabled, "smaps")
+	// 映射的大文件，用于审计数据库的 mmap 缓存和共享库占用的地址空间
+	if enabled.has("mmaps") {
+		if files, err := readMappedFiles(p.Pid); err == nil {
+			for path, size := range files {
+				if size >= c.mmapMinBytes {
+					ch <- prometheus.MustNewConstMetric(c.mmapFileBytes, prometheus.GaugeValue, float64(size), name, pidStr, path)
+				}
+			}
+		} else {
+			fail("mmaps", err)
+		}
+	}
+	timer.mark(enabled, "mmaps")
+	// 按类型区分文件描述符，区分 socket 泄漏和日志文件句柄泄漏
+	if enabled.has("fdtypes") {
+		if counts, err := readFDTypes(p.Pid); err == nil {
+			for t, n := range counts {
+				ch <- prometheus.MustNewConstMetric(c.fdsByType, prometheus.GaugeValue, float64(n), name, pidStr, t)
+			}
+		} else {
+			fail("fdtypes", err)
+		}
+	}
+	timer.mark(enabled, "fdtypes")
+	if target.CmdlineChecked {
+		mismatch := 0.0
+		if target.CmdlineMismatch {
+			mismatch = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.cmdlineMismatch, prometheus.GaugeValue, mismatch, name, pidStr)
+	}
+	if target.OrphanChecked {
+		orphaned := 0.0
+		if target.Orphaned {
+			orphaned = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.orphaned, prometheus.GaugeValue, orphaned, name, pidStr)
+	}
+	if rec := target.Exec; rec != nil {
+		ch <- prometheus.MustNewConstMetric(c.execInfo, prometheus.GaugeValue, 1, name, pidStr, rec.loginUser, rec.uid, rec.session, rec.tty, target.ExecParent)
+	}
+	if target.WorkingSetValid {
+		ch <- prometheus.MustNewConstMetric(c.memoryWorkingSet, prometheus.GaugeValue, float64(target.WorkingSet), name, pidStr)
+	}
+
+	// 进程状态，blocked 即 Linux 的 D 状态（不可中断睡眠），持续处于该状态通常意味着存储出了问题
+	if enabled.has("state") {
+		if state, err := r.State(); err == nil && state != "" {
+			ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, 1, name, pidStr, state)
+		} else if err != nil {
+			fail("state", err)
+		}
+	}
+	timer.mark(enabled, "state")
+
+	// 采集线程
+	if enabled.has("threads") {
+		if numThreads, err := r.NumThreads(); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.numThreads, prometheus.GaugeValue, float64(numThreads), name, pidStr)
+		} else {
+			fail("threads", err)
+		}
+	}
+	timer.mark(enabled, "threads")
+
+	// 线程级别的 CPU 时间和状态，Java 等给线程命名的服务可以按线程池统计 CPU
+	// 按 CPU 拆分的 CPU 时间用于发现挤在同一个核上的进程，两者共用一次 /proc/pid/task 遍历
+	if enabled.has("threadstats") || (enabled.has("percpu") && c.perCPU != nil) {
+		if threads, err := readThreads(p.Pid); err == nil {
+			if enabled.has("threadstats") {
+				for _, t := range threads {
+					ch <- prometheus.MustNewConstMetric(c.threadCPU, prometheus.CounterValue, t.user, name, pidStr, t.tid, t.name, "user")
+					ch <- prometheus.MustNewConstMetric(c.threadCPU, prometheus.CounterValue, t.system, name, pidStr, t.tid, t.name, "system")
+					ch <- prometheus.MustNewConstMetric(c.threadState, prometheus.GaugeValue, 1, name, pidStr, t.tid, t.name, t.state)
+				}
+			}
+			if enabled.has("percpu") && c.perCPU != nil {
+				for cpu, v := range c.perCPU.Observe(procKey{pid: p.Pid, start: target.StartTime}, threads) {
+					ch <- prometheus.MustNewConstMetric(c.cpuByCore, prometheus.CounterValue, v, name, pidStr, cpu)
+				}
+			}
+		} else {
+			for _, name := range []string{"threadstats", "percpu"} {
+				if enabled.has(name) {
+					fail(name, err)
+				}
+			}
+		}
+	}
+	timer.mark(enabled, "threadstats", "percpu")
+	// 所在 cgroup 的限制和限流，用于判断进程的用量离上限还有多远
+	if enabled.has("cgroup") {
+		if cg, err := readCgroupStats(p.Pid); err == nil {
+			if !math.IsNaN(cg.memoryMax) {
+				ch <- prometheus.MustNewConstMetric(c.cgroupMemoryMax, prometheus.GaugeValue, cg.memoryMax, name, pidStr, cg.path)
+			}
+			ch <- prometheus.MustNewConstMetric(c.cgroupMemoryCurrent, prometheus.GaugeValue, cg.memoryCurrent, name, pidStr, cg.path)
+			if !math.IsNaN(cg.cpuLimit) {
+				ch <- prometheus.MustNewConstMetric(c.cgroupCPULimit, prometheus.GaugeValue, cg.cpuLimit, name, pidStr, cg.path)
+			}
+			ch <- prometheus.MustNewConstMetric(c.cgroupPeriods, prometheus.CounterValue, float64(cg.periods), name, pidStr, cg.path)
+			ch <- prometheus.MustNewConstMetric(c.cgroupThrottledPeriods, prometheus.CounterValue, float64(cg.throttledPeriods), name, pidStr, cg.path)
+			ch <- prometheus.MustNewConstMetric(c.cgroupThrottledSeconds, prometheus.CounterValue, cg.throttledSeconds, name, pidStr, cg.path)
+		} else {
+			fail("cgroup", err)
+		}
+	}
+	timer.mark(enabled, "cgroup")
+	// 允许运行的 CPU 数量，用于核对绑核配置
+	if enabled.has("affinity") {
+		if n, err := readAffinityCPUs(p.Pid); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.affinityCPUs, prometheus.GaugeValue, float64(n), name, pidStr)
+		} else {
+			fail("affinity", err)
+		}
+	}
+	timer.mark(enabled, "affinity")
+
+	// 采集句柄
+	if enabled.has("fds") {
+		if fds, err := p.NumFDs(); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.openFDs, prometheus.GaugeValue, float64(fds), name, pidStr)
+		} else {
+			fail("fds", err)
+		}
+	}
+	timer.mark(enabled, "fds")
+	// fd 数按当前趋势达到软限制的剩余时间，比 fd 数与限制的比值更早发现缓慢的泄漏
+	if enabled.has("fdexhaustion") && c.fdExhaustion != nil {
+		if seconds, ok := c.fdExhaustion.Projection(procKey{pid: p.Pid, start: target.StartTime}); ok {
+			ch <- prometheus.MustNewConstMetric(c.fdsExhaustion, prometheus.GaugeValue, seconds, name, pidStr)
+		}
+	}
+	// 父进程和进程树汇总在刷新时计算
+	if enabled.has("tree") && target.PPID != 0 {
+		ch <- prometheus.MustNewConstMetric(c.parentPID, prometheus.GaugeValue, float64(target.PPID), name, pidStr)
+		if target.TreeRoot {
+			ch <- prometheus.MustNewConstMetric(c.treeCPU, prometheus.CounterValue, target.TreeCPU, name, pidStr)
+			ch <- prometheus.MustNewConstMetric(c.treeRSS, prometheus.GaugeValue, float64(target.TreeRSS), name, pidStr)
+			ch <- prometheus.MustNewConstMetric(c.treeProcs, prometheus.GaugeValue, float64(target.TreeProcs), name, pidStr)
+		}
+	}
+	// Windows 的句柄数、工作集、Private Bytes 和读写以外的 IO
+	if enabled.has("windows") {
+		if wp, err := readWindowsProcess(p.Pid); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.openHandles, prometheus.GaugeValue, float64(wp.handles), name, pidStr)
+			ch <- prometheus.MustNewConstMetric(c.memoryWorkingSet, prometheus.GaugeValue, float64(wp.workingSet), name, pidStr)
+			ch <- prometheus.MustNewConstMetric(c.memoryPrivate, prometheus.GaugeValue, float64(wp.privateBytes), name, pidStr)
+			ch <- counterSinceStart(c.ioOtherBytes, float64(wp.otherBytes), created, name, pidStr)
+			ch <- counterSinceStart(c.ioOtherOperations, float64(wp.otherOps), created, name, pidStr)
+		} else {
+			fail("windows", err)
+		}
+	}
+	timer.mark(enabled, "windows")
+
+	// 缺页次数，来自 /proc/pid/stat
+	if enabled.has("pagefaults") {
+		if minor, major, err := r.PageFaults(); err == nil {
+			ch <- counterSinceStart(c.majorPageFaults, float64(major), created, name, pidStr)
+			ch <- counterSinceStart(c.minorPageFaults, float64(minor), created, name, pidStr)
+		} else {
+			fail("pagefaults", err)
+		}
+	}
+	timer.mark(enabled, "pagefaults")
+
+	// 等待块设备 IO 的时间，来自 /proc/pid/stat，找出哪个服务在卡磁盘
+	if enabled.has("blkio") {
+		if d, err := r.BlkioDelay(); err == nil {
+			ch <- counterSinceStart(c.blkioDelay, d, created, name, pidStr)
+		} else {
+			fail("blkio", err)
+		}
+	}
+	timer.mark(enabled, "blkio")
+
+	// 磁盘读写，来自 /proc/pid/io，读取其他用户的进程需要 root
+	if enabled.has("io") {
+		if io, err := p.IOCounters(); err == nil {
+			ch <- counterSinceStart(c.ioReadBytes, float64(io.ReadBytes), created, name, pidStr)
+			ch <- counterSinceStart(c.ioWriteBytes, float64(io.WriteBytes), created, name, pidStr)
+			ch <- counterSinceStart(c.ioReadSyscalls, float64(io.ReadCount), created, name, pidStr)
+			ch <- counterSinceStart(c.ioWriteSyscalls, float64(io.WriteCount), created, name, pidStr)
+		} else {
+			fail("io", err)
+		}
+	}
+	timer.mark(enabled, "io")
+
+	// 按协议统计 socket 数量用于发现连接泄漏，监听端口用于发现进程还在但已不再提供服务
+	// 两者共用一次 socket 读取
+	if enabled.has("connections") || enabled.has("listen") {
+		if conns, err := readConnections(p.Pid); err == nil {
+			if enabled.has("connections") {
+				counts := countConnections(conns)
+				for _, proto := range connectionProtos {
+					ch <- prometheus.MustNewConstMetric(c.networkConnections, prometheus.GaugeValue, float64(counts[proto]), name, pidStr, proto)
+				}
+			}
+			if enabled.has("listen") {
+				for _, lp := range listeningPorts(conns) {
+					ch <- prometheus.MustNewConstMetric(c.listenPorts, prometheus.GaugeValue, 1, name, pidStr, strconv.Itoa(int(lp.port)), lp.proto)
+				}
+			}
+		} else {
+			for _, name := range []string{"connections", "listen"} {
+				if enabled.has(name) {
+					fail(name, err)
+				}
+			}
+		}
+	}
+	timer.mark(enabled, "connections", "listen")
+	// 长时间没有数据收发的 TCP 连接用于发现泄漏或卡住的连接，收发字节数用于找出占用带宽的进程
+	// 两者共用一次 socket inode 读取
+	if enabled.has("idleconns") || (enabled.has("netbytes") && c.netBytes != nil) {
+		inodes, err := readSocketInodes(p.Pid)
+		if err == nil {
+			err = c.tcpSocketsErr
+		}
+		if err == nil {
+			if enabled.has("idleconns") {
+				counts := countIdleConnections(inodes, c.tcpSockets, c.idleThresholds)
+				for i, t := range c.idleThresholds {
+					ch <- prometheus.MustNewConstMetric(c.idleConnections, prometheus.GaugeValue, float64(counts[i]), name, pidStr, idleThresholdLabel(t))
+				}
+			}
+			if enabled.has("netbytes") && c.netBytes != nil {
+				total := c.netBytes.Observe(procKey{pid: p.Pid, start: target.StartTime}, inodes, c.tcpSockets)
+				ch <- prometheus.MustNewConstMetric(c.networkReceiveBytes, prometheus.CounterValue, float64(total.received), name, pidStr)
+				ch <- prometheus.MustNewConstMetric(c.networkTransmitBytes, prometheus.CounterValue, float64(total.sent), name, pidStr)
+			}
+		} else {
+			for _, name := range []string{"idleconns", "netbytes"} {
+				if enabled.has(name) {
+					fail(name, err)
+				}
+			}
+		}
+	}
+	timer.mark(enabled, "idleconns", "netbytes")
+
+	// 写入监控路径下文件的字节数，用于找出日志量暴涨的服务
+	if enabled.has("pathwrites") && c.pathWrites != nil {
+		if fds, err := readWriteFDs(p.Pid); err == nil {
+			for path, written := range c.pathWrites.Observe(procKey{pid: p.Pid, start: target.StartTime}, fds) {
+				ch <- prometheus.MustNewConstMetric(c.pathWriteBytes, prometheus.CounterValue, written, name, pidStr, path)
+			}
+		} else {
+			fail("pathwrites", err)
+		}
+	}
+	timer.mark(enabled, "pathwrites")
+
+	// 上下文切换次数，来自 /proc/pid/status
+	// 每次睡眠后被唤醒都会产生一次主动切换，用来近似进程的唤醒频率
+	if enabled.has("ctxswitches") {
+		if voluntary, involuntary, err := r.CtxSwitches(); err == nil {
+			ch <- counterSinceStart(c.contextSwitches, float64(voluntary), created, name, pidStr, "voluntary")
+			ch <- counterSinceStart(c.contextSwitches, float64(involuntary), created, name, pidStr, "involuntary")
+		} else {
+			fail("ctxswitches", err)
+		}
+	}
+	timer.mark(enabled, "ctxswitches")
+	// 运行队列等待时间，进程已就绪却拿不到 CPU 的时间，区分 CPU 争用和进程自身阻塞
+	if enabled.has("schedstat") {
+		if s, err := readSchedStat(p.Pid); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.runDelay, prometheus.CounterValue, s.runDelay, name, pidStr)
+			ch <- prometheus.MustNewConstMetric(c.timeslices, prometheus.CounterValue, float64(s.timeslices), name, pidStr)
+		} else {
+			fail("schedstat", err)
+		}
+	}
+	timer.mark(enabled, "schedstat")
+
+	// 资源限制，与 process_open_fds 对比可以在 EMFILE 之前告警
+	if enabled.has("rlimits") {
+		if limits, err := p.Rlimit(); err == nil {
+			for _, l := range limits {
+				resource, ok := rlimitResources[l.Resource]
+				if !ok {
+					continue
+				}
+				ch <- prometheus.MustNewConstMetric(c.rlimitSoft, prometheus.GaugeValue, rlimitValue(l.Soft), name, pidStr, resource)
+				ch <- prometheus.MustNewConstMetric(c.rlimitHard, prometheus.GaugeValue, rlimitValue(l.Hard), name, pidStr, resource)
+			}
+		} else {
+			fail("rlimits", err)
+		}
+	}
+	timer.mark(enabled, "rlimits")
+
+	// 按频率加权的 CPU 时间，在刷新缓存时累加，这里只输出
+	if enabled.has("cpufreq") {
+		ch <- prometheus.MustNewConstMetric(c.cpuFrequencyCycles, prometheus.CounterValue, target.FreqCPUTime, name, pidStr)
+		ch <- prometheus.MustNewConstMetric(c.cpuFrequencySampled, prometheus.CounterValue, target.FreqSampledTime, name, pidStr)
+	}
+
+	// I/O 调度类别，用于发现忘了设置 idle 类别的备份、批处理任务
+	if enabled.has("ioprio") {
+		if class, prio, err := readIOPriority(p.Pid); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.ioPriority, prometheus.GaugeValue, float64(prio), name, pidStr, class)
+		} else {
+			fail("ioprio", err)
+		}
+	}
+	timer.mark(enabled, "ioprio")
+
+	// 启动时间，刷新缓存时已经读取过
+	if enabled.has("starttime") {
+		ch <- prometheus.MustNewConstMetric(c.startTime, prometheus.GaugeValue, float64(target.StartTime)/1000.0, name, pidStr)
+	}
+
+	// 每个采集项花费的时间，用于找出打开文件特别多之类拖慢采集的进程
+	c.telemetry.observeCollectTime(timer)
+	if enabled.has("collecttime") {
+		for collector, d := range timer.durations {
+			ch <- prometheus.MustNewConstMetric(c.collectDuration, prometheus.GaugeValue, d.Seconds(), name, pidStr, collector)
+		}
+	}
+
+	success := 0.0
+	if succeeded {
+		success = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.collectSuccess, prometheus.GaugeValue, success, name, pidStr)
+
+	// UP 指标
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1, name, pidStr)
+	status.done(enabled)
+}
+
+// collectScrapeStatus 输出本次采集是否完整以及每个采集项的成功比例
+// 进程在采集过程中退出不算失败，因此 complete 为 0 说明 exporter 自身读取失败（例如权限不足）或者超时
+func (c *ProcessCollector) collectScrapeStatus(ch chan<- prometheus.Metric, status *collectStatus, enabled enabledCollectors, complete bool) {
+	v := 0.0
+	if complete {
+		v = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.scrapeComplete, prometheus.GaugeValue, v)
+	for name, on := range enabled {
+		if on {
+			ch <- prometheus.MustNewConstMetric(c.collectorSuccess, prometheus.GaugeValue, status.successRatio(name), name)
+		}
+	}
+}
+
+// observeCollectError 记录采集项读取进程信息时的错误
+func (c *ProcessCollector) observeCollectError(status *collectStatus, collector string, err error) {
+	c.telemetry.observeError(err)
+	status.fail(collector, err)
+}
+
+// collectAnnotations 为有标注的进程输出 process_annotation_info
+// 标签名来自标注文件，每次加载都可能变化，所以描述符按当前标注动态创建，不在 Describe 中声明
+func (c *ProcessCollector) collectAnnotations(ch chan<- prometheus.Metric, targets []CachedProcess) {
+	set := c.annotations.Load()
+	if set == nil || len(set.keys) == 0 {
+		return
+	}
+	desc := prometheus.NewDesc(
+		"process_annotation_info", "Labels attached to the process by files in the annotations directory.",
+		append([]string{"process_name", "pid"}, set.keys...), nil,
+	)
+	for _, target := range targets {
+		if len(target.Labels) == 0 {
+			continue
+		}
+		values := []string{target.Name, strconv.Itoa(int(target.Proc.Pid))}
+		for _, k := range set.keys {
+			values = append(values, target.Labels[k])
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, values...)
+	}
+}
@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sort"
+)
+
+// collectorOptions 可以通过 -collector.<name> 单独开关的采集项，值为默认是否开启
+// 关闭后既不读取对应的 /proc 文件，也不在 Describe 中输出对应的指标
+var collectorOptions = map[string]struct {
+	enabled bool
+	help    string
+	// metrics 采集项产生的指标，抓取时通过 name[] 只请求部分指标时据此跳过不需要的采集项
+	metrics []string
+}{
+	"cpu":          {true, "CPU time (process_cpu_*_seconds_total)", []string{"process_cpu_user_seconds_total", "process_cpu_system_seconds_total"}},
+	"memory":       {true, "RSS and VMS memory (process_memory_*_bytes)", []string{"process_memory_rss_bytes", "process_memory_vms_bytes"}},
+	"threads":      {true, "thread count (process_num_threads)", []string{"process_num_threads"}},
+	"fds":          {runtime.GOOS != "windows", "open file descriptor count (process_open_fds), on Windows the handle count, use the windows collector instead", []string{"process_open_fds"}},
+	"starttime":    {true, "process start time (process_start_time_seconds)", []string{"process_start_time_seconds"}},
+	"pagefaults":   {true, "major and minor page faults (process_*_page_faults_total)", []string{"process_major_page_faults_total", "process_minor_page_faults_total"}},
+	"io":           {true, "disk IO bytes and read/write syscalls (process_io_*_total)", []string{"process_io_read_bytes_total", "process_io_write_bytes_total", "process_io_read_syscalls_total", "process_io_write_syscalls_total"}},
+	"state":        {true, "process state such as running, sleep or blocked (process_state)", []string{"process_state"}},
+	"swap":         {true, "swapped out memory from /proc/pid/status (process_memory_swap_bytes)", []string{"process_memory_swap_bytes"}},
+	"rsspeak":      {true, "peak RSS since process start (VmHWM) and peak group RSS observed by the exporter (process_*memory_rss_peak_bytes)", []string{"process_memory_rss_peak_bytes", "process_group_memory_rss_peak_bytes"}},
+	"rlimits":      {true, "soft and hard limits of open files, processes and locked memory from /proc/pid/limits (process_rlimit_*)", []string{"process_rlimit_soft", "process_rlimit_hard"}},
+	"connections":  {false, "sockets by protocol (process_network_connections), reads /proc/net/* for every process", []string{"process_network_connections"}},
+	"idleconns":    {false, "TCP connections idle longer than -connections.idle-thresholds via inet_diag (process_network_idle_connections), Linux only", []string{"process_network_idle_connections"}},
+	"netbytes":     {false, "bytes sent and received on the TCP connections held by each process via inet_diag (process_network_*_bytes_total), Linux 4.2+ only, reads /proc/pid/fd for every process", []string{"process_network_receive_bytes_total", "process_network_transmit_bytes_total"}},
+	"ioprio":       {false, "I/O scheduling class and priority via ioprio_get (process_io_priority), Linux only", []string{"process_io_priority"}},
+	"pathwrites":   {false, "estimate of bytes written beneath -path-writes.paths from file offsets in /proc/pid/fdinfo, misses short-lived fds, pwrite and mmap writes (process_path_write_estimated_bytes_total), Linux only", []string{"process_path_write_estimated_bytes_total"}},
+	"listen":       {false, "listening TCP/UDP ports (process_listen_ports), reads /proc/net/* for every process", []string{"process_listen_ports"}},
+	"ctxswitches":  {false, "voluntary and involuntary context switches from /proc/pid/status (process_context_switches_total); this is not a wakeup rate, voluntary switches count every time the process blocks on IO, locks or sleeps", []string{"process_context_switches_total"}},
+	"schedstat":    {false, "run queue delay and number of timeslices summed over all threads from /proc/pid/task/tid/schedstat (process_cpu_run_delay_seconds_total, process_cpu_timeslices_total); this is not off-CPU time, sleeping and blocked time are not counted, Linux only", []string{"process_cpu_run_delay_seconds_total", "process_cpu_timeslices_total"}},
+	"blkio":        {false, "time the main thread spent waiting for block IO from delay accounting in /proc/pid/stat (process_blkio_delay_seconds_total), Linux with delay accounting enabled only", []string{"process_blkio_delay_seconds_total"}},
+	"affinity":     {false, "number of CPUs the process is allowed to run on via sched_getaffinity (process_cpu_affinity_cpus), Linux only", []string{"process_cpu_affinity_cpus"}},
+	"percpu":       {false, "CPU time split by the CPU each thread last ran on, from /proc/pid/task (process_cpu_core_seconds_total), Linux only, one series per CPU used", []string{"process_cpu_core_seconds_total"}},
+	"collecttime":  {false, "time spent reading each process by collector in the current scrape (process_collect_duration_seconds), one series per process and collector", []string{"process_collect_duration_seconds"}},
+	"cgroup":       {false, "memory and CPU limits, memory usage and CPU throttling of the cgroup each process belongs to, cgroup v1 or v2 (process_cgroup_*), Linux only", []string{"process_cgroup_memory_max_bytes", "process_cgroup_memory_current_bytes", "process_cgroup_cpu_limit_cpus", "process_cgroup_cpu_periods_total", "process_cgroup_cpu_throttled_periods_total", "process_cgroup_cpu_throttled_seconds_total"}},
+	"threadstats":  {false, "per-thread CPU time and state from /proc/pid/task (process_thread_*), Linux only, one series per thread", []string{"process_thread_cpu_seconds_total", "process_thread_state"}},
+	"zombies":      {false, "zombie children of monitored processes per group (process_zombies), Linux only", []string{"process_zombies"}},
+	"cpufreq":      {false, "approximation, not perf sampling: CPU time deltas from /proc/pid/stat between cache refreshes weighted by the current frequency of the core the process last ran on (process_cpu_frequency_*), Linux with cpufreq only", []string{"process_cpu_frequency_hertz_seconds_total", "process_cpu_frequency_sampled_seconds_total"}},
+	"windows":      {true, "open handles, working set, private bytes and IO other than read/write from the Windows APIs (process_open_handles, process_memory_working_set_bytes, process_memory_private_bytes, process_io_other_*_total), Windows only", []string{"process_open_handles", "process_memory_working_set_bytes", "process_memory_private_bytes", "process_io_other_bytes_total", "process_io_other_operations_total"}},
+	"fdtypes":      {false, "open file descriptors by type from the /proc/pid/fd link targets (process_fds), Linux only, one readlink per descriptor", []string{"process_fds"}},
+	"fdexhaustion": {false, "projected time until RLIMIT_NOFILE is reached from the open file descriptor trend sampled at each cache refresh (process_fds_exhaustion_seconds)", []string{"process_fds_exhaustion_seconds"}},
+	"tree":         {false, "parent PID of each process, and CPU time, RSS and process count of the whole process tree below processes whose parent is not monitored (process_parent_pid, process_tree_*), reads every process on each cache refresh", []string{"process_parent_pid", "process_tree_cpu_seconds_total", "process_tree_memory_rss_bytes", "process_tree_num_procs"}},
+	"node":         {false, "load average, available memory and CPU count of the host (process_node_*), for hosts without node_exporter", []string{"process_node_load1", "process_node_memory_available_bytes", "process_node_cpus"}},
+	"mmaps":        {false, "memory-mapped files of at least -mmaps.min-size from /proc/pid/maps (process_mmap_file_bytes), Linux only, one series per file", []string{"process_mmap_file_bytes"}},
+	"smaps":        {false, "PSS and USS memory from /proc/pid/smaps_rollup (process_memory_pss_bytes, process_memory_uss_bytes), expensive for large processes", []string{"process_memory_pss_bytes", "process_memory_uss_bytes"}},
+}
+
+// enabledCollectors 开启的采集项
+type enabledCollectors map[string]bool
+
+// has 判断采集项是否开启
+func (e enabledCollectors) has(name string) bool {
+	return e[name]
+}
+
+// only 只保留 names 中的采集项，用于处理抓取请求中的 collect[] 参数
+func (e enabledCollectors) only(names []string) (enabledCollectors, error) {
+	filtered := make(enabledCollectors, len(e))
+	for _, name := range names {
+		if _, ok := collectorOptions[name]; !ok {
+			return nil, fmt.Errorf("unknown collector %q", name)
+		}
+		filtered[name] = e[name]
+	}
+	return filtered, nil
+}
+
+// forMetrics 只保留至少产生 metrics 中一个指标的采集项，用于处理抓取请求中的 name[] 参数
+func (e enabledCollectors) forMetrics(metrics map[string]struct{}) enabledCollectors {
+	filtered := make(enabledCollectors, len(e))
+	for name, on := range e {
+		if !on {
+			continue
+		}
+		for _, m := range collectorOptions[name].metrics {
+			if _, ok := metrics[m]; ok {
+				filtered[name] = true
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// defaultCollectors 返回默认开启的采集项
+func defaultCollectors() enabledCollectors {
+	enabled := make(enabledCollectors, len(collectorOptions))
+	for name, opt := range collectorOptions {
+		enabled[name] = opt.enabled
+	}
+	return enabled
+}
+
+// linuxOnlyCollectors 读取 /proc、netlink 等 Linux 特有接口的采集项，用于其他平台的 unsupportedCollectors
+func linuxOnlyCollectors() map[string]string {
+	unsupported := make(map[string]string)
+	for _, name := range []string{"swap", "rsspeak", "idleconns", "ioprio", "pathwrites", "threadstats", "zombies", "cpufreq", "smaps", "mmaps", "fdtypes", "netbytes", "schedstat", "blkio", "affinity", "percpu", "cgroup"} {
+		unsupported[name] = "Linux only"
+	}
+	return unsupported
+}
+
+// RegisterCollectorFlags 为每个采集项注册 -collector.<name> 开关，flag.Parse 之后调用返回的函数得到结果，用作 Options.Collectors
+// 当前平台不支持的采集项总是关闭，既不采集也不出现在 Describe 中；显式开启时输出警告
+func RegisterCollectorFlags(fs *flag.FlagSet) func() map[string]bool {
+	names := make([]string, 0, len(collectorOptions))
+	for name := range collectorOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]*bool, len(names))
+	for _, name := range names {
+		opt := collectorOptions[name]
+		values[name] = fs.Bool("collector."+name, opt.enabled, fmt.Sprintf("Enable the %s collector: %s.", name, opt.help))
+	}
+
+	return func() map[string]bool {
+		explicit := make(map[string]bool)
+		fs.Visit(func(f *flag.Flag) {
+			explicit[f.Name] = true
+		})
+		unsupported := unsupportedCollectors()
+		enabled := make(map[string]bool, len(values))
+		for name, v := range values {
+			reason, ok := unsupported[name]
+			if *v && ok {
+				if explicit["collector."+name] {
+					slog.Warn("Collector is not supported on this platform, disabled", "collector", name, "reason", reason)
+				}
+				continue
+			}
+			enabled[name] = *v
+		}
+		return enabled
+	}
+}
@@ -0,0 +1,10 @@
+package collector
+
+// unsupportedCollectors 返回当前系统上不可用的采集项及原因
+// macOS 没有按进程统计的磁盘 IO
+func unsupportedCollectors() map[string]string {
+	unsupported := linuxOnlyCollectors()
+	unsupported["windows"] = "Windows only"
+	unsupported["io"] = "not implemented on macOS"
+	return unsupported
+}
@@ -0,0 +1,38 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"process-exporter/internal/procfs"
+	"process-exporter/internal/sysfs"
+)
+
+// unsupportedCollectors 返回当前系统上不可用的采集项及原因
+// Linux 上所有采集项都已实现，只检测依赖内核特性的采集项
+func unsupportedCollectors() map[string]string {
+	unsupported := map[string]string{
+		"windows": "Windows only",
+	}
+	if _, err := os.Stat(sysfs.Path("devices", "system", "cpu", "cpu0", "cpufreq")); err != nil {
+		unsupported["cpufreq"] = "cpufreq is not enabled in the kernel"
+	}
+	if _, err := os.Stat(procfs.Path("self", "smaps_rollup")); err != nil {
+		unsupported["smaps"] = "/proc/pid/smaps_rollup requires Linux 4.14 or later"
+	}
+	if _, err := os.Stat(procfs.Path("self", "schedstat")); err != nil {
+		unsupported["schedstat"] = "/proc/pid/schedstat requires CONFIG_SCHED_INFO"
+	}
+	// Linux 5.14 起延迟统计默认关闭，需要 sysctl kernel.task_delayacct=1 或启动参数 delayacct；更早的内核没有该开关，默认开启
+	if v, err := os.ReadFile(procfs.Path("sys", "kernel", "task_delayacct")); err == nil && strings.TrimSpace(string(v)) == "0" {
+		unsupported["blkio"] = "delay accounting is disabled, enable it with sysctl kernel.task_delayacct=1"
+	}
+	root := cgroupRoot()
+	_, v2 := os.Stat(filepath.Join(root, "cgroup.controllers"))
+	_, v1 := os.Stat(filepath.Join(root, "memory"))
+	if v2 != nil && v1 != nil {
+		unsupported["cgroup"] = "cgroup filesystem is not mounted at " + root
+	}
+	return unsupported
+}
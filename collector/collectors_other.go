@@ -0,0 +1,10 @@
+//go:build !linux && !windows && !darwin
+
+package collector
+
+// unsupportedCollectors 返回当前系统上不可用的采集项及原因
+func unsupportedCollectors() map[string]string {
+	unsupported := linuxOnlyCollectors()
+	unsupported["windows"] = "Windows only"
+	return unsupported
+}
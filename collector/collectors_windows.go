@@ -0,0 +1,12 @@
+package collector
+
+// unsupportedCollectors 返回当前系统上不可用的采集项及原因
+// gopsutil 在 Windows 上没有实现进程状态、资源限制和上下文切换
+func unsupportedCollectors() map[string]string {
+	unsupported := linuxOnlyCollectors()
+	unsupported["state"] = "not implemented on Windows"
+	unsupported["rlimits"] = "not implemented on Windows"
+	unsupported["ctxswitches"] = "not implemented on Windows"
+	unsupported["fdexhaustion"] = "RLIMIT_NOFILE does not exist on Windows"
+	return unsupported
+}
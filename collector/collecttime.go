@@ -0,0 +1,33 @@
+package collector
+
+import "time"
+
+// collectTimer 记录采集单个进程时每个采集项花费的时间
+// 每个采集项执行完后调用 mark，上一次 mark 以来的时间计入该采集项
+type collectTimer struct {
+	start, last time.Time
+	durations   map[string]time.Duration
+}
+
+func newCollectTimer() *collectTimer {
+	now := time.Now()
+	return &collectTimer{start: now, last: now, durations: make(map[string]time.Duration)}
+}
+
+// mark 将上一次 mark 以来的时间计入 names 中第一个开启的采集项
+// 多个采集项共用一次读取时只计入第一个，都没有开启时丢弃这段时间
+func (t *collectTimer) mark(enabled enabledCollectors, names ...string) {
+	now := time.Now()
+	for _, name := range names {
+		if enabled.has(name) {
+			t.durations[name] += now.Sub(t.last)
+			break
+		}
+	}
+	t.last = now
+}
+
+// total 采集这个进程花费的总时间
+func (t *collectTimer) total() time.Duration {
+	return t.last.Sub(t.start)
+}
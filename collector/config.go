@@ -0,0 +1,446 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/shirou/gopsutil/v4/process"
+	"go.yaml.in/yaml/v2"
+
+	"process-exporter/matcher"
+)
+
+// Config 是 -config.file 指向的 YAML 文件结构
+type Config struct {
+	// Names 需要监控的进程名称，与 -names 含义相同
+	Names []string `yaml:"names"`
+	// PIDFiles 按 PID 文件选择进程，与 -pidfile 含义相同，分组名称为去掉 .pid 后缀的文件名
+	PIDFiles []string `yaml:"pidfiles"`
+	// Groups 需要额外配置的分组，分组名称同样作为匹配的进程名称
+	Groups []GroupConfig `yaml:"groups"`
+	// Labels 附加在所有指标上的常量标签，与 -labels 含义相同，只在启动时读取
+	Labels map[string]string `yaml:"labels"`
+	// EnvLabels 标签名 -> 环境变量名，从 /proc/<pid>/environ 读取后作为该进程指标的标签，只在启动时读取
+	EnvLabels map[string]string `yaml:"env_labels"`
+}
+
+// GroupConfig 单个分组的配置
+type GroupConfig struct {
+	Name string `yaml:"name"`
+	// Match 分组的匹配规则，未设置时按分组名称匹配进程名称
+	Match *matcher.RuleConfig `yaml:"match"`
+	// Exclude 匹配之后再排除的进程，任意一条规则匹配即排除
+	Exclude []matcher.RuleConfig `yaml:"exclude"`
+	// Priority 一个进程同时匹配多个分组时，优先级高的分组胜出，相同时按配置顺序
+	Priority int `yaml:"priority"`
+	// ExpectedCmdline 分组内进程命令行应当匹配的正则，不匹配时 process_cmdline_mismatch 为 1
+	ExpectedCmdline string `yaml:"expected_cmdline"`
+	// DependsOn 该分组依赖的其他分组，例如 nginx 依赖 php-fpm
+	DependsOn []string `yaml:"depends_on"`
+	// Parent 分组内进程的父进程应当属于的分组，例如 nginx-worker 的父进程属于 nginx-master
+	// 父进程不属于该分组（通常是 master 退出后被 init 收养）时 process_orphaned 为 1
+	Parent string `yaml:"parent"`
+	// MinInstances 分组期望的最少进程数，默认 1，进程数不少于该值的时长计入 process_group_available_seconds_total
+	MinInstances int `yaml:"min_instances"`
+}
+
+// Target 一个监控目标，即一个进程分组
+type Target struct {
+	Name string
+	// Rule 分组的匹配规则，-names 和未配置 match 的分组按名称子串匹配
+	Rule            matcher.Rule
+	Priority        int
+	ExpectedCmdline *regexp.Regexp
+	DependsOn       []string
+	Parent          string
+	MinInstances    int
+	// Service 不为空时分组为该 Windows 服务的进程（-services），PID 通过服务控制管理器查询，不使用 Rule
+	Service string
+	// PIDFile 不为空时分组为该 PID 文件中记录的进程（-pidfile），每次刷新时重新读取，不使用 Rule
+	PIDFile string
+	// NameTemplate 不为 nil 时分组名称由模板按进程生成（-config.path），Name 为模板原文
+	NameTemplate *template.Template
+	// captures 名称模板中 {{.Matches}} 使用的命令行正则
+	captures []*regexp.Regexp
+}
+
+// minInstances 分组期望的最少进程数，未配置时为 1
+func (t Target) minInstances() int {
+	if t.MinInstances > 0 {
+		return t.MinInstances
+	}
+	return 1
+}
+
+// targets 将配置转换为监控目标列表，names、分组名称和 match 中的 name 按 opts 匹配
+func (c *Config) targets(opts matcher.NameOptions) ([]Target, error) {
+	targets := make([]Target, 0, len(c.Names)+len(c.PIDFiles)+len(c.Groups))
+	for _, name := range c.Names {
+		rule, err := matcher.NameRule(name, opts)
+		if err != nil {
+			return nil, fmt.Errorf("name %q: %w", name, err)
+		}
+		targets = append(targets, Target{Name: name, Rule: rule})
+	}
+	for _, path := range c.PIDFiles {
+		targets = append(targets, Target{Name: pidFileGroup(path), PIDFile: path})
+	}
+	for _, g := range c.Groups {
+		t := Target{Name: g.Name, Priority: g.Priority, DependsOn: g.DependsOn, Parent: g.Parent, MinInstances: g.MinInstances}
+		if g.Match != nil {
+			rule, err := g.Match.CompileWith(opts)
+			if err != nil {
+				return nil, fmt.Errorf("group %q: invalid match: %w", g.Name, err)
+			}
+			t.Rule = rule
+		} else {
+			rule, err := matcher.NameRule(g.Name, opts)
+			if err != nil {
+				return nil, fmt.Errorf("group %q: %w", g.Name, err)
+			}
+			t.Rule = rule
+		}
+		if len(g.Exclude) > 0 {
+			exclude, err := matcher.CompileRules(g.Exclude, opts)
+			if err != nil {
+				return nil, fmt.Errorf("group %q: invalid exclude: %w", g.Name, err)
+			}
+			t.Rule.Exclude = append(t.Rule.Exclude, exclude...)
+		}
+		if g.ExpectedCmdline != "" {
+			re, err := regexp.Compile(g.ExpectedCmdline)
+			if err != nil {
+				return nil, fmt.Errorf("group %q: invalid expected_cmdline: %w", g.Name, err)
+			}
+			t.ExpectedCmdline = re
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// LoadConfig 读取并校验配置文件
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(content, c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	for i, name := range c.Names {
+		c.Names[i] = strings.TrimSpace(name)
+		if c.Names[i] == "" {
+			return nil, fmt.Errorf("%s: names[%d] is empty", path, i)
+		}
+	}
+	for i, file := range c.PIDFiles {
+		if strings.TrimSpace(file) == "" {
+			return nil, fmt.Errorf("%s: pidfiles[%d] is empty", path, i)
+		}
+	}
+	for i := range c.Groups {
+		c.Groups[i].Name = strings.TrimSpace(c.Groups[i].Name)
+		if c.Groups[i].Name == "" {
+			return nil, fmt.Errorf("%s: groups[%d] has no name", path, i)
+		}
+		if c.Groups[i].MinInstances < 0 {
+			return nil, fmt.Errorf("%s: groups[%d]: min_instances must not be negative", path, i)
+		}
+	}
+	for name := range c.Labels {
+		if !model.LabelName(name).IsValid() {
+			return nil, fmt.Errorf("%s: invalid label name %q", path, name)
+		}
+	}
+	for name, env := range c.EnvLabels {
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("%s: env_labels: invalid label name %q", path, name)
+		}
+		if _, ok := reservedAnnotationLabels[name]; ok || name == idLabel {
+			return nil, fmt.Errorf("%s: env_labels: label %q is reserved", path, name)
+		}
+		if env == "" || strings.Contains(env, "=") {
+			return nil, fmt.Errorf("%s: env_labels: invalid environment variable name %q for label %q", path, env, name)
+		}
+	}
+	return c, nil
+}
+
+// Reloader 负责重新加载配置文件并替换采集器的目标进程集合
+// HTTP 服务和注册表在重载过程中保持不变
+type Reloader struct {
+	path string
+	// ncabatoffPath -config.path 指定的 ncabatoff/process-exporter 格式配置文件
+	ncabatoffPath string
+	flagNames     []string // -names 指定的目标，重载时始终保留
+	services      []string // -services 指定的 Windows 服务，重载时始终保留
+	pidFiles      []string // -pidfile 指定的 PID 文件，重载时始终保留
+	// excludeNames -exclude.names 生成的排除规则，附加到所有按规则匹配的目标上
+	excludeNames []matcher.Rule
+	// allowEmpty 允许没有任何目标，只输出 -top.n 的进程
+	allowEmpty bool
+	// nameOptions -names.match-mode 和 -names.normalize，-names、配置文件的 names 和分组名称的匹配方式
+	nameOptions matcher.NameOptions
+
+	// apply 在重载成功后调用，用于替换采集器的目标集合
+	apply func(targets []Target)
+
+	mu sync.Mutex // 防止并发重载
+
+	lastReloadSuccessful prometheus.Gauge
+	lastReloadSuccessTS  prometheus.Gauge
+}
+
+func newConfigReloader(path string, flagNames []string) *Reloader {
+	return &Reloader{
+		path:      path,
+		flagNames: flagNames,
+		lastReloadSuccessful: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "process_exporter_config_last_reload_successful",
+			Help: "Whether the last configuration reload attempt was successful.",
+		}),
+		lastReloadSuccessTS: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "process_exporter_config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration reload.",
+		}),
+	}
+}
+
+// targets 合并 -names 和配置文件中的目标，返回去重后的列表
+// 同名目标以后出现的为准，因此 groups 中的配置会覆盖同名的 names
+func (r *Reloader) targets() ([]Target, error) {
+	targets := make([]Target, 0, len(r.flagNames))
+	for _, name := range r.flagNames {
+		rule, err := matcher.NameRule(name, r.nameOptions)
+		if err != nil {
+			return nil, fmt.Errorf("-names %q: %w", name, err)
+		}
+		targets = append(targets, Target{Name: name, Rule: rule})
+	}
+	for _, name := range r.services {
+		targets = append(targets, Target{Name: name, Service: name})
+	}
+	for _, path := range r.pidFiles {
+		targets = append(targets, Target{Name: pidFileGroup(path), PIDFile: path})
+	}
+	if r.path != "" {
+		c, err := LoadConfig(r.path)
+		if err != nil {
+			return nil, err
+		}
+		fileTargets, err := c.targets(r.nameOptions)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, fileTargets...)
+	}
+	if r.ncabatoffPath != "" {
+		fileTargets, err := loadNcabatoffConfig(r.ncabatoffPath)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, fileTargets...)
+	}
+
+	index := make(map[string]int, len(targets))
+	uniq := make([]Target, 0, len(targets))
+	for _, t := range targets {
+		if i, ok := index[t.Name]; ok {
+			uniq[i] = t
+			continue
+		}
+		index[t.Name] = len(uniq)
+		uniq = append(uniq, t)
+	}
+
+	if len(uniq) == 0 && !r.allowEmpty {
+		return nil, errors.New("no process names configured")
+	}
+	if len(r.excludeNames) > 0 {
+		for i, t := range uniq {
+			if t.Service == "" && t.PIDFile == "" {
+				uniq[i].Rule.Exclude = slices.Concat(t.Rule.Exclude, r.excludeNames)
+			}
+		}
+	}
+	for _, t := range uniq {
+		for _, dep := range t.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("group %q depends on unknown group %q", t.Name, dep)
+			}
+		}
+		if t.Parent != "" {
+			if _, ok := index[t.Parent]; !ok {
+				return nil, fmt.Errorf("group %q has unknown parent group %q", t.Name, t.Parent)
+			}
+		}
+	}
+	return uniq, nil
+}
+
+// Load 解析配置并更新重载状态指标，但不替换目标集合，用于启动时的首次加载
+func (r *Reloader) Load() ([]Target, error) {
+	targets, err := r.targets()
+	if err != nil {
+		r.lastReloadSuccessful.Set(0)
+		return nil, err
+	}
+
+	r.lastReloadSuccessful.Set(1)
+	r.lastReloadSuccessTS.Set(float64(time.Now().Unix()))
+	return targets, nil
+}
+
+// Reload 重新解析配置并原子替换目标集合，失败时保留旧的目标集合
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	targets, err := r.Load()
+	if err != nil {
+		return err
+	}
+
+	if r.apply != nil {
+		r.apply(targets)
+	}
+	slog.Info("Configuration reloaded", "monitoring", targetNames(targets))
+	return nil
+}
+
+// WatchSIGHUP 收到 SIGHUP 时重新加载配置
+func (r *Reloader) WatchSIGHUP() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := r.Reload(); err != nil {
+				slog.Error("Error reloading config", "err", err)
+			}
+		}
+	}()
+}
+
+// ServeHTTP 处理 POST /-/reload，与 Prometheus 的约定一致
+func (r *Reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost && req.Method != http.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		http.Error(w, "Only POST or PUT requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.Reload(); err != nil {
+		slog.Error("Error reloading config", "err", err)
+		http.Error(w, fmt.Sprintf("failed to reload config: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// targetSet 目标列表及由其构建的匹配器
+type targetSet struct {
+	list    []Target
+	byName  map[string]Target
+	matcher *matcher.Matcher
+	// services 按 Windows 服务选择进程的目标名称
+	services []string
+	// pidFiles 按 PID 文件选择进程的目标
+	pidFiles []Target
+}
+
+func newTargetSet(targets []Target) *targetSet {
+	s := &targetSet{
+		list:    targets,
+		byName:  make(map[string]Target, len(targets)),
+		matcher: matcher.New(),
+	}
+	for _, t := range targets {
+		s.byName[t.Name] = t
+		if t.Service != "" {
+			s.services = append(s.services, t.Service)
+			continue
+		}
+		if t.PIDFile != "" {
+			s.pidFiles = append(s.pidFiles, t)
+			continue
+		}
+		s.matcher.Add(t.Name, t.Priority, t.Rule)
+	}
+	return s
+}
+
+// match 返回进程所属的目标，即优先级最高的匹配分组
+// 使用名称模板的目标返回副本，Name 为按进程生成的分组名称
+func (s *targetSet) match(p *process.Process) (Target, bool) {
+	mp := matcher.FromProcess(p)
+	name, ok := s.matcher.Match(mp)
+	if !ok {
+		return Target{}, false
+	}
+	t := s.byName[name]
+	if t.NameTemplate != nil {
+		rendered, err := t.renderName(p, mp)
+		if err != nil || rendered == "" {
+			return Target{}, false
+		}
+		t.Name = rendered
+	}
+	return t, true
+}
+
+// groups 返回输出分组级别指标的目标：配置中的固定分组，以及 present 中由名称模板生成的分组
+func (s *targetSet) groups(present map[string]int) []Target {
+	groups := make([]Target, 0, len(s.list))
+	for _, t := range s.list {
+		if t.NameTemplate == nil {
+			groups = append(groups, t)
+		}
+	}
+	var dynamic []string
+	for name := range present {
+		if t, ok := s.byName[name]; !ok || t.NameTemplate != nil {
+			dynamic = append(dynamic, name)
+		}
+	}
+	sort.Strings(dynamic)
+	for _, name := range dynamic {
+		groups = append(groups, Target{Name: name})
+	}
+	return groups
+}
+
+// targetNames 返回目标名称列表，用于日志输出
+func targetNames(targets []Target) []string {
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = t.Name
+	}
+	return names
+}
+
+func (r *Reloader) Describe(ch chan<- *prometheus.Desc) {
+	r.lastReloadSuccessful.Describe(ch)
+	r.lastReloadSuccessTS.Describe(ch)
+}
+
+func (r *Reloader) Collect(ch chan<- prometheus.Metric) {
+	r.lastReloadSuccessful.Collect(ch)
+	r.lastReloadSuccessTS.Collect(ch)
+}
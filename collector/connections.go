@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"sort"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// connectionProtos process_network_connections 的 proto 标签取值，每个进程总是全部输出
+var connectionProtos = []string{"tcp", "tcp6", "udp", "udp6"}
+
+// readConnections 读取进程持有的 IPv4/IPv6 socket
+// Linux 上需要读取 /proc/pid/fd 和 /proc/net/{tcp,tcp6,udp,udp6}，进程和连接多时开销较大
+func readConnections(pid int32) ([]net.ConnectionStat, error) {
+	return net.ConnectionsPid("inet", pid)
+}
+
+// connectionProto 返回 socket 对应的 proto 标签，不是 TCP/UDP 时返回 false
+func connectionProto(conn net.ConnectionStat) (string, bool) {
+	var proto string
+	switch conn.Type {
+	case syscall.SOCK_STREAM:
+		proto = "tcp"
+	case syscall.SOCK_DGRAM:
+		proto = "udp"
+	default:
+		return "", false
+	}
+	if conn.Family == syscall.AF_INET6 {
+		proto += "6"
+	}
+	return proto, true
+}
+
+// countConnections 按协议统计 socket 数量
+func countConnections(conns []net.ConnectionStat) map[string]int {
+	counts := make(map[string]int, len(connectionProtos))
+	for _, conn := range conns {
+		if proto, ok := connectionProto(conn); ok {
+			counts[proto]++
+		}
+	}
+	return counts
+}
+
+// listenPort 进程监听的端口
+type listenPort struct {
+	proto string
+	port  uint32
+}
+
+// listeningPorts 返回处于 LISTEN 状态的 TCP 端口和未连接到对端的 UDP 端口
+// 同一端口上的多个 socket（例如 SO_REUSEPORT）只算一次，结果按协议和端口排序
+func listeningPorts(conns []net.ConnectionStat) []listenPort {
+	seen := make(map[listenPort]struct{})
+	for _, conn := range conns {
+		proto, ok := connectionProto(conn)
+		if !ok || conn.Laddr.Port == 0 {
+			continue
+		}
+		if conn.Type == syscall.SOCK_STREAM && conn.Status != "LISTEN" {
+			continue
+		}
+		if conn.Type == syscall.SOCK_DGRAM && conn.Raddr.Port != 0 {
+			continue
+		}
+		seen[listenPort{proto: proto, port: conn.Laddr.Port}] = struct{}{}
+	}
+	ports := make([]listenPort, 0, len(seen))
+	for p := range seen {
+		ports = append(ports, p)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].proto != ports[j].proto {
+			return ports[i].proto < ports[j].proto
+		}
+		return ports[i].port < ports[j].port
+	})
+	return ports
+}
@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"process-exporter/internal/procfs"
+	"process-exporter/internal/sysfs"
+)
+
+// readLastCPU 读取进程最后一次运行所在的 CPU，即 /proc/pid/stat 的第 39 个字段 processor
+func readLastCPU(pid int32) (int, error) {
+	content, err := os.ReadFile(procfs.PID(pid, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	end := bytes.LastIndexByte(content, ')')
+	if end < 0 {
+		return 0, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	// ')' 之后从 state(3) 开始，processor 是第 39 个字段
+	fields := bytes.Fields(content[end+1:])
+	if len(fields) < 37 {
+		return 0, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	return strconv.Atoi(string(fields[36]))
+}
+
+// readCPUFrequency 读取 CPU 当前的频率（Hz），需要内核开启 cpufreq，虚拟机上通常没有
+func readCPUFrequency(cpu int) (float64, error) {
+	content, err := os.ReadFile(sysfs.Path("devices", "system", "cpu", "cpu"+strconv.Itoa(cpu), "cpufreq", "scaling_cur_freq"))
+	if err != nil {
+		return 0, err
+	}
+	khz, err := strconv.ParseUint(string(bytes.TrimSpace(content)), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(khz) * 1000, nil
+}
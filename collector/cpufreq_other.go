@@ -0,0 +1,13 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readLastCPU(pid int32) (int, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func readCPUFrequency(cpu int) (float64, error) {
+	return 0, errors.ErrUnsupported
+}
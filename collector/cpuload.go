@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// cpuLoadWindows EWMA 的时间常数，与系统 load average 的 1/5/15 分钟一致
+var cpuLoadWindows = []struct {
+	label string
+	tau   time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+}
+
+// cpuLoad 一个分组的 CPU 使用量 EWMA（核数）
+type cpuLoad struct {
+	at  time.Time
+	avg []float64
+}
+
+// cpuLoadTracker 按分组维护 CPU 使用量的指数加权移动平均，不需要记录规则就能得到类似 load average 的信号
+// 刷新间隔不固定（配置重载、进程事件都可能触发刷新），衰减系数按实际间隔计算
+type cpuLoadTracker struct {
+	mu    sync.Mutex
+	loads map[string]*cpuLoad
+}
+
+func newCPULoadTracker() *cpuLoadTracker {
+	return &cpuLoadTracker{loads: make(map[string]*cpuLoad)}
+}
+
+// Observe 记录分组在 at 时刻的 CPU 使用量，第一次采样直接作为初始值
+func (t *cpuLoadTracker) Observe(group string, at time.Time, cores float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l, ok := t.loads[group]
+	if !ok {
+		l = &cpuLoad{at: at, avg: make([]float64, len(cpuLoadWindows))}
+		for i := range l.avg {
+			l.avg[i] = cores
+		}
+		t.loads[group] = l
+		return
+	}
+	dt := at.Sub(l.at)
+	if dt <= 0 {
+		return
+	}
+	for i, w := range cpuLoadWindows {
+		decay := math.Exp(-dt.Seconds() / w.tau.Seconds())
+		l.avg[i] = l.avg[i]*decay + cores*(1-decay)
+	}
+	l.at = at
+}
+
+// Loads 返回分组各时间窗口的 EWMA，顺序与 cpuLoadWindows 相同，没有采样时返回 false
+func (t *cpuLoadTracker) Loads(group string) ([]float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.loads[group]
+	if !ok {
+		return nil, false
+	}
+	return append([]float64(nil), l.avg...), true
+}
@@ -0,0 +1,25 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// processCreated 进程的启动时间，作为从进程启动开始累计的计数器的创建时间，未读取到启动时间时为零值
+func processCreated(target CachedProcess) time.Time {
+	if target.StartTime <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(target.StartTime)
+}
+
+// counterSinceStart 从进程启动开始累计的计数器（CPU 时间、IO、缺页等），带上创建时间
+// OpenMetrics 格式输出 <name>_created，同一个 pid 被新进程复用后创建时间变化，下游据此识别计数器重置
+// created 为零值时与普通计数器相同
+func counterSinceStart(desc *prometheus.Desc, value float64, created time.Time, labelValues ...string) prometheus.Metric {
+	if created.IsZero() {
+		return prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, labelValues...)
+	}
+	return prometheus.MustNewConstMetricWithCreatedTimestamp(desc, prometheus.CounterValue, value, created, labelValues...)
+}
@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// readEnvLabels 从进程的环境变量中读取 envLabels（标签名 -> 环境变量名）对应的值，没有设置的变量不输出标签
+// 环境变量在 exec 之后不再变化，只在进程加入缓存时读取一次
+func readEnvLabels(p *process.Process, envLabels map[string]string) (map[string]string, error) {
+	environ, err := p.Environ()
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string, len(envLabels))
+	for _, name := range envLabels {
+		vars[name] = ""
+	}
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if _, want := vars[name]; want {
+			vars[name] = value
+		}
+	}
+	var labels map[string]string
+	for label, name := range envLabels {
+		if vars[name] == "" {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string, len(envLabels))
+		}
+		labels[label] = vars[name]
+	}
+	return labels, nil
+}
+
+// envLabelGatherer 给进程级指标加上从进程环境变量读取的标签（配置文件的 env_labels）
+// 在聚合之前应用，开启 -metrics.aggregate 时聚合结果按这些标签分组
+// 指标自身已有同名标签时保留指标的值；没有进程的分组的 process_up 0 使用分组最近一个进程的标签
+type envLabelGatherer struct {
+	gatherer  prometheus.Gatherer
+	collector *ProcessCollector
+}
+
+func (g envLabelGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+
+	c := g.collector
+	env := make(map[string]map[string]string)
+	c.rwMutex.RLock()
+	for pid, cached := range c.cachedProcs {
+		if len(cached.Env) > 0 {
+			env[strconv.Itoa(int(pid))] = cached.Env
+		}
+	}
+	groupEnv := c.groupEnv
+	c.rwMutex.RUnlock()
+	if len(env) == 0 && len(groupEnv) == 0 {
+		return families, err
+	}
+
+	for _, mf := range families {
+		if !hasPIDLabel(mf) {
+			continue
+		}
+		for _, m := range mf.Metric {
+			var labels map[string]string
+			for _, lp := range m.Label {
+				if lp.GetName() == "pid" {
+					labels = env[lp.GetValue()]
+					if lp.GetValue() == noProcessPID {
+						labels = groupEnv[metricLabel(m, "process_name")]
+					}
+					break
+				}
+			}
+			if len(labels) == 0 {
+				continue
+			}
+			existing := make(map[string]struct{}, len(m.Label))
+			for _, lp := range m.Label {
+				existing[lp.GetName()] = struct{}{}
+			}
+			for name, value := range labels {
+				if _, ok := existing[name]; ok {
+					continue
+				}
+				m.Label = append(m.Label, &dto.LabelPair{Name: &name, Value: &value})
+			}
+			sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+		}
+	}
+	return families, err
+}
+
+// metricLabel 返回序列中标签 name 的值，没有该标签时返回空字符串
+func metricLabel(m *dto.Metric, name string) string {
+	for _, lp := range m.Label {
+		if lp.GetName() == name {
+			return lp.GetValue()
+		}
+	}
+	return ""
+}
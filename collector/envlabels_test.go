@@ -0,0 +1,39 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestEnvLabelGathererNoProcess(t *testing.T) {
+	running := cached(20, "nginx", 100)
+	running.Env = map[string]string{"app": "web"}
+	c := collectorWith(running)
+	c.groupEnv = map[string]map[string]string{"nginx": {"app": "web"}, "mysql": {"app": "db"}}
+
+	g := envLabelGatherer{collector: c, gatherer: prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return []*dto.MetricFamily{family("process_up", dto.MetricType_GAUGE,
+			sample{"20", "nginx", 1}, sample{noProcessPID, "mysql", 0}, sample{noProcessPID, "redis", 0})}, nil
+	})}
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	want := [][]string{
+		{"app=web", "pid=20", "process_name=nginx"},
+		{"app=db", "pid=0", "process_name=mysql"},
+		// 从来没有进程的分组没有环境变量标签可用
+		{"pid=0", "process_name=redis"},
+	}
+	var got [][]string
+	for _, m := range families[0].Metric {
+		got = append(got, labels(m))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("labels = %v, want %v", got, want)
+	}
+}
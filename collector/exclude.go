@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"strconv"
+	"strings"
+
+	"process-exporter/matcher"
+)
+
+// scanExclusions 刷新缓存时直接跳过的进程，例如 Kubernetes 节点上所有容器内的进程
+// 在读取进程名称等信息之前判断，减少全量扫描的开销
+type scanExclusions struct {
+	uids    map[uint32]struct{}
+	cgroups []string // cgroup 路径前缀
+}
+
+// parseScanExclusions 解析 -exclude.uids 和 -exclude.cgroups 的值
+func parseScanExclusions(uids, cgroups string) (scanExclusions, error) {
+	var e scanExclusions
+	for _, s := range strings.Split(uids, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		uid, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return e, err
+		}
+		if e.uids == nil {
+			e.uids = make(map[uint32]struct{})
+		}
+		e.uids[uint32(uid)] = struct{}{}
+	}
+	for _, s := range strings.Split(cgroups, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			e.cgroups = append(e.cgroups, s)
+		}
+	}
+	return e, nil
+}
+
+// excluded 判断 PID 是否需要跳过，读取失败时不跳过，交给后续流程处理
+func (e scanExclusions) excluded(pid int32) bool {
+	if len(e.uids) > 0 {
+		if uid, err := processUID(pid); err == nil {
+			if _, ok := e.uids[uid]; ok {
+				return true
+			}
+		}
+	}
+	if len(e.cgroups) > 0 {
+		if paths, err := matcher.ReadCgroups(pid); err == nil {
+			for _, path := range paths {
+				for _, prefix := range e.cgroups {
+					if strings.HasPrefix(path, prefix) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
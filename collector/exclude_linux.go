@@ -0,0 +1,22 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"process-exporter/internal/procfs"
+)
+
+// processUID 通过 /proc/pid 目录的属主得到进程的 UID，只需要一次 stat
+func processUID(pid int32) (uint32, error) {
+	fi, err := os.Stat(procfs.PID(pid))
+	if err != nil {
+		return 0, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("unexpected stat type for pid %d", pid)
+	}
+	return st.Uid, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func processUID(pid int32) (uint32, error) {
+	return 0, errors.ErrUnsupported
+}
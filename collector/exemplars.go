@@ -0,0 +1,44 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// processEvent 进程的一次生命周期事件（重启或退出），作为计数器的 exemplar 输出
+// 只有协商为 OpenMetrics 格式的抓取才会输出 exemplar
+type processEvent struct {
+	PID       int32
+	StartTime int64
+	At        time.Time
+}
+
+func newProcessEvent(cached CachedProcess) processEvent {
+	return processEvent{PID: cached.Proc.Pid, StartTime: cached.StartTime}
+}
+
+// ID 事件 ID，由 PID 和启动时间组成，同一次进程运行的启动和退出事件 ID 相同，可以在日志中检索
+func (e processEvent) ID() string {
+	return fmt.Sprintf("%d-%d", e.PID, e.StartTime)
+}
+
+func (e processEvent) labels() prometheus.Labels {
+	return prometheus.Labels{"event_id": e.ID(), "pid": fmt.Sprint(e.PID)}
+}
+
+// exemplar 为计数器附加事件 exemplar，时间戳为事件发生时间；附加失败时原样返回
+func (e processEvent) exemplar(m prometheus.Metric) prometheus.Metric {
+	withExemplar, err := prometheus.NewMetricWithExemplars(m, prometheus.Exemplar{
+		Value:     1,
+		Labels:    e.labels(),
+		Timestamp: e.At,
+	})
+	if err != nil {
+		slog.Debug("Error attaching exemplar", "err", err)
+		return m
+	}
+	return withExemplar
+}
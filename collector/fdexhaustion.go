@@ -0,0 +1,139 @@
+package collector
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// fdSample 一次刷新时进程打开的文件描述符数
+type fdSample struct {
+	at  time.Time
+	fds float64
+}
+
+// fdSeries 一次进程运行在窗口内的 fd 数采样，以及最近一次读到的 RLIMIT_NOFILE 软限制
+type fdSeries struct {
+	samples []fdSample
+	limit   float64
+}
+
+// fdExhaustionTracker 每次刷新缓存时记录进程的 fd 数，按窗口内的线性趋势推算耗尽 RLIMIT_NOFILE 的时间
+// fd 泄漏通常是缓慢、线性的，在 EMFILE 之前数小时就能发现
+type fdExhaustionTracker struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	series map[procKey]*fdSeries
+}
+
+func newFDExhaustionTracker(window time.Duration) *fdExhaustionTracker {
+	return &fdExhaustionTracker{window: window, series: make(map[procKey]*fdSeries)}
+}
+
+// Observe 记录一次采样，丢弃窗口之外的旧采样
+func (t *fdExhaustionTracker) Observe(key procKey, at time.Time, fds int32, limit float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.series[key]
+	if !ok {
+		s = &fdSeries{}
+		t.series[key] = s
+	}
+	s.limit = limit
+	s.samples = append(s.samples, fdSample{at: at, fds: float64(fds)})
+	drop := 0
+	for drop < len(s.samples) && at.Sub(s.samples[drop].at) > t.window {
+		drop++
+	}
+	s.samples = s.samples[drop:]
+}
+
+// Prune 删除已退出进程的采样
+func (t *fdExhaustionTracker) Prune(cache map[int32]CachedProcess) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.series {
+		if cached, ok := cache[key.pid]; !ok || cached.StartTime != key.start {
+			delete(t.series, key)
+		}
+	}
+}
+
+// minFDSamples 推算耗尽时间至少需要的采样数
+const minFDSamples = 3
+
+// Projection 按最小二乘拟合的 fd 增长速度推算多少秒后达到软限制
+// 采样不足、fd 数没有增长或没有限制时返回 false，已经达到限制时返回 0
+func (t *fdExhaustionTracker) Projection(key procKey) (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.series[key]
+	if !ok || len(s.samples) < minFDSamples || math.IsInf(s.limit, 1) {
+		return 0, false
+	}
+	// fd 数不变时拟合的斜率可能因为浮点误差略大于 0，要求窗口内确实有增长
+	last := s.samples[len(s.samples)-1].fds
+	slope := fdSlope(s.samples)
+	if slope <= 0 || last <= s.samples[0].fds {
+		return 0, false
+	}
+	remaining := s.limit - last
+	return max(remaining/slope, 0), true
+}
+
+// fdSlope 最小二乘拟合的每秒 fd 增长数
+func fdSlope(samples []fdSample) float64 {
+	origin := samples[0].at
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.at.Sub(origin).Seconds()
+		sumX += x
+		sumY += s.fds
+		sumXY += x * s.fds
+		sumXX += x * x
+	}
+	n := float64(len(samples))
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denom
+}
+
+// nofileLimit 读取进程 RLIMIT_NOFILE 的软限制，unlimited 时为 +Inf
+func nofileLimit(p *process.Process) (float64, error) {
+	limits, err := p.Rlimit()
+	if err != nil {
+		return 0, err
+	}
+	for _, l := range limits {
+		if l.Resource == process.RLIMIT_NOFILE {
+			return rlimitValue(l.Soft), nil
+		}
+	}
+	return math.Inf(1), nil
+}
+
+// recordFDs 记录本次刷新每个进程的 fd 数和软限制，读取失败的进程跳过
+func (c *ProcessCollector) recordFDs(newCache map[int32]CachedProcess) {
+	now := time.Now()
+	for pid, cached := range newCache {
+		fds, err := cached.Proc.NumFDs()
+		if err != nil {
+			c.telemetry.observeError(err)
+			continue
+		}
+		limit, err := nofileLimit(cached.Proc)
+		if err != nil {
+			c.telemetry.observeError(err)
+			continue
+		}
+		c.fdExhaustion.Observe(procKey{pid: pid, start: cached.StartTime}, now, fds, limit)
+	}
+	c.fdExhaustion.Prune(newCache)
+}
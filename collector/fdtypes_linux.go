@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"os"
+	"strings"
+
+	"process-exporter/internal/procfs"
+)
+
+// fdTypes process_fds 的 type 标签取值，file 包括普通文件、目录和设备文件，other 为 net、mnt 等命名空间句柄
+var fdTypes = []string{"socket", "pipe", "file", "anon_inode", "other"}
+
+// readFDTypes 读取 /proc/pid/fd 中每个符号链接的目标，按类型统计文件描述符数量
+// 结果总是包含 fdTypes 中的全部类型，没有该类型时为 0
+func readFDTypes(pid int32) (map[string]int, error) {
+	dir := procfs.PID(pid, "fd")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(fdTypes))
+	for _, t := range fdTypes {
+		counts[t] = 0
+	}
+	for _, entry := range entries {
+		target, err := os.Readlink(dir + "/" + entry.Name())
+		if err != nil {
+			// 读取目录和读取链接之间文件描述符可能已经关闭
+			continue
+		}
+		counts[fdType(target)]++
+	}
+	return counts, nil
+}
+
+// fdType 根据符号链接目标判断文件描述符类型，例如 socket:[12345]、pipe:[12345]、anon_inode:[eventfd]
+func fdType(target string) string {
+	switch {
+	case strings.HasPrefix(target, "/"):
+		return "file"
+	case strings.HasPrefix(target, "socket:"):
+		return "socket"
+	case strings.HasPrefix(target, "pipe:"):
+		return "pipe"
+	case strings.HasPrefix(target, "anon_inode:"):
+		return "anon_inode"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readFDTypes(pid int32) (map[string]int, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,100 @@
+package collector
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// restartRing 固定容量的环形缓冲区，保存某个分组最近的重启时间
+type restartRing struct {
+	times []time.Time
+	next  int
+	full  bool
+}
+
+func (r *restartRing) add(t time.Time) {
+	r.times[r.next] = t
+	r.next = (r.next + 1) % len(r.times)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// oldest 返回缓冲区中最早的一次重启时间，缓冲区未满时返回 false
+func (r *restartRing) oldest() (time.Time, bool) {
+	if !r.full {
+		return time.Time{}, false
+	}
+	return r.times[r.next], true
+}
+
+// restartTracker 按分组记录重启时间，用于判断进程是否在频繁重启
+// 在 window 时间内重启超过 threshold 次即认为在抖动（flapping）
+type restartTracker struct {
+	threshold int
+	window    time.Duration
+
+	mu    sync.Mutex
+	rings map[string]*restartRing
+	// totals 启动以来每个分组的重启次数
+	totals map[string]int
+	// last 每个分组最近一次重启的事件，作为 process_restarts_total 的 exemplar
+	last map[string]processEvent
+}
+
+func newRestartTracker(threshold int, window time.Duration) *restartTracker {
+	return &restartTracker{
+		threshold: threshold,
+		window:    window,
+		rings:     make(map[string]*restartRing),
+		totals:    make(map[string]int),
+		last:      make(map[string]processEvent),
+	}
+}
+
+// Record 记录分组的一次重启，event 为新启动的进程
+func (t *restartTracker) Record(group string, at time.Time, event processEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.rings[group]
+	if !ok {
+		// 只需要保留 threshold+1 个时间点：最早的一个仍在窗口内，就说明超过了阈值
+		r = &restartRing{times: make([]time.Time, t.threshold+1)}
+		t.rings[group] = r
+	}
+	r.add(at)
+	t.totals[group]++
+	event.At = at
+	t.last[group] = event
+	slog.Info("Process group restarted", "group", group, "pid", event.PID, "event_id", event.ID())
+}
+
+// Last 返回分组最近一次重启的事件
+func (t *restartTracker) Last(group string) (processEvent, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	event, ok := t.last[group]
+	return event, ok
+}
+
+// Total 返回分组启动以来的重启次数
+func (t *restartTracker) Total(group string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.totals[group]
+}
+
+// Flapping 判断分组当前是否处于频繁重启状态
+func (t *restartTracker) Flapping(group string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.rings[group]
+	if !ok {
+		return false
+	}
+	oldest, ok := r.oldest()
+	return ok && now.Sub(oldest) <= t.window
+}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultIdleThresholds -connections.idle-thresholds 的默认值
+const defaultIdleThresholds = "1m,10m,1h"
+
+// parseIdleThresholds 解析 -connections.idle-thresholds 的值，结果从小到大排序并去重
+func parseIdleThresholds(s string) ([]time.Duration, error) {
+	var thresholds []time.Duration
+	seen := make(map[time.Duration]struct{})
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("threshold %q must be positive", part)
+		}
+		if _, ok := seen[d]; ok {
+			continue
+		}
+		seen[d] = struct{}{}
+		thresholds = append(thresholds, d)
+	}
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] < thresholds[j] })
+	return thresholds, nil
+}
+
+// idleThresholdLabel process_network_idle_connections 的 idle_seconds 标签取值
+func idleThresholdLabel(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// tcpSocket inet_diag 返回的一个 TCP 连接
+type tcpSocket struct {
+	// idle 最后一次发送和接收数据距今的较小值
+	idle time.Duration
+	// bytes 连接建立以来的收发字节数，内核不支持时为 nil
+	bytes *tcpBytes
+}
+
+// tcpBytes tcp_info 中的 tcpi_bytes_acked（已被对端确认的发送字节数）和 tcpi_bytes_received
+type tcpBytes struct {
+	sent, received uint64
+}
+
+// countIdleConnections 统计进程持有的 TCP 连接中空闲时间超过每个阈值的数量
+// sockets 为本次抓取 inet_diag 返回的 socket inode 到连接信息的映射，不在其中的 socket（UDP、UNIX 等）不统计
+func countIdleConnections(inodes []uint64, sockets map[uint64]tcpSocket, thresholds []time.Duration) []int {
+	counts := make([]int, len(thresholds))
+	for _, inode := range inodes {
+		s, ok := sockets[inode]
+		if !ok {
+			continue
+		}
+		for i, t := range thresholds {
+			if s.idle >= t {
+				counts[i]++
+			}
+		}
+	}
+	return counts
+}
@@ -0,0 +1,156 @@
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"process-exporter/internal/procfs"
+)
+
+const (
+	// inet_diag_req_v2 和 inet_diag_msg 的长度，见 linux/inet_diag.h
+	inetDiagReqV2Len = 56
+	inetDiagMsgLen   = 72
+	// inetDiagInfo 请求返回 struct tcp_info 的扩展属性 INET_DIAG_INFO
+	inetDiagInfo = 2
+	// tcp_info 中 tcpi_last_data_sent 和 tcpi_last_data_recv 的偏移，单位毫秒
+	tcpInfoLastDataSent = 44
+	tcpInfoLastDataRecv = 52
+	// tcp_info 中 tcpi_bytes_acked 和 tcpi_bytes_received 的偏移，Linux 4.2 之前的内核没有这两个字段
+	tcpInfoBytesAcked    = 120
+	tcpInfoBytesReceived = 128
+	// 除 LISTEN 外的所有 TCP 状态，监听 socket 没有数据收发，不算空闲连接
+	tcpStatesExceptListen = (1<<12 - 1) &^ (1 << unix.BPF_TCP_LISTEN)
+)
+
+// readTCPSockets 通过 NETLINK_SOCK_DIAG 一次性读取本机所有 TCP 连接的空闲时间和收发字节数
+// 返回 socket inode 到连接信息的映射，只能看到与 exporter 同一网络命名空间中的连接
+func readTCPSockets() (map[uint64]tcpSocket, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_SOCK_DIAG)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, err
+	}
+
+	sockets := make(map[uint64]tcpSocket)
+	for _, family := range []uint8{unix.AF_INET, unix.AF_INET6} {
+		if err := dumpTCPSockets(fd, family, sockets); err != nil {
+			return nil, err
+		}
+	}
+	return sockets, nil
+}
+
+// dumpTCPSockets 发送一次 SOCK_DIAG_BY_FAMILY dump 请求，将结果写入 sockets
+func dumpTCPSockets(fd int, family uint8, sockets map[uint64]tcpSocket) error {
+	req := make([]byte, unix.NLMSG_HDRLEN+inetDiagReqV2Len)
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], unix.SOCK_DIAG_BY_FAMILY)
+	binary.NativeEndian.PutUint16(req[6:8], unix.NLM_F_REQUEST|unix.NLM_F_DUMP)
+	body := req[unix.NLMSG_HDRLEN:]
+	body[0] = family
+	body[1] = unix.IPPROTO_TCP
+	body[2] = 1 << (inetDiagInfo - 1)
+	binary.NativeEndian.PutUint32(body[4:8], tcpStatesExceptListen)
+	if err := unix.Sendto(fd, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			switch msg.Header.Type {
+			case unix.NLMSG_DONE:
+				return nil
+			case unix.NLMSG_ERROR:
+				if len(msg.Data) >= 4 {
+					if errno := int32(binary.NativeEndian.Uint32(msg.Data[:4])); errno != 0 {
+						return unix.Errno(-errno)
+					}
+				}
+				return fmt.Errorf("inet_diag dump failed")
+			}
+			if inode, s, ok := parseInetDiagMsg(msg.Data); ok {
+				sockets[inode] = s
+			}
+		}
+	}
+}
+
+// parseInetDiagMsg 解析 inet_diag_msg 及其后的 INET_DIAG_INFO 属性
+// 空闲时间取最后一次发送和接收数据距今的较小值
+func parseInetDiagMsg(data []byte) (uint64, tcpSocket, bool) {
+	if len(data) < inetDiagMsgLen {
+		return 0, tcpSocket{}, false
+	}
+	inode := uint64(binary.NativeEndian.Uint32(data[68:72]))
+	attrs := data[inetDiagMsgLen:]
+	for len(attrs) >= unix.SizeofRtAttr {
+		attrLen := int(binary.NativeEndian.Uint16(attrs[0:2]))
+		attrType := binary.NativeEndian.Uint16(attrs[2:4])
+		if attrLen < unix.SizeofRtAttr || attrLen > len(attrs) {
+			break
+		}
+		value := attrs[unix.SizeofRtAttr:attrLen]
+		if attrType == inetDiagInfo && len(value) >= tcpInfoLastDataRecv+4 {
+			sent := binary.NativeEndian.Uint32(value[tcpInfoLastDataSent:])
+			recv := binary.NativeEndian.Uint32(value[tcpInfoLastDataRecv:])
+			s := tcpSocket{idle: time.Duration(min(sent, recv)) * time.Millisecond}
+			if len(value) >= tcpInfoBytesReceived+8 {
+				s.bytes = &tcpBytes{
+					sent:     binary.NativeEndian.Uint64(value[tcpInfoBytesAcked:]),
+					received: binary.NativeEndian.Uint64(value[tcpInfoBytesReceived:]),
+				}
+			}
+			return inode, s, true
+		}
+		// 属性按 4 字节对齐
+		next := (attrLen + unix.RTA_ALIGNTO - 1) &^ (unix.RTA_ALIGNTO - 1)
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+	return 0, tcpSocket{}, false
+}
+
+// readSocketInodes 读取进程打开的 socket 的 inode，即 /proc/pid/fd 中 socket:[inode] 形式的链接
+func readSocketInodes(pid int32) ([]uint64, error) {
+	dir := procfs.PID(pid, "fd")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var inodes []uint64
+	for _, entry := range entries {
+		// fd 可能在读取期间被关闭，忽略错误
+		link, err := os.Readlink(filepath.Join(dir, entry.Name()))
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+		if err == nil {
+			inodes = append(inodes, inode)
+		}
+	}
+	return inodes, nil
+}
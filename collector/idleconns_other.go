@@ -0,0 +1,13 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readTCPSockets() (map[uint64]tcpSocket, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func readSocketInodes(pid int32) ([]uint64, error) {
+	return nil, errors.ErrUnsupported
+}
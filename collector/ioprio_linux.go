@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioPrioMask   = 1<<ioprioClassShift - 1
+)
+
+// ioprioClasses 内核 IOPRIO_CLASS_* 对应的名称，none 表示未设置，由 nice 值推导
+var ioprioClasses = []string{"none", "realtime", "best-effort", "idle"}
+
+// readIOPriority 通过 ioprio_get 读取进程（主线程）的 I/O 调度类别和优先级
+func readIOPriority(pid int32) (class string, prio int, err error) {
+	v, _, errno := unix.Syscall(unix.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(pid), 0)
+	if errno != 0 {
+		return "", 0, errno
+	}
+	c := int(v >> ioprioClassShift)
+	if c >= len(ioprioClasses) {
+		return "unknown", int(v & ioprioPrioMask), nil
+	}
+	return ioprioClasses[c], int(v & ioprioPrioMask), nil
+}
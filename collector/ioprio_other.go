@@ -0,0 +1,9 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readIOPriority(pid int32) (class string, prio int, err error) {
+	return "", 0, errors.ErrUnsupported
+}
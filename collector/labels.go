@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"process-exporter/internal/remotewrite"
+)
+
+// loadConstLabels 合并配置文件中的 labels、-labels 和分片标签，作为附加在所有指标上的常量标签
+// -labels 覆盖配置文件中的同名标签；配置文件中的 labels 只在启动时读取，修改后需要重启
+func loadConstLabels(configFile, flagLabels string, shardLabels prometheus.Labels) (prometheus.Labels, error) {
+	labels := prometheus.Labels{}
+	if configFile != "" {
+		c, err := LoadConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(labels, c.Labels)
+	}
+	parsed, err := remotewrite.ParseLabels(flagLabels)
+	if err != nil {
+		return nil, fmt.Errorf("-labels: %w", err)
+	}
+	maps.Copy(labels, parsed)
+	for name, value := range shardLabels {
+		if _, ok := labels[name]; ok {
+			return nil, fmt.Errorf("label %q is reserved for sharding", name)
+		}
+		labels[name] = value
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	return labels, nil
+}
+
+// loadEnvLabels 读取配置文件中的 env_labels，标签不能与常量标签同名
+func loadEnvLabels(configFile string, constLabels prometheus.Labels) (map[string]string, error) {
+	if configFile == "" {
+		return nil, nil
+	}
+	c, err := LoadConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	for name := range c.EnvLabels {
+		if _, ok := constLabels[name]; ok {
+			return nil, fmt.Errorf("label %q is also a constant label", name)
+		}
+	}
+	return c.EnvLabels, nil
+}
+
+// checkConstLabels 常量标签与指标自身的标签同名时注册会失败，启动时用一个空注册表提前检查
+func checkConstLabels(labels prometheus.Labels, cs ...prometheus.Collector) error {
+	reg := prometheus.WrapRegistererWith(labels, prometheus.NewRegistry())
+	for _, c := range cs {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// landingTemplate 首页，直接访问端口时显示版本、监控目标和各端点的链接，不再是 404
+var landingTemplate = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Process Exporter</title></head>
+<body>
+<h1>Process Exporter</h1>
+<p>Version: {{.Version}}</p>
+{{- if .Cached}}
+<p>Matched processes: {{.Processes}}, cache age: {{.CacheAge}}{{if not .Ready}} (warming up){{end}}</p>
+<table border="1" cellpadding="4">
+<tr><th>Group</th><th>Processes</th><th>Match</th></tr>
+{{- range .Groups}}
+<tr><td>{{.Name}}</td><td>{{.Processes}}</td><td><code>{{.Match}}</code></td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>Profile: full, all processes are scanned on every scrape.</p>
+{{- end}}
+<ul>
+{{- range .Links}}
+<li><a href="{{.}}">{{.}}</a></li>
+{{- end}}
+</ul>
+</body>
+</html>
+`))
+
+// landingGroup 首页中的一个分组
+type landingGroup struct {
+	Name      string
+	Processes int
+	Match     string
+}
+
+// landingPage 首页的处理器，collector 为 nil 时（full 模式）只显示版本和链接
+type landingPage struct {
+	version   string
+	collector *ProcessCollector
+	links     []string
+}
+
+// LandingPage 返回 / 的处理器，显示版本、配置的目标及匹配到的进程数、缓存的刷新时间，以及 links 中各端点的链接
+// c 为 nil 时（full 模式）只显示版本和链接；只处理 / 本身，其他路径返回 404
+func LandingPage(version string, c *ProcessCollector, links []string) http.Handler {
+	return &landingPage{version: version, collector: c, links: links}
+}
+
+func (p *landingPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	data := struct {
+		Version   string
+		Cached    bool
+		Processes int
+		CacheAge  string
+		Ready     bool
+		Groups    []landingGroup
+		Links     []string
+	}{Version: p.version, Links: p.links}
+	if c := p.collector; c != nil {
+		data.Cached = true
+		data.Ready = c.telemetry.warmedUp.Load()
+		data.CacheAge = "never refreshed"
+		if ts := c.telemetry.lastRefresh.Load(); ts > 0 {
+			data.CacheAge = time.Since(time.Unix(0, ts)).Round(time.Second).String()
+		}
+
+		counts := make(map[string]int)
+		c.rwMutex.RLock()
+		for _, cached := range c.cachedProcs {
+			counts[cached.Group]++
+		}
+		data.Processes = len(c.cachedProcs)
+		c.rwMutex.RUnlock()
+		for _, t := range c.targets.Load().list {
+			data.Groups = append(data.Groups, landingGroup{Name: t.Name, Processes: counts[t.Name], Match: describeTarget(t)})
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := landingTemplate.Execute(w, data); err != nil {
+		slog.Error("Error rendering landing page", "err", err)
+	}
+}
+
+// Healthy 处理 /-/healthy，进程在运行即返回 200，与 Prometheus 的约定一致
+func Healthy(w http.ResponseWriter, _ *http.Request) {
+	w.Write([]byte("Healthy.\n"))
+}
+
+// serveReady 处理 /-/ready，启动预热完成前返回 503，负载均衡和滚动发布可以据此等待第一次完整采集
+func (c *ProcessCollector) serveReady(w http.ResponseWriter, _ *http.Request) {
+	if !c.telemetry.warmedUp.Load() {
+		http.Error(w, "Warming up.", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("Ready.\n"))
+}
@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+
+	"process-exporter/internal/procfs"
+)
+
+// readMappedFiles 读取 /proc/pid/maps，按文件路径汇总映射的地址空间大小（字节）
+// 匿名映射以及 [heap]、[stack] 等伪路径不计入，已删除的文件路径带有 " (deleted)" 后缀
+func readMappedFiles(pid int32) (map[string]uint64, error) {
+	content, err := os.ReadFile(procfs.PID(pid, "maps"))
+	if err != nil {
+		return nil, err
+	}
+	return parseMappedFiles(content), nil
+}
+
+// parseMappedFiles 解析 maps 的内容
+// Linux 路径可以包含任意字节，非 UTF-8 的部分替换为 U+FFFD 后作为标签值，否则 MustNewConstMetric 会 panic
+func parseMappedFiles(content []byte) map[string]uint64 {
+	files := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// 每行格式为 "start-end perms offset dev inode path"，路径中可能有空格，只按前 5 个字段切分
+		parts := bytes.SplitN(scanner.Bytes(), []byte(" "), 6)
+		if len(parts) != 6 || string(parts[4]) == "0" {
+			continue
+		}
+		path := strings.ToValidUTF8(string(bytes.TrimLeft(parts[5], " ")), "\uFFFD")
+		if len(path) == 0 || path[0] != '/' {
+			continue
+		}
+		start, end, ok := bytes.Cut(parts[0], []byte("-"))
+		if !ok {
+			continue
+		}
+		lo, err := strconv.ParseUint(string(start), 16, 64)
+		if err != nil {
+			continue
+		}
+		hi, err := strconv.ParseUint(string(end), 16, 64)
+		if err != nil || hi < lo {
+			continue
+		}
+		files[path] += hi - lo
+	}
+	return files
+}
@@ -0,0 +1,47 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMappedFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]uint64
+	}{
+		{
+			name:    "file mappings summed by path",
+			content: "00400000-00401000 r-xp 00000000 08:01 1234 /usr/bin/app\n00600000-00602000 rw-p 00000000 08:01 1234 /usr/bin/app\n",
+			want:    map[string]uint64{"/usr/bin/app": 0x3000},
+		},
+		{
+			name:    "anonymous and pseudo paths skipped",
+			content: "01000000-01100000 rw-p 00000000 00:00 0 [heap]\n7f000000-7f001000 rw-p 00000000 00:00 0 \n",
+			want:    map[string]uint64{},
+		},
+		{
+			name:    "path with spaces and deleted suffix",
+			content: "7f000000-7f010000 r--s 00000000 08:01 99         /data/my db.mdb (deleted)\n",
+			want:    map[string]uint64{"/data/my db.mdb (deleted)": 0x10000},
+		},
+		{
+			name:    "invalid utf-8 path",
+			content: "7f000000-7f001000 r--p 00000000 08:01 99 /data/\xff.bin\n",
+			want:    map[string]uint64{"/data/�.bin": 0x1000},
+		},
+		{
+			name:    "malformed lines skipped",
+			content: "zzzz-7f001000 r--p 00000000 08:01 99 /a\n7f001000-7f000000 r--p 00000000 08:01 99 /b\nshort line\n",
+			want:    map[string]uint64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMappedFiles([]byte(tt.content)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMappedFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
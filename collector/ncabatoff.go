@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"go.yaml.in/yaml/v2"
+
+	"process-exporter/matcher"
+)
+
+// ncabatoffConfig ncabatoff/process-exporter 的 -config.path 配置文件格式
+//
+//	process_names:
+//	  - name: "{{.Comm}}"
+//	    cmdline:
+//	      - '.+'
+//	  - name: postgres
+//	    exe:
+//	      - /usr/lib/postgresql/16/bin/postgres
+type ncabatoffConfig struct {
+	ProcessNames []ncabatoffGroup `yaml:"process_names"`
+}
+
+// ncabatoffGroup 一个分组，comm、exe、cmdline 中设置的每一类条件都需要满足
+// comm 和 exe 中列出的多个值满足其一即可，cmdline 中的多个正则需要全部匹配
+type ncabatoffGroup struct {
+	Name    string   `yaml:"name"`
+	Comm    []string `yaml:"comm"`
+	Exe     []string `yaml:"exe"`
+	Cmdline []string `yaml:"cmdline"`
+}
+
+// loadNcabatoffConfig 读取 ncabatoff 格式的配置文件并转换为监控目标
+// 进程按配置顺序匹配第一个满足条件的分组；名称相同的多个分组合并为一个目标
+func loadNcabatoffConfig(path string) ([]Target, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c ncabatoffConfig
+	if err := yaml.UnmarshalStrict(content, &c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	var targets []Target
+	index := make(map[string]int)
+	for i, g := range c.ProcessNames {
+		rule, captures, err := g.rule()
+		if err != nil {
+			return nil, fmt.Errorf("%s: process_names[%d]: %w", path, i, err)
+		}
+		name := strings.TrimSpace(g.Name)
+		if name == "" {
+			// ncabatoff 的默认名称
+			name = "{{.ExeBase}}"
+		}
+		if j, ok := index[name]; ok {
+			merged := &targets[j]
+			merged.Rule = matcher.Rule{Any: []matcher.Rule{merged.Rule, rule}}
+			merged.captures = append(merged.captures, captures...)
+			continue
+		}
+
+		t := Target{Name: name, Rule: rule, captures: captures}
+		if strings.Contains(name, "{{") {
+			tmpl, err := template.New(name).Option("missingkey=zero").Parse(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: process_names[%d]: invalid name template: %w", path, i, err)
+			}
+			t.NameTemplate = tmpl
+		}
+		index[name] = len(targets)
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// rule 将分组的匹配条件转换为匹配规则，同时返回 cmdline 中的正则，用于名称模板中的 {{.Matches}}
+func (g ncabatoffGroup) rule() (matcher.Rule, []*regexp.Regexp, error) {
+	var rule matcher.Rule
+	if len(g.Comm) > 0 {
+		var any []matcher.Rule
+		for _, comm := range g.Comm {
+			any = append(any, matcher.Rule{Name: comm, NameMode: matcher.Exact})
+		}
+		rule.All = append(rule.All, matcher.Rule{Any: any})
+	}
+	if len(g.Exe) > 0 {
+		var any []matcher.Rule
+		for _, exe := range g.Exe {
+			any = append(any, matcher.Rule{Exe: exe})
+		}
+		rule.All = append(rule.All, matcher.Rule{Any: any})
+	}
+	var captures []*regexp.Regexp
+	for _, expr := range g.Cmdline {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return matcher.Rule{}, nil, fmt.Errorf("invalid cmdline: %w", err)
+		}
+		rule.All = append(rule.All, matcher.Rule{Cmdline: re})
+		captures = append(captures, re)
+	}
+	if len(rule.All) == 0 {
+		return matcher.Rule{}, nil, fmt.Errorf("no comm, exe or cmdline matcher")
+	}
+	return rule, captures, nil
+}
+
+// nameTemplateData 名称模板中可以使用的字段，与 ncabatoff/process-exporter 一致
+type nameTemplateData struct {
+	Comm      string
+	ExeBase   string
+	ExeFull   string
+	Username  string
+	PID       int
+	StartTime time.Time
+	Cgroups   []string
+	// Matches cmdline 正则中的命名分组，例如 (?P<port>\d+) 对应 {{.Matches.port}}
+	Matches map[string]string
+}
+
+// renderName 按目标的名称模板生成进程所属的分组名称
+func (t Target) renderName(p *process.Process, mp matcher.Process) (string, error) {
+	data := nameTemplateData{PID: int(p.Pid), Matches: make(map[string]string)}
+	data.Comm, _ = mp.Name()
+	if exe, err := mp.Exe(); err == nil {
+		data.ExeFull, data.ExeBase = exe, filepath.Base(exe)
+	}
+	data.Username, _ = mp.Username()
+	data.Cgroups, _ = mp.Cgroups()
+	if ms, err := p.CreateTime(); err == nil {
+		data.StartTime = time.UnixMilli(ms)
+	}
+	if len(t.captures) > 0 {
+		if cmdline, err := mp.Cmdline(); err == nil {
+			for _, re := range t.captures {
+				m := re.FindStringSubmatch(cmdline)
+				for i, name := range re.SubexpNames() {
+					if name != "" && i < len(m) {
+						data.Matches[name] = m[i]
+					}
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.NameTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
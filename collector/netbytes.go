@@ -0,0 +1,61 @@
+package collector
+
+import "sync"
+
+// netBytesTracker 按进程累计 TCP 连接的收发字节数
+// 每次采集时对进程持有的每个连接取 tcp_info 中计数的增量：新出现的连接计入建立以来的全部字节，
+// 已有连接计入两次采集之间的增量，连接关闭前最后一次采集之后的字节不会被统计；
+// 通过 fork 共享的连接在每个持有它的进程上都会计入
+type netBytesTracker struct {
+	mu     sync.Mutex
+	last   map[procKey]map[uint64]tcpBytes
+	totals map[procKey]tcpBytes
+}
+
+func newNetBytesTracker() *netBytesTracker {
+	return &netBytesTracker{
+		last:   make(map[procKey]map[uint64]tcpBytes),
+		totals: make(map[procKey]tcpBytes),
+	}
+}
+
+// Observe 记录进程本次采集持有的连接，返回累计的发送和接收字节数
+// sockets 中没有的 inode（UDP、UNIX socket 等）和内核不支持字节计数的连接不统计
+func (t *netBytesTracker) Observe(key procKey, inodes []uint64, sockets map[uint64]tcpSocket) tcpBytes {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	total := t.totals[key]
+	last := t.last[key]
+	current := make(map[uint64]tcpBytes, len(inodes))
+	for _, inode := range inodes {
+		s, ok := sockets[inode]
+		if !ok || s.bytes == nil {
+			continue
+		}
+		b := *s.bytes
+		current[inode] = b
+		prev := last[inode]
+		if b.sent >= prev.sent {
+			total.sent += b.sent - prev.sent
+		}
+		if b.received >= prev.received {
+			total.received += b.received - prev.received
+		}
+	}
+	t.last[key] = current
+	t.totals[key] = total
+	return total
+}
+
+// Prune 删除已不在缓存中的进程的状态
+func (t *netBytesTracker) Prune(cache map[int32]CachedProcess) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.totals {
+		if cached, ok := cache[key.pid]; !ok || cached.StartTime != key.start {
+			delete(t.totals, key)
+			delete(t.last, key)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// collectNodeContext 输出主机的 1 分钟负载、可用内存和逻辑 CPU 数
+// 只运行本 exporter 的边缘主机没有 node_exporter，用于把进程的 CPU、内存换算成占主机容量的比例
+func (c *ProcessCollector) collectNodeContext(ch chan<- prometheus.Metric, status *collectStatus) {
+	if avg, err := load.Avg(); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.nodeLoad1, prometheus.GaugeValue, avg.Load1)
+	} else {
+		c.observeCollectError(status, "node", err)
+	}
+	if vm, err := mem.VirtualMemory(); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.nodeMemoryAvailable, prometheus.GaugeValue, float64(vm.Available))
+	} else {
+		c.observeCollectError(status, "node", err)
+	}
+	if n, err := cpu.Counts(true); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.nodeCPUs, prometheus.GaugeValue, float64(n))
+	} else {
+		c.observeCollectError(status, "node", err)
+	}
+}
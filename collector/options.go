@@ -0,0 +1,397 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"process-exporter/internal/encoder"
+	"process-exporter/internal/logging"
+	"process-exporter/matcher"
+)
+
+// Options 创建 ProcessCollector 的参数，字段与 process-exporter 的同名命令行参数一一对应
+// 字符串类型的列表字段使用与命令行参数相同的逗号分隔格式，DefaultOptions 返回命令行参数的默认值
+type Options struct {
+	// Names 按名称监控的进程（-names），NameMode 和 NormalizeNames 为匹配方式
+	Names          []string
+	NameMode       string
+	NormalizeNames bool
+	// ExcludeNames 名称或命令行包含其中任意一个值的进程不监控（-exclude.names）
+	ExcludeNames []string
+	// PIDFiles 按 PID 文件监控的进程，分组名称为去掉 .pid 后缀的文件名（-pidfile）
+	PIDFiles []string
+	// Services 按服务名称监控的 Windows 服务（-services），其他平台上返回错误
+	Services []string
+	// ConfigFile YAML 配置文件（-config.file），ConfigPath 为 ncabatoff/process-exporter 格式的配置文件（-config.path）
+	ConfigFile string
+	ConfigPath string
+	// Children 不属于任何分组的子进程计入最近的已匹配祖先进程的分组（-children）
+	Children bool
+	// TopN 同时导出 CPU 和内存占用最高的 N 个进程（-top.n），0 表示不导出
+	TopN int
+	// Labels 附加在所有指标上的 name=value 标签（-labels）
+	Labels string
+
+	// Collectors 采集项名称 -> 是否开启，为 nil 时使用默认开启的采集项
+	Collectors map[string]bool
+	// Concurrency 采集时并发的 worker 数量（-collect-concurrency）
+	Concurrency int
+	// RefreshInterval 全量扫描进程列表的间隔（-refresh-interval）
+	RefreshInterval time.Duration
+	// WarmupDelay 启动时第二次刷新缓存的延迟（-startup.warmup-delay），0 表示不做第二次刷新
+	WarmupDelay time.Duration
+	// BackgroundInterval 后台采集的间隔（-collect.background-interval），0 表示每次抓取时采集
+	BackgroundInterval time.Duration
+	// ProcEvents 通过 proc connector 增量更新进程缓存（-proc-events），仅 Linux
+	ProcEvents bool
+
+	// FlappingRestarts 和 FlappingWindow 判断分组频繁重启的阈值（-flapping.restarts、-flapping.window）
+	FlappingRestarts int
+	FlappingWindow   time.Duration
+	// CPURecommendationWindow 计算 CPU 配额建议的滑动窗口（-cpu-recommendation.window）
+	CPURecommendationWindow time.Duration
+	// WorkingSet 清除页面引用位估算工作集（-memory.working-set），仅 Linux
+	WorkingSet bool
+	// ShardIndex 和 ShardCount 多个实例按 PID 分片时本实例的序号和实例总数（-shard.index、-shard.count）
+	ShardIndex int
+	ShardCount int
+	// ExcludeUIDs 和 ExcludeCgroups 扫描时直接跳过的 UID 和 cgroup 路径前缀，逗号分隔（-exclude.uids、-exclude.cgroups）
+	ExcludeUIDs    string
+	ExcludeCgroups string
+	// AuditLog auditd 日志路径（-audit.log），为空时不关联 execve 记录
+	AuditLog string
+	// AnnotationsDir 进程标注目录（-annotations.dir），为空时不加载
+	AnnotationsDir string
+	// FDsExhaustionWindow 推算 fd 耗尽时间的样本窗口（-fds.exhaustion-window）
+	FDsExhaustionWindow time.Duration
+	// MaxProcsPerGroup 每个分组最多输出多少个进程的进程级序列（-max-procs-per-group），0 表示不限制
+	MaxProcsPerGroup int
+	// PathWritePaths pathwrites 采集项监控的目录，逗号分隔（-path-writes.paths）
+	PathWritePaths string
+	// MMapMinSize 输出 process_mmap_file_bytes 的最小映射文件大小，单位 MiB（-mmaps.min-size）
+	MMapMinSize uint64
+	// IdleThresholds 空闲连接的统计阈值，逗号分隔（-connections.idle-thresholds）
+	IdleThresholds string
+}
+
+// DefaultOptions 返回与命令行参数默认值相同的参数，调用方只需要再设置要监控的进程
+func DefaultOptions() Options {
+	return Options{
+		NameMode:                "substring",
+		Concurrency:             4,
+		RefreshInterval:         30 * time.Second,
+		WarmupDelay:             time.Second,
+		FlappingRestarts:        3,
+		FlappingWindow:          10 * time.Minute,
+		CPURecommendationWindow: time.Hour,
+		ShardCount:              1,
+		FDsExhaustionWindow:     time.Hour,
+		MMapMinSize:             10,
+		IdleThresholds:          defaultIdleThresholds,
+	}
+}
+
+// enabledCollectors 返回开启的采集项，Collectors 为 nil 时为当前平台支持的默认采集项
+func (o Options) enabledCollectors() enabledCollectors {
+	if o.Collectors != nil {
+		return enabledCollectors(o.Collectors)
+	}
+	enabled := defaultCollectors()
+	for name := range unsupportedCollectors() {
+		delete(enabled, name)
+	}
+	return enabled
+}
+
+// NewReloader 按 opts 中的进程选择参数创建配置重载器，Load 读取当前的目标列表
+func NewReloader(opts Options) (*Reloader, error) {
+	r := newConfigReloader(opts.ConfigFile, opts.Names)
+	r.pidFiles = opts.PIDFiles
+	r.allowEmpty = opts.TopN > 0
+	mode, err := matcher.ParseNameMode(opts.NameMode)
+	if err != nil {
+		return nil, fmt.Errorf("-names.match-mode: %w", err)
+	}
+	r.nameOptions = matcher.NameOptions{Mode: mode, Normalize: opts.NormalizeNames}
+	r.excludeNames = matcher.ExcludeNames(opts.ExcludeNames, r.nameOptions)
+	if len(opts.Services) > 0 {
+		r.services = opts.Services
+		if _, err := queryServicePIDs(r.services); errors.Is(err, errors.ErrUnsupported) {
+			return nil, errors.New("-services is only supported on Windows")
+		}
+	}
+	r.ncabatoffPath = opts.ConfigPath
+	return r, nil
+}
+
+// New 检查参数并创建 ProcessCollector，读取一次配置得到目标列表，但不开始刷新进程缓存
+// 调用 Start 启动后台刷新，RegisterHandlers 注册 /metrics 等 HTTP 处理器
+func New(opts Options) (*ProcessCollector, error) {
+	switch {
+	case opts.ShardCount < 1 || opts.ShardIndex < 0 || opts.ShardIndex >= opts.ShardCount:
+		return nil, errors.New("-shard.index must be within [0, -shard.count)")
+	case opts.Concurrency < 1:
+		return nil, errors.New("-collect-concurrency must be at least 1")
+	case opts.RefreshInterval <= 0:
+		return nil, errors.New("-refresh-interval must be positive")
+	case opts.MaxProcsPerGroup < 0:
+		return nil, errors.New("-max-procs-per-group must not be negative")
+	case opts.TopN < 0:
+		return nil, errors.New("-top.n must not be negative")
+	case opts.FlappingRestarts < 0:
+		return nil, errors.New("-flapping.restarts must not be negative")
+	}
+	enabled := opts.enabledCollectors()
+	for name := range enabled {
+		if _, ok := collectorOptions[name]; !ok {
+			return nil, fmt.Errorf("unknown collector %q", name)
+		}
+	}
+
+	exclusions, err := parseScanExclusions(opts.ExcludeUIDs, opts.ExcludeCgroups)
+	if err != nil {
+		return nil, fmt.Errorf("-exclude.uids: %w", err)
+	}
+	idleThresholds, err := parseIdleThresholds(opts.IdleThresholds)
+	if err != nil {
+		return nil, fmt.Errorf("-connections.idle-thresholds: %w", err)
+	}
+	watchedPaths, err := parseWatchedPaths(opts.PathWritePaths)
+	if err != nil {
+		return nil, fmt.Errorf("-path-writes.paths: %w", err)
+	}
+	if enabled.has("pathwrites") && len(watchedPaths) == 0 {
+		return nil, errors.New("-collector.pathwrites requires -path-writes.paths")
+	}
+
+	reloader, err := NewReloader(opts)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := reloader.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+
+	c := newProcessCollector(
+		targets,
+		newRestartTracker(opts.FlappingRestarts, opts.FlappingWindow),
+		newCPURecommender(opts.CPURecommendationWindow),
+	)
+	c.opts = opts
+	c.reloader = reloader
+	c.workingSet = opts.WorkingSet
+	c.children = opts.Children
+	c.shard = shard{index: opts.ShardIndex, count: opts.ShardCount}
+	if c.constLabels, err = loadConstLabels(opts.ConfigFile, opts.Labels, c.shard.labels()); err != nil {
+		return nil, err
+	}
+	if err := checkConstLabels(c.constLabels, c); err != nil {
+		return nil, err
+	}
+	if c.envLabels, err = loadEnvLabels(opts.ConfigFile, c.constLabels); err != nil {
+		return nil, fmt.Errorf("env_labels: %w", err)
+	}
+	c.exclusions = exclusions
+	c.concurrency = opts.Concurrency
+	c.collectors = enabled
+	c.annotationsDir = opts.AnnotationsDir
+	c.idleThresholds = idleThresholds
+	c.mmapMinBytes = opts.MMapMinSize << 20
+	c.maxProcsPerGroup = opts.MaxProcsPerGroup
+	if opts.TopN > 0 {
+		c.top = newTopTracker(opts.TopN)
+	}
+	if enabled.has("fdexhaustion") {
+		c.fdExhaustion = newFDExhaustionTracker(opts.FDsExhaustionWindow)
+	}
+	if enabled.has("pathwrites") {
+		c.pathWrites = newPathWriteTracker(watchedPaths)
+	}
+	if enabled.has("netbytes") {
+		c.netBytes = newNetBytesTracker()
+	}
+	if enabled.has("percpu") {
+		c.perCPU = newPerCPUTracker()
+	}
+	if opts.AuditLog != "" {
+		c.audit = newAuditIndex(opts.AuditLog)
+	}
+	reloader.apply = c.SetTargets
+	return c, nil
+}
+
+// Reloader 返回重载配置文件的 Reloader，用于 SIGHUP 和 /-/reload
+func (c *ProcessCollector) Reloader() *Reloader {
+	return c.reloader
+}
+
+// TargetNames 当前监控的目标名称，用于日志输出
+func (c *ProcessCollector) TargetNames() []string {
+	return targetNames(c.targets.Load().list)
+}
+
+// Start 启动后台刷新进程缓存，预热完成后按 BackgroundInterval 开始后台采集，ctx 取消后全部停止
+// 预热期间已经可以抓取，后台采集在预热完成后开始，缓存的指标总是完整的
+func (c *ProcessCollector) Start(ctx context.Context) error {
+	c.StartCacheUpdater(ctx, c.opts.RefreshInterval)
+	go func() {
+		c.WarmUp(c.opts.WarmupDelay)
+		if c.opts.BackgroundInterval > 0 {
+			c.StartBackgroundCollection(ctx, c.opts.BackgroundInterval)
+		}
+	}()
+	if c.opts.ProcEvents {
+		if err := c.StartProcEvents(ctx); err != nil {
+			return fmt.Errorf("subscribing to process events: %w", err)
+		}
+	}
+	return nil
+}
+
+// HandlerOptions RegisterHandlers 的参数，字段与同名命令行参数对应
+type HandlerOptions struct {
+	// TimeoutOffset 从 Prometheus 的抓取超时中减去的时间（-scrape-timeout-offset）
+	TimeoutOffset time.Duration
+	// PIDLabel 进程级序列的 pid 标签输出方式（-pid-label），为空时与 PIDLabelPID 相同
+	PIDLabel string
+	// Aggregate /metrics 只输出分组聚合后的序列（-metrics.aggregate）
+	Aggregate bool
+	// SnapshotsKeep 保存最近多少次抓取的快照（-snapshots.keep），0 表示不保存
+	SnapshotsKeep int
+	// SelfMetrics 同时输出 exporter 自身的 Go 运行时和进程指标（-self-metrics）
+	SelfMetrics bool
+	// Version 首页显示的版本
+	Version string
+	// Links 首页上除 RegisterHandlers 注册的端点外额外列出的链接，例如 /remote/metrics
+	Links []string
+}
+
+// newScrapeHandler 按 opts 创建 /metrics 使用的 scrapeHandler，base 中注册配置重载和 exporter 自身的指标
+func (c *ProcessCollector) newScrapeHandler(opts HandlerOptions) (*scrapeHandler, error) {
+	if opts.PIDLabel == "" {
+		opts.PIDLabel = PIDLabelPID
+	}
+	if err := validatePIDLabel(opts.PIDLabel); err != nil {
+		return nil, err
+	}
+	if opts.SnapshotsKeep < 0 {
+		return nil, errors.New("-snapshots.keep must not be negative")
+	}
+
+	// 自定义注册表不包含默认的 Go 运行时和 exporter 自身的进程指标，所有指标都带上常量标签
+	// ProcessCollector 不注册在这里，由 scrapeHandler 按请求带上抓取超时注册
+	r := prometheus.NewRegistry()
+	reg := prometheus.WrapRegistererWith(c.constLabels, r)
+	if c.opts.ConfigFile != "" || c.opts.ConfigPath != "" {
+		if err := reg.Register(c.reloader); err != nil {
+			return nil, err
+		}
+	}
+	// 进程指标加上 process_exporter 前缀，避免和带标签的 process_open_fds 等指标重名冲突
+	if opts.SelfMetrics {
+		if err := reg.Register(collectors.NewGoCollector()); err != nil {
+			return nil, err
+		}
+		if err := reg.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{Namespace: "process_exporter"})); err != nil {
+			return nil, err
+		}
+	}
+
+	handler := &scrapeHandler{
+		base:      r,
+		collector: c,
+		labels:    c.constLabels,
+		offset:    opts.TimeoutOffset,
+		aggregate: opts.Aggregate,
+		carry:     newCounterCarry(c.liveStarts),
+		opts: promhttp.HandlerOpts{
+			ErrorLog:      logging.ErrorLogger(),
+			ErrorHandling: promhttp.ContinueOnError,
+			// OpenMetrics 格式才能输出重启和退出事件的 exemplar，以及以进程启动时间为准的 _created
+			EnableOpenMetrics:                   true,
+			EnableOpenMetricsTextCreatedSamples: true,
+		},
+	}
+	if opts.PIDLabel != PIDLabelPID {
+		handler.ids = newProcessIDs(opts.PIDLabel, c)
+	}
+	return handler, nil
+}
+
+// RegisterHandlers 在 mux 上注册 /metrics、/metrics/detailed、/probe、/-/healthy、/-/ready 和首页 /，保存快照时还有 /debug/snapshots
+// 返回与 /metrics 内容相同、不限制采集时间的 Gatherer，用于主动推送
+func (c *ProcessCollector) RegisterHandlers(mux *http.ServeMux, opts HandlerOptions) (prometheus.Gatherer, error) {
+	handler, err := c.newScrapeHandler(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// /metrics/detailed 总是输出进程级别的序列，开启 Aggregate 时 /metrics 只输出分组聚合后的序列
+	// 两者共用采集结果，同一轮抓取中的聚合值由明细中的同一批数据得到
+	// 快照保存进程级别的数据，开启聚合时只记录 /metrics/detailed 的抓取
+	handler.pass, handler.endpoint = newSharedPass(), "/metrics"
+	detailed := *handler
+	detailed.aggregate = false
+	detailed.carry = newCounterCarry(c.liveStarts)
+	detailed.endpoint = "/metrics/detailed"
+	links := []string{"/metrics", "/metrics/detailed"}
+	if opts.SnapshotsKeep > 0 {
+		links = append(links, "/debug/snapshots")
+		snapshots := newSnapshotStore(opts.SnapshotsKeep)
+		mux.HandleFunc("/debug/snapshots", snapshots.serveList)
+		mux.Handle("/debug/snapshots/diff", snapshots)
+		if opts.Aggregate {
+			detailed.snapshots = snapshots
+		} else {
+			handler.snapshots = snapshots
+		}
+	}
+	mux.Handle("/metrics", handler)
+	mux.Handle("/metrics/detailed", &detailed)
+	mux.Handle("/probe", &probeHandler{
+		collector:   c,
+		labels:      handler.labels,
+		offset:      handler.offset,
+		opts:        handler.opts,
+		nameOptions: c.reloader.nameOptions,
+	})
+	mux.HandleFunc("/-/healthy", Healthy)
+	mux.HandleFunc("/-/ready", c.serveReady)
+	links = append(links, "/-/healthy", "/-/ready")
+	mux.Handle("/", LandingPage(opts.Version, c, append(links, opts.Links...)))
+	return handler.gatherer(time.Time{}, c.collectors, false), nil
+}
+
+// Once 不启动后台刷新，刷新一次进程缓存后采集一次，按 format（prometheus、influx 或 jsonl）编码写入 w
+// WarmupDelay 大于 0 时间隔该时长再刷新一次，CPU 使用量等需要两次采样的指标才有值；用于 -once
+func (c *ProcessCollector) Once(w io.Writer, format string, opts HandlerOptions) error {
+	enc, err := encoder.New(format)
+	if err != nil {
+		return err
+	}
+	handler, err := c.newScrapeHandler(opts)
+	if err != nil {
+		return err
+	}
+	c.refreshProcessCache()
+	if c.opts.WarmupDelay > 0 {
+		time.Sleep(c.opts.WarmupDelay)
+		c.refreshProcessCache()
+	}
+	families, err := handler.gatherer(time.Time{}, c.collectors, false).Gather()
+	if err != nil {
+		// 与 promhttp.ContinueOnError 一致，记录错误后输出已采集到的部分
+		slog.Error("Error gathering metrics", "err", err)
+	}
+	return enc.Encode(w, families, time.Now())
+}
@@ -0,0 +1,123 @@
+package collector
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// parseWatchedPaths 解析 -path-writes.paths 的值，返回去掉末尾 / 的绝对路径，路径必须是合法的 UTF-8
+func parseWatchedPaths(s string) ([]string, error) {
+	var paths []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			return nil, fmt.Errorf("watched path %q must be absolute", p)
+		}
+		// 监控路径即 path 标签的值，非 UTF-8 的标签值会让 MustNewConstMetric panic
+		if !utf8.ValidString(p) {
+			return nil, fmt.Errorf("watched path %q is not valid UTF-8", p)
+		}
+		paths = append(paths, filepath.Clean(p))
+	}
+	return paths, nil
+}
+
+// writeFD 进程以写方式打开的一个文件
+type writeFD struct {
+	fd    int
+	inode uint64
+	path  string
+	// pos 文件偏移，追加写入的日志文件即已写入的字节数
+	pos int64
+}
+
+// fdKey 标识一次打开的文件，fd 编号被复用时 inode 通常会变化
+type fdKey struct {
+	fd    int
+	inode uint64
+}
+
+// procKey 标识一次进程运行，PID 复用时计数从 0 开始
+type procKey struct {
+	pid   int32
+	start int64
+}
+
+// pathWriteTracker 按进程累计写入监控路径下文件的字节数
+// 通过两次采样之间文件偏移的增量估算：适合日志这类追加写入的文件，
+// 两次采样之间打开又关闭的文件、pwrite 和通过 mmap 的写入不会被统计，向前 seek 会被当作写入，偏移变小（截断、轮转、向后 seek）时重新建立基线
+type pathWriteTracker struct {
+	paths []string
+
+	mu     sync.Mutex
+	last   map[procKey]map[fdKey]int64
+	totals map[procKey]map[string]float64
+}
+
+func newPathWriteTracker(paths []string) *pathWriteTracker {
+	return &pathWriteTracker{
+		paths:  paths,
+		last:   make(map[procKey]map[fdKey]int64),
+		totals: make(map[procKey]map[string]float64),
+	}
+}
+
+// watchedPath 返回文件所在的监控路径，不在任何监控路径下时返回 false
+func (t *pathWriteTracker) watchedPath(file string) (string, bool) {
+	for _, p := range t.paths {
+		if file == p || strings.HasPrefix(file, p+"/") || p == "/" {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// Observe 记录进程本次采样的写文件偏移，返回每个监控路径的累计写入字节数
+func (t *pathWriteTracker) Observe(key procKey, fds []writeFD) map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	totals, ok := t.totals[key]
+	if !ok {
+		totals = make(map[string]float64, len(t.paths))
+		t.totals[key] = totals
+	}
+	last := t.last[key]
+	current := make(map[fdKey]int64, len(fds))
+	for _, f := range fds {
+		path, ok := t.watchedPath(f.path)
+		if !ok {
+			continue
+		}
+		k := fdKey{fd: f.fd, inode: f.inode}
+		current[k] = f.pos
+		if prev, ok := last[k]; ok && f.pos > prev {
+			totals[path] += float64(f.pos - prev)
+		}
+	}
+	t.last[key] = current
+
+	result := make(map[string]float64, len(t.paths))
+	for _, p := range t.paths {
+		result[p] = totals[p]
+	}
+	return result
+}
+
+// Prune 删除已不在缓存中的进程的状态
+func (t *pathWriteTracker) Prune(cache map[int32]CachedProcess) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.totals {
+		if cached, ok := cache[key.pid]; !ok || cached.StartTime != key.start {
+			delete(t.totals, key)
+			delete(t.last, key)
+		}
+	}
+}
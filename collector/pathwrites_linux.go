@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"process-exporter/internal/procfs"
+)
+
+// readWriteFDs 读取进程以写方式（O_WRONLY 或 O_RDWR）打开的普通文件及其当前偏移
+// 偏移和打开方式来自 /proc/pid/fdinfo，fd 可能在读取期间被关闭，读取失败的 fd 直接跳过
+func readWriteFDs(pid int32) ([]writeFD, error) {
+	dir := procfs.PID(pid, "fd")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var fds []writeFD
+	for _, entry := range entries {
+		fd, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		target, err := os.Readlink(filepath.Join(dir, entry.Name()))
+		if err != nil || !filepath.IsAbs(target) {
+			// socket:[...]、pipe:[...] 等不是文件路径
+			continue
+		}
+		pos, flags, ok := readFDInfo(procfs.PID(pid, "fdinfo", entry.Name()))
+		if !ok || flags&syscall.O_ACCMODE == syscall.O_RDONLY {
+			continue
+		}
+		fi, err := os.Stat(filepath.Join(dir, entry.Name()))
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		var inode uint64
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+			inode = st.Ino
+		}
+		fds = append(fds, writeFD{fd: fd, inode: inode, path: target, pos: pos})
+	}
+	return fds, nil
+}
+
+// readFDInfo 读取 fdinfo 中的 pos 和 flags（八进制）字段
+func readFDInfo(path string) (pos int64, flags int, ok bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	var havePos, haveFlags bool
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, found := bytes.Cut(scanner.Bytes(), []byte(":"))
+		if !found {
+			continue
+		}
+		value = bytes.TrimSpace(value)
+		switch string(key) {
+		case "pos":
+			pos, err = strconv.ParseInt(string(value), 10, 64)
+			havePos = err == nil
+		case "flags":
+			f, err := strconv.ParseInt(string(value), 8, 64)
+			flags, haveFlags = int(f), err == nil
+		}
+	}
+	return pos, flags, havePos && haveFlags
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readWriteFDs(pid int32) ([]writeFD, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWatchedPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", input: "", want: nil},
+		{name: "cleaned", input: "/var/log/, /data//logs", want: []string{"/var/log", "/data/logs"}},
+		{name: "relative path", input: "/var/log,logs", wantErr: true},
+		{name: "invalid utf-8", input: "/var/log/\xff", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWatchedPaths(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWatchedPaths(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseWatchedPaths(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathWriteTracker(t *testing.T) {
+	tracker := newPathWriteTracker([]string{"/var/log"})
+	key := procKey{pid: 1, start: 100}
+	steps := []struct {
+		name string
+		fds  []writeFD
+		want float64
+	}{
+		{"first sample is the baseline", []writeFD{{fd: 3, inode: 10, path: "/var/log/app.log", pos: 1000}}, 0},
+		{"growth is counted", []writeFD{{fd: 3, inode: 10, path: "/var/log/app.log", pos: 1500}, {fd: 4, inode: 11, path: "/tmp/x", pos: 9000}}, 500},
+		{"rotation resets the baseline", []writeFD{{fd: 3, inode: 10, path: "/var/log/app.log", pos: 100}}, 500},
+		{"growth after rotation", []writeFD{{fd: 3, inode: 10, path: "/var/log/app.log", pos: 300}}, 700},
+		{"reused fd is a new file", []writeFD{{fd: 3, inode: 12, path: "/var/log/other.log", pos: 5000}}, 700},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if got := tracker.Observe(key, step.fds)["/var/log"]; got != step.want {
+				t.Errorf("written = %v, want %v", got, step.want)
+			}
+		})
+	}
+
+	tracker.Prune(map[int32]CachedProcess{1: {StartTime: 200}})
+	if len(tracker.totals) != 0 || len(tracker.last) != 0 {
+		t.Errorf("state of a restarted process kept after Prune")
+	}
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"strconv"
+	"sync"
+)
+
+// perCPUTracker 按 CPU 累计进程的 CPU 时间
+// 每次采集时把每个线程两次采集之间增加的 CPU 时间全部计入它最后一次运行所在的 CPU，
+// 线程在两次采集之间迁移到其他 CPU 时会计入错误的 CPU，采集间隔越短越准确；
+// 进程第一次采集时只记录基线，之后新出现的线程计入创建以来的全部 CPU 时间
+type perCPUTracker struct {
+	mu     sync.Mutex
+	last   map[procKey]map[string]float64
+	totals map[procKey]map[string]float64
+}
+
+func newPerCPUTracker() *perCPUTracker {
+	return &perCPUTracker{
+		last:   make(map[procKey]map[string]float64),
+		totals: make(map[procKey]map[string]float64),
+	}
+}
+
+// Observe 记录进程本次采集的线程，返回 CPU 编号到累计 CPU 时间（秒）的映射
+// 不知道最后运行在哪个 CPU 的线程不统计
+func (t *perCPUTracker) Observe(key procKey, threads []threadStat) map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last, seen := t.last[key]
+	totals := t.totals[key]
+	if totals == nil {
+		totals = make(map[string]float64)
+		t.totals[key] = totals
+	}
+	current := make(map[string]float64, len(threads))
+	for _, th := range threads {
+		used := th.user + th.system
+		current[th.tid] = used
+		if !seen || th.cpu < 0 {
+			continue
+		}
+		if delta := used - last[th.tid]; delta > 0 {
+			totals[strconv.Itoa(th.cpu)] += delta
+		}
+	}
+	t.last[key] = current
+
+	result := make(map[string]float64, len(totals))
+	for cpu, v := range totals {
+		result[cpu] = v
+	}
+	return result
+}
+
+// Prune 删除已不在缓存中的进程的状态
+func (t *perCPUTracker) Prune(cache map[int32]CachedProcess) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.last {
+		if cached, ok := cache[key.pid]; !ok || cached.StartTime != key.start {
+			delete(t.totals, key)
+			delete(t.last, key)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// pidFileGroup PID 文件对应的分组名称，即去掉 .pid 后缀的文件名，/var/run/myapp.pid 为 myapp
+func pidFileGroup(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".pid")
+}
+
+// pidFileState PID 文件最近一次读取的结果
+type pidFileState struct {
+	pid int32
+	// stale 文件不存在、内容无效、PID 对应的进程不存在，或 PID 已被其他进程复用
+	stale bool
+}
+
+// readPIDFile 读取 PID 文件，返回其中的 PID 和对应的进程
+// 进程启动时间晚于文件的修改时间说明写入该 PID 的进程已经退出，PID 被其他进程复用
+func readPIDFile(path string) (*process.Process, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 32)
+	if err != nil || pid <= 0 {
+		return nil, fmt.Errorf("%s: invalid pid %q", path, strings.TrimSpace(string(content)))
+	}
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return nil, fmt.Errorf("%s: pid %d: %w", path, pid, err)
+	}
+	createTime, err := p.CreateTime()
+	if err != nil {
+		return nil, fmt.Errorf("%s: pid %d: %w", path, pid, err)
+	}
+	// 文件系统的时间戳精度可能只有秒，留出 1 秒的余量
+	if time.UnixMilli(createTime).After(info.ModTime().Add(time.Second)) {
+		return nil, fmt.Errorf("%s: pid %d was reused by a process started after the file was written", path, pid)
+	}
+	return p, nil
+}
+
+// refreshPIDFiles 重新读取所有 PID 文件，更新 pidFilePIDs 和 pidFiles
+// 文件中的 PID 变化或变为过期时记录日志，调用方需要持有 refreshMu
+func (c *ProcessCollector) refreshPIDFiles(targets []Target) {
+	previous := c.pidFiles.Load()
+	states := make(map[string]pidFileState, len(targets))
+	for _, t := range targets {
+		var state pidFileState
+		p, err := readPIDFile(t.PIDFile)
+		if err == nil {
+			state.pid = p.Pid
+			c.pidFilePIDs[p.Pid] = t.Name
+		} else {
+			state.stale = true
+		}
+		states[t.Name] = state
+
+		var old pidFileState
+		if previous != nil {
+			old = (*previous)[t.Name]
+		}
+		switch {
+		case state.stale && (previous == nil || !old.stale):
+			slog.Warn("PID file is stale", "group", t.Name, "path", t.PIDFile, "err", err)
+		case !state.stale && old.pid != 0 && old.pid != state.pid:
+			slog.Info("PID file changed", "group", t.Name, "path", t.PIDFile, "old_pid", old.pid, "pid", state.pid)
+		}
+	}
+	c.pidFiles.Store(&states)
+}
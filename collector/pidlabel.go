@@ -0,0 +1,155 @@
+package collector
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// pid 标签的输出方式
+const (
+	PIDLabelPID       = "pid"
+	PIDLabelOrdinal   = "ordinal"
+	PIDLabelStartTime = "starttime"
+)
+
+// idLabel 替换 pid 标签时使用的标签名
+const idLabel = "id"
+
+func validatePIDLabel(mode string) error {
+	switch mode {
+	case PIDLabelPID, PIDLabelOrdinal, PIDLabelStartTime:
+		return nil
+	}
+	return fmt.Errorf("unknown -pid-label %q, expected %s, %s or %s", mode, PIDLabelPID, PIDLabelOrdinal, PIDLabelStartTime)
+}
+
+// processIDs 为进程分配代替 pid 的稳定标识
+// ordinal：同名进程中的序号，进程退出后序号空出来给下一个新进程，重启后的进程沿用原来的序列，rate() 把它当作计数器重置
+// starttime：PID 和启动时间的哈希，每次运行一个标识，与 pid 相比不会因为 PID 复用而混在一起
+type processIDs struct {
+	mode      string
+	collector *ProcessCollector
+
+	mu sync.Mutex
+	// ordinals 进程名称 -> 一次进程运行 -> 序号
+	ordinals map[string]map[procKey]int
+}
+
+func newProcessIDs(mode string, collector *ProcessCollector) *processIDs {
+	return &processIDs{mode: mode, collector: collector, ordinals: make(map[string]map[procKey]int)}
+}
+
+// assign 按当前缓存更新标识，返回 PID -> 标识
+func (ids *processIDs) assign() map[string]string {
+	c := ids.collector
+	c.rwMutex.RLock()
+	procs := make([]CachedProcess, 0, len(c.cachedProcs))
+	for _, cached := range c.cachedProcs {
+		procs = append(procs, cached)
+	}
+	c.rwMutex.RUnlock()
+
+	result := make(map[string]string, len(procs))
+	if ids.mode == PIDLabelStartTime {
+		for _, p := range procs {
+			h := fnv.New32a()
+			fmt.Fprintf(h, "%d-%d", p.Proc.Pid, p.StartTime)
+			result[strconv.Itoa(int(p.Proc.Pid))] = fmt.Sprintf("%08x", h.Sum32())
+		}
+		return result
+	}
+
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+	// 先释放已退出进程的序号，再按启动时间顺序给新进程分配最小的空闲序号
+	alive := make(map[string]map[procKey]struct{})
+	for _, p := range procs {
+		if alive[p.Name] == nil {
+			alive[p.Name] = make(map[procKey]struct{})
+		}
+		alive[p.Name][procKey{pid: p.Proc.Pid, start: p.StartTime}] = struct{}{}
+	}
+	for name, keys := range ids.ordinals {
+		for key := range keys {
+			if _, ok := alive[name][key]; !ok {
+				delete(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			delete(ids.ordinals, name)
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool {
+		if procs[i].StartTime != procs[j].StartTime {
+			return procs[i].StartTime < procs[j].StartTime
+		}
+		return procs[i].Proc.Pid < procs[j].Proc.Pid
+	})
+	for _, p := range procs {
+		key := procKey{pid: p.Proc.Pid, start: p.StartTime}
+		keys := ids.ordinals[p.Name]
+		if keys == nil {
+			keys = make(map[procKey]int)
+			ids.ordinals[p.Name] = keys
+		}
+		ordinal, ok := keys[key]
+		if !ok {
+			ordinal = freeOrdinal(keys)
+			keys[key] = ordinal
+		}
+		result[strconv.Itoa(int(p.Proc.Pid))] = strconv.Itoa(ordinal)
+	}
+	return result
+}
+
+// freeOrdinal 返回未被占用的最小序号
+func freeOrdinal(keys map[procKey]int) int {
+	used := make(map[int]struct{}, len(keys))
+	for _, o := range keys {
+		used[o] = struct{}{}
+	}
+	for o := 0; ; o++ {
+		if _, ok := used[o]; !ok {
+			return o
+		}
+	}
+}
+
+// pidLabelGatherer 把进程级指标的 pid 标签替换为 id 标签，值由 processIDs 分配
+// 缓存中已经没有的进程（采集期间刚退出）保留原来的 PID 作为标识
+type pidLabelGatherer struct {
+	gatherer prometheus.Gatherer
+	ids      *processIDs
+}
+
+func (g pidLabelGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	ids := g.ids.assign()
+	name := idLabel
+	for _, mf := range families {
+		if !hasPIDLabel(mf) {
+			continue
+		}
+		for _, m := range mf.Metric {
+			for i, lp := range m.Label {
+				if lp.GetName() != "pid" {
+					continue
+				}
+				id, ok := ids[lp.GetValue()]
+				if !ok {
+					id = lp.GetValue()
+				}
+				m.Label[i] = &dto.LabelPair{Name: &name, Value: &id}
+			}
+			// 标签需要按名称排序
+			sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+		}
+	}
+	return families, err
+}
@@ -0,0 +1,175 @@
+package collector
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/shirou/gopsutil/v4/process"
+	"google.golang.org/protobuf/proto"
+)
+
+// cached 构造缓存中的进程
+func cached(pid int32, name string, start int64) CachedProcess {
+	return CachedProcess{Proc: &process.Process{Pid: pid}, Name: name, Group: name, StartTime: start}
+}
+
+func collectorWith(procs ...CachedProcess) *ProcessCollector {
+	c := &ProcessCollector{cachedProcs: make(map[int32]CachedProcess)}
+	for _, p := range procs {
+		c.cachedProcs[p.Proc.Pid] = p
+	}
+	return c
+}
+
+func TestProcessIDsOrdinal(t *testing.T) {
+	// 每一步替换缓存中的进程，检查分配到的序号
+	steps := []struct {
+		name  string
+		procs []CachedProcess
+		want  map[string]string
+	}{
+		{
+			name:  "ordinals by start time per name",
+			procs: []CachedProcess{cached(30, "nginx", 200), cached(20, "nginx", 100), cached(10, "mysql", 300)},
+			want:  map[string]string{"20": "0", "30": "1", "10": "0"},
+		},
+		{
+			name:  "ordinals stable while running",
+			procs: []CachedProcess{cached(30, "nginx", 200), cached(20, "nginx", 100), cached(10, "mysql", 300), cached(40, "nginx", 50)},
+			want:  map[string]string{"20": "0", "30": "1", "10": "0", "40": "2"},
+		},
+		{
+			name:  "restarted process takes the freed ordinal",
+			procs: []CachedProcess{cached(30, "nginx", 200), cached(50, "nginx", 400), cached(10, "mysql", 300), cached(40, "nginx", 50)},
+			want:  map[string]string{"30": "1", "50": "0", "10": "0", "40": "2"},
+		},
+		{
+			name:  "reused pid is a new process",
+			procs: []CachedProcess{cached(30, "nginx", 500), cached(50, "nginx", 400), cached(10, "mysql", 300), cached(40, "nginx", 50)},
+			want:  map[string]string{"30": "1", "50": "0", "10": "0", "40": "2"},
+		},
+		{
+			name:  "all exited",
+			procs: nil,
+			want:  map[string]string{},
+		},
+	}
+
+	c := collectorWith()
+	ids := newProcessIDs(PIDLabelOrdinal, c)
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			c.cachedProcs = collectorWith(step.procs...).cachedProcs
+			if got := ids.assign(); !reflect.DeepEqual(got, step.want) {
+				t.Errorf("assign() = %v, want %v", got, step.want)
+			}
+		})
+	}
+	if len(ids.ordinals) != 0 {
+		t.Errorf("ordinals of exited processes kept: %v", ids.ordinals)
+	}
+}
+
+func TestProcessIDsStartTime(t *testing.T) {
+	hex := regexp.MustCompile(`^[0-9a-f]{8}$`)
+	first := newProcessIDs(PIDLabelStartTime, collectorWith(cached(10, "nginx", 100), cached(20, "nginx", 100))).assign()
+	again := newProcessIDs(PIDLabelStartTime, collectorWith(cached(10, "nginx", 100))).assign()
+	reused := newProcessIDs(PIDLabelStartTime, collectorWith(cached(10, "nginx", 200))).assign()
+
+	for pid, id := range first {
+		if !hex.MatchString(id) {
+			t.Errorf("id of pid %s = %q, want 8 hex digits", pid, id)
+		}
+	}
+	if first["10"] == first["20"] {
+		t.Errorf("different processes share id %s", first["10"])
+	}
+	if first["10"] != again["10"] {
+		t.Errorf("id of the same process changed: %s -> %s", first["10"], again["10"])
+	}
+	if first["10"] == reused["10"] {
+		t.Errorf("reused pid kept id %s", first["10"])
+	}
+}
+
+// labels 把序列的标签转成 name=value 列表，保持原有顺序
+func labels(m *dto.Metric) []string {
+	var got []string
+	for _, lp := range m.Label {
+		got = append(got, lp.GetName()+"="+lp.GetValue())
+	}
+	return got
+}
+
+func TestPIDLabelGatherer(t *testing.T) {
+	c := collectorWith(cached(20, "nginx", 100), cached(30, "nginx", 200))
+	metric := func(pid string) *dto.Metric {
+		return &dto.Metric{
+			Label: []*dto.LabelPair{
+				{Name: proto.String("pid"), Value: proto.String(pid)},
+				{Name: proto.String("process_name"), Value: proto.String("nginx")},
+			},
+			Gauge: &dto.Gauge{Value: proto.Float64(1)},
+		}
+	}
+	other := &dto.MetricFamily{
+		Name:   proto.String("process_exporter_build_info"),
+		Type:   dto.MetricType_GAUGE.Enum(),
+		Metric: []*dto.Metric{{Label: []*dto.LabelPair{{Name: proto.String("version"), Value: proto.String("1")}}, Gauge: &dto.Gauge{Value: proto.Float64(1)}}},
+	}
+	g := pidLabelGatherer{
+		gatherer: prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return []*dto.MetricFamily{{
+				Name:   proto.String("process_up"),
+				Type:   dto.MetricType_GAUGE.Enum(),
+				Metric: []*dto.Metric{metric("30"), metric("20"), metric("99")},
+			}, other}, nil
+		}),
+		ids: newProcessIDs(PIDLabelOrdinal, c),
+	}
+
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		got  *dto.Metric
+		want []string
+	}{
+		{"pid replaced by ordinal", families[0].Metric[0], []string{"id=1", "process_name=nginx"}},
+		{"oldest process gets ordinal 0", families[0].Metric[1], []string{"id=0", "process_name=nginx"}},
+		{"process not in cache keeps pid", families[0].Metric[2], []string{"id=99", "process_name=nginx"}},
+		{"family without pid label unchanged", families[1].Metric[0], []string{"version=1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := labels(tt.got); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("labels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePIDLabel(t *testing.T) {
+	tests := []struct {
+		mode    string
+		wantErr bool
+	}{
+		{PIDLabelPID, false},
+		{PIDLabelOrdinal, false},
+		{PIDLabelStartTime, false},
+		{"", true},
+		{"hash", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			if err := validatePIDLabel(tt.mode); (err != nil) != tt.wantErr {
+				t.Errorf("validatePIDLabel(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"process-exporter/matcher"
+)
+
+// probeHandler 处理 /probe?name=<名称>，按 multi-target exporter 的方式只采集请求中指定的进程
+// 一个实例可以服务多个抓取任务，各自使用不同的进程选择和抓取间隔
+// 每次请求都会扫描一次进程列表，不影响 /metrics 使用的缓存
+type probeHandler struct {
+	collector *ProcessCollector
+	labels    prometheus.Labels
+	offset    time.Duration
+	opts      promhttp.HandlerOpts
+	// nameOptions 与 -names 相同，由 -names.match-mode 和 -names.normalize 指定
+	nameOptions matcher.NameOptions
+}
+
+func (h *probeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	names := query["name"]
+	if len(names) == 0 {
+		http.Error(w, "name parameter is missing", http.StatusBadRequest)
+		return
+	}
+	enabled, err := requestedCollectors(h.collector.collectors, query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 与 -names 的匹配方式相同，每个 name 是一个分组
+	targets := make([]Target, 0, len(names))
+	for _, name := range names {
+		rule, err := matcher.NameRule(name, h.nameOptions)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		targets = append(targets, Target{Name: name, Rule: rule})
+	}
+	pc := h.collector.probe(targets)
+
+	reg := prometheus.NewRegistry()
+	prometheus.WrapRegistererWith(h.labels, reg).MustRegister(deadlineCollector{
+		collector:  pc,
+		deadline:   scrapeDeadline(r, h.offset),
+		collectors: enabled,
+	})
+	promhttp.HandlerFor(reg, h.opts).ServeHTTP(w, r)
+}
+
+// probe 创建只包含 targets 的临时采集器并扫描一次进程列表
+// 沿用当前采集器的采集项、分片、扫描排除和标注，不清除 referenced 标记（工作集估算只由主缓存负责）
+// 重启、CPU 建议等需要历史数据的指标在临时采集器中没有意义
+func (c *ProcessCollector) probe(targets []Target) *ProcessCollector {
+	pc := newProcessCollector(targets, newRestartTracker(0, 0), newCPURecommender(0))
+	pc.collectors = c.collectors
+	pc.shard = c.shard
+	pc.exclusions = c.exclusions
+	pc.concurrency = c.concurrency
+	pc.idleThresholds = c.idleThresholds
+	pc.mmapMinBytes = c.mmapMinBytes
+	pc.audit = c.audit
+	pc.annotations.Store(c.annotations.Load())
+	pc.refreshLocked()
+	return pc
+}
@@ -0,0 +1,150 @@
+package collector
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// 内核 proc connector 相关常量，见 linux/connector.h 和 linux/cn_proc.h
+const (
+	cnIdxProc         = 0x1
+	cnValProc         = 0x1
+	procCnMcastListen = 1
+
+	procEventFork = 0x00000001
+	procEventExec = 0x00000002
+	procEventExit = 0x80000000
+
+	// struct cn_msg 的长度，proc_event 紧跟其后
+	cnMsgLen = 20
+)
+
+// StartProcEvents 通过 netlink proc connector 订阅进程的 fork/exec/exit 事件
+// 增量更新进程缓存，两次全量扫描之间启动的短命进程也能被采集到
+func (c *ProcessCollector) StartProcEvents(ctx context.Context) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, unix.NETLINK_CONNECTOR)
+	if err != nil {
+		return err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: cnIdxProc}); err != nil {
+		unix.Close(fd)
+		return err
+	}
+	if err := subscribeProcEvents(fd); err != nil {
+		unix.Close(fd)
+		return err
+	}
+
+	// 非阻塞的 fd 交给 os.File 后由 runtime 的 poller 管理，Close 会唤醒阻塞的 Read 并在其返回后才真正关闭 fd，
+	// 不会出现直接 close 裸 fd 时 fd 被复用、Recvfrom 读到别的文件的竞争
+	f := os.NewFile(uintptr(fd), "proc-connector")
+	rescan := make(chan struct{}, 1)
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+	go c.rescanProcesses(ctx, rescan)
+	go c.readProcEvents(ctx, f, rescan)
+	return nil
+}
+
+// rescanProcesses 事件丢失后做全量扫描，扫描期间再次丢失的请求合并为一次
+func (c *ProcessCollector) rescanProcesses(ctx context.Context, rescan <-chan struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-rescan:
+			c.refreshProcessCache()
+		}
+	}
+}
+
+// subscribeProcEvents 发送 PROC_CN_MCAST_LISTEN 开始接收事件
+func subscribeProcEvents(fd int) error {
+	buf := make([]byte, unix.NLMSG_HDRLEN+cnMsgLen+4)
+	ne := binary.NativeEndian
+
+	// struct nlmsghdr
+	ne.PutUint32(buf[0:], uint32(len(buf)))
+	ne.PutUint16(buf[4:], unix.NLMSG_DONE)
+
+	// struct cn_msg
+	msg := buf[unix.NLMSG_HDRLEN:]
+	ne.PutUint32(msg[0:], cnIdxProc)
+	ne.PutUint32(msg[4:], cnValProc)
+	ne.PutUint16(msg[16:], 4)
+
+	// enum proc_cn_mcast_op
+	ne.PutUint32(msg[cnMsgLen:], procCnMcastListen)
+
+	return unix.Sendto(fd, buf, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK})
+}
+
+func (c *ProcessCollector) readProcEvents(ctx context.Context, f *os.File, rescan chan<- struct{}) {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, os.ErrClosed) {
+				return
+			}
+			if errors.Is(err, unix.ENOBUFS) {
+				// 事件太多，接收缓冲区溢出丢了事件，做一次全量扫描兜底；已经有扫描在排队时不再重复
+				slog.Warn("Process event buffer overrun, rescanning processes")
+				select {
+				case rescan <- struct{}{}:
+				default:
+				}
+				continue
+			}
+			slog.Error("Error reading process events, falling back to periodic scans", "err", err)
+			return
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			slog.Warn("Error parsing process event", "err", err)
+			continue
+		}
+		for _, m := range msgs {
+			c.handleProcEvent(m.Data)
+		}
+	}
+}
+
+// handleProcEvent 解析 struct proc_event，只处理进程（而非线程）级别的事件
+func (c *ProcessCollector) handleProcEvent(data []byte) {
+	// what(4) + cpu(4) + timestamp_ns(8) 之后是 event_data 联合体
+	const eventData = cnMsgLen + 16
+	if len(data) < eventData+16 {
+		return
+	}
+	ne := binary.NativeEndian
+	what := ne.Uint32(data[cnMsgLen:])
+	ev := data[eventData:]
+
+	switch what {
+	case procEventFork:
+		childPid, childTgid := int32(ne.Uint32(ev[8:])), int32(ne.Uint32(ev[12:]))
+		if childPid == childTgid {
+			c.trackProcess(childTgid)
+		}
+	case procEventExec:
+		pid, tgid := int32(ne.Uint32(ev[0:])), int32(ne.Uint32(ev[4:]))
+		if pid == tgid {
+			c.trackProcess(tgid)
+		}
+	case procEventExit:
+		pid, tgid := int32(ne.Uint32(ev[0:])), int32(ne.Uint32(ev[4:]))
+		if pid == tgid {
+			c.untrackProcess(tgid)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package collector
+
+import (
+	"context"
+	"errors"
+)
+
+// StartProcEvents 只在 Linux 上支持
+func (c *ProcessCollector) StartProcEvents(ctx context.Context) error {
+	return errors.ErrUnsupported
+}
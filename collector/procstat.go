@@ -0,0 +1,151 @@
+package collector
+
+import (
+	"errors"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// procStat 一次解析 /proc/pid/stat 得到的字段
+type procStat struct {
+	// user、system CPU 时间，单位秒
+	user, system float64
+	// rss、vms 单位字节
+	rss, vms    uint64
+	state       string
+	threads     int32
+	minorFaults uint64
+	majorFaults uint64
+	// blkioDelay 等待块设备 IO 完成的累计时间，单位秒
+	blkioDelay float64
+}
+
+// procStatus 一次解析 /proc/pid/status 得到的字段
+type procStatus struct {
+	// swap、rssPeak 即 VmSwap、VmHWM，单位字节，内核线程没有这两个字段时为 0
+	swap, rssPeak uint64
+	// voluntary、involuntary 主动和被动上下文切换次数
+	voluntary, involuntary int64
+}
+
+// procReader 采集单个进程时读取 CPU、内存、状态等字段
+// Linux 上 /proc/pid/stat 和 /proc/pid/status 各只读取、解析一次，第一次用到时读取；
+// gopsutil 每个方法都会重新打开并解析对应的文件，只在其他系统上作为后备
+type procReader struct {
+	p *process.Process
+
+	stat      *procStat
+	statErr   error
+	status    *procStatus
+	statusErr error
+}
+
+func newProcReader(p *process.Process) *procReader {
+	return &procReader{p: p}
+}
+
+// loadStat 读取 /proc/pid/stat，返回 false 表示当前系统没有快速路径
+func (r *procReader) loadStat() bool {
+	if r.stat == nil && r.statErr == nil {
+		s, err := readProcStat(r.p.Pid)
+		r.stat, r.statErr = &s, err
+	}
+	return !errors.Is(r.statErr, errors.ErrUnsupported)
+}
+
+// loadStatus 读取 /proc/pid/status，返回 false 表示当前系统没有快速路径
+func (r *procReader) loadStatus() bool {
+	if r.status == nil && r.statusErr == nil {
+		s, err := readProcStatus(r.p.Pid)
+		r.status, r.statusErr = &s, err
+	}
+	return !errors.Is(r.statusErr, errors.ErrUnsupported)
+}
+
+// Times 用户态和内核态 CPU 时间，单位秒
+func (r *procReader) Times() (user, system float64, err error) {
+	if r.loadStat() {
+		return r.stat.user, r.stat.system, r.statErr
+	}
+	times, err := r.p.Times()
+	if err != nil {
+		return 0, 0, err
+	}
+	return times.User, times.System, nil
+}
+
+// Memory RSS 和 VMS，单位字节
+func (r *procReader) Memory() (rss, vms uint64, err error) {
+	if r.loadStat() {
+		return r.stat.rss, r.stat.vms, r.statErr
+	}
+	mem, err := r.p.MemoryInfo()
+	if err != nil {
+		return 0, 0, err
+	}
+	return mem.RSS, mem.VMS, nil
+}
+
+// State 进程状态，取值与 gopsutil 的 Status 相同
+func (r *procReader) State() (string, error) {
+	if r.loadStat() {
+		return r.stat.state, r.statErr
+	}
+	states, err := r.p.Status()
+	if err != nil || len(states) == 0 {
+		return "", err
+	}
+	return states[0], nil
+}
+
+// NumThreads 线程数
+func (r *procReader) NumThreads() (int32, error) {
+	if r.loadStat() {
+		return r.stat.threads, r.statErr
+	}
+	return r.p.NumThreads()
+}
+
+// PageFaults 次缺页和主缺页次数
+func (r *procReader) PageFaults() (minor, major uint64, err error) {
+	if r.loadStat() {
+		return r.stat.minorFaults, r.stat.majorFaults, r.statErr
+	}
+	faults, err := r.p.PageFaults()
+	if err != nil {
+		return 0, 0, err
+	}
+	return faults.MinorFaults, faults.MajorFaults, nil
+}
+
+// CtxSwitches 主动和被动上下文切换次数
+func (r *procReader) CtxSwitches() (voluntary, involuntary int64, err error) {
+	if r.loadStatus() {
+		return r.status.voluntary, r.status.involuntary, r.statusErr
+	}
+	n, err := r.p.NumCtxSwitches()
+	if err != nil {
+		return 0, 0, err
+	}
+	return n.Voluntary, n.Involuntary, nil
+}
+
+// Swap 换出到 swap 的内存，单位字节，仅 Linux
+func (r *procReader) Swap() (uint64, error) {
+	r.loadStatus()
+	return r.status.swap, r.statusErr
+}
+
+// RSSPeak 进程启动以来 RSS 的最大值，单位字节，仅 Linux
+func (r *procReader) RSSPeak() (uint64, error) {
+	r.loadStatus()
+	return r.status.rssPeak, r.statusErr
+}
+
+// BlkioDelay 主线程等待块设备 IO 完成的累计时间，单位秒，需要内核开启延迟统计，仅 Linux
+func (r *procReader) BlkioDelay() (float64, error) {
+	if !r.loadStat() {
+		return 0, errors.ErrUnsupported
+	}
+	return r.stat.blkioDelay, r.statErr
+}
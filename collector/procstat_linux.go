@@ -0,0 +1,111 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/shirou/gopsutil/v4/process"
+
+	"process-exporter/internal/procfs"
+)
+
+// pageSize /proc/pid/stat 中 rss 的单位
+var pageSize = uint64(os.Getpagesize())
+
+// procStates /proc/pid/stat 中状态字符对应的名称，与 gopsutil 的 Status 一致
+var procStates = map[byte]string{
+	'R': process.Running,
+	'S': process.Sleep,
+	'D': process.Blocked,
+	'T': process.Stop,
+	't': process.Stop,
+	'Z': process.Zombie,
+	'I': process.Idle,
+	'W': process.Wait,
+}
+
+// readProcStat 读取并解析一次 /proc/pid/stat
+func readProcStat(pid int32) (procStat, error) {
+	content, err := os.ReadFile(procfs.PID(pid, "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+	// 进程名可能包含空格和括号，以最后一个 ')' 为界
+	end := bytes.LastIndexByte(content, ')')
+	if end < 0 {
+		return procStat{}, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	// ')' 之后从 state(3) 开始，minflt(10) majflt(12) utime(14) stime(15) num_threads(20) vsize(23) rss(24)
+	fields := bytes.Fields(content[end+1:])
+	if len(fields) < 22 {
+		return procStat{}, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	var values [22]uint64
+	for _, i := range []int{7, 9, 11, 12, 17, 20, 21} {
+		if values[i], err = strconv.ParseUint(string(fields[i]), 10, 64); err != nil {
+			return procStat{}, fmt.Errorf("malformed stat for pid %d: %w", pid, err)
+		}
+	}
+	// delayacct_blkio_ticks(42) 只有主线程的值，内核没有开启延迟统计时为 0
+	var blkio uint64
+	if len(fields) > 39 {
+		if blkio, err = strconv.ParseUint(string(fields[39]), 10, 64); err != nil {
+			return procStat{}, fmt.Errorf("malformed stat for pid %d: %w", pid, err)
+		}
+	}
+	state, ok := procStates[fields[0][0]]
+	if !ok {
+		state = process.UnknownState
+	}
+	return procStat{
+		user:        float64(values[11]) / userHZ,
+		system:      float64(values[12]) / userHZ,
+		rss:         values[21] * pageSize,
+		vms:         values[20],
+		state:       state,
+		threads:     int32(values[17]),
+		minorFaults: values[7],
+		majorFaults: values[9],
+		blkioDelay:  float64(blkio) / userHZ,
+	}, nil
+}
+
+// readProcStatus 读取并解析一次 /proc/pid/status 中的 VmSwap、VmHWM 和上下文切换次数
+func readProcStatus(pid int32) (procStatus, error) {
+	content, err := os.ReadFile(procfs.PID(pid, "status"))
+	if err != nil {
+		return procStatus{}, err
+	}
+
+	var s procStatus
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// 格式为 "VmSwap:      123 kB" 或 "voluntary_ctxt_switches:	42"
+		key, value, ok := bytes.Cut(scanner.Bytes(), []byte(":"))
+		if !ok {
+			continue
+		}
+		parts := bytes.Fields(value)
+		if len(parts) < 1 {
+			continue
+		}
+		n, err := strconv.ParseUint(string(parts[0]), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch string(key) {
+		case "VmSwap":
+			s.swap = n * 1024
+		case "VmHWM":
+			s.rssPeak = n * 1024
+		case "voluntary_ctxt_switches":
+			s.voluntary = int64(n)
+		case "nonvoluntary_ctxt_switches":
+			s.involuntary = int64(n)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readProcStat(pid int32) (procStat, error) {
+	return procStat{}, errors.ErrUnsupported
+}
+
+func readProcStatus(pid int32) (procStatus, error) {
+	return procStatus{}, errors.ErrUnsupported
+}
@@ -0,0 +1,115 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"process-exporter/fullscan"
+	"process-exporter/internal/logging"
+	"process-exporter/matcher"
+)
+
+// FullProfileCollectors full 模式支持的采集项，其余 -collector.<name> 开关在 full 模式下不起作用
+var FullProfileCollectors = []string{"cpu", "memory", "fds", "io"}
+
+// fullProfileCollectors 将 -collector.<name> 开关映射到 full 模式的采集项
+func fullProfileCollectors(enabled enabledCollectors) fullscan.Collectors {
+	return fullscan.Collectors{
+		CPU:       enabled.has("cpu"),
+		Memory:    enabled.has("memory"),
+		OpenFiles: enabled.has("fds"),
+		IO:        enabled.has("io"),
+	}
+}
+
+// FullProfileOptions full 模式特有的参数，字段与同名命令行参数对应
+type FullProfileOptions struct {
+	// UserCacheTTL UID 到用户名的缓存时间（-user-cache.ttl）
+	UserCacheTTL time.Duration
+	// CacheTTLs 进程名称、命令行等属性的缓存时间（-cache.static-ttl、-cache.slow-ttl），0 表示缓存到进程退出，需要显式填写，默认值为 fullscan.DefaultCacheTTLs
+	CacheTTLs fullscan.CacheTTLs
+	// SelfMetrics 同时输出 exporter 自身的 Go 运行时和进程指标（-self-metrics）
+	SelfMetrics bool
+	// Cmd cmd 标签的改写规则，可以为 nil
+	Cmd *fullscan.CmdRewriter
+}
+
+// NewFullProfileHandler 创建 full 模式（每次抓取扫描全部进程，导出 node_process_* 指标）的 /metrics 处理器
+// opts 配置了 Names、ConfigFile 或 ConfigPath 时使用与 cached 模式相同的匹配规则，reloader 重载后替换；否则采集所有进程
+// opts.Labels 和配置文件中的 labels 附加在所有指标上，与指标自身的标签同名时返回错误
+// opts 中 full 模式不支持的进程选择方式（PIDFiles、Services、TopN 等）不为空时返回错误，而不是静默忽略
+// 同时返回与 /metrics 内容相同的 Gatherer，用于主动推送
+func NewFullProfileHandler(reloader *Reloader, opts Options, fullOpts FullProfileOptions) (http.Handler, prometheus.Gatherer, error) {
+	hasTargets := len(opts.Names) > 0 || opts.ConfigFile != "" || opts.ConfigPath != ""
+	if err := checkFullProfile(opts, hasTargets); err != nil {
+		return nil, nil, err
+	}
+	labels, err := loadConstLabels(opts.ConfigFile, opts.Labels, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	full := fullscan.NewProcessCollector(nil, fullProfileCollectors(opts.enabledCollectors()), matcher.NewUserCache(fullOpts.UserCacheTTL))
+	full.SetCmdRewriter(fullOpts.Cmd)
+	full.SetCacheTTLs(fullOpts.CacheTTLs)
+	r := prometheus.NewRegistry()
+	reg := prometheus.WrapRegistererWith(labels, r)
+	if err := reg.Register(full); err != nil {
+		return nil, nil, err
+	}
+	if hasTargets {
+		targets, err := reloader.Load()
+		if err != nil {
+			return nil, nil, err
+		}
+		full.SetMatcher(newTargetSet(targets).matcher)
+		reloader.apply = func(targets []Target) {
+			full.SetMatcher(newTargetSet(targets).matcher)
+		}
+		reg.MustRegister(reloader)
+	}
+	if fullOpts.SelfMetrics {
+		reg.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{Namespace: "process_exporter"}),
+		)
+	}
+	return promhttp.HandlerFor(r, promhttp.HandlerOpts{
+		ErrorLog:      logging.ErrorLogger(),
+		ErrorHandling: promhttp.ContinueOnError,
+	}), r, nil
+}
+
+// checkFullProfile full 模式只按名称和配置文件选择进程，opts 中只有 cached 模式支持的选择方式不为空时返回错误
+// -exclude.names 附加在匹配规则上，没有 Names、ConfigFile 或 ConfigPath（采集所有进程）时无处生效
+func checkFullProfile(opts Options, hasTargets bool) error {
+	var unsupported []string
+	if len(opts.PIDFiles) > 0 {
+		unsupported = append(unsupported, "-pidfile")
+	}
+	if len(opts.Services) > 0 {
+		unsupported = append(unsupported, "-services")
+	}
+	if opts.TopN > 0 {
+		unsupported = append(unsupported, "-top.n")
+	}
+	if opts.ExcludeUIDs != "" {
+		unsupported = append(unsupported, "-exclude.uids")
+	}
+	if opts.ExcludeCgroups != "" {
+		unsupported = append(unsupported, "-exclude.cgroups")
+	}
+	if !hasTargets && slices.ContainsFunc(opts.ExcludeNames, func(name string) bool { return strings.TrimSpace(name) != "" }) {
+		unsupported = append(unsupported, "-exclude.names without -names, -config.file or -config.path")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("not supported with -profile=full: %s", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
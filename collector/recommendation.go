@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// cpuSample 某一次缓存刷新时分组的 CPU 使用量（核数）
+type cpuSample struct {
+	at    time.Time
+	cores float64
+}
+
+// cpuRecommender 保存每个分组在滑动窗口内的 CPU 使用采样
+// 以 p95 作为 CPU 配额建议值，用于容量规划
+type cpuRecommender struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	samples map[string][]cpuSample
+}
+
+func newCPURecommender(window time.Duration) *cpuRecommender {
+	return &cpuRecommender{
+		window:  window,
+		samples: make(map[string][]cpuSample),
+	}
+}
+
+// Observe 记录分组的一次 CPU 使用采样，并丢弃窗口外的旧采样
+func (r *cpuRecommender) Observe(group string, at time.Time, cores float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	samples := append(r.samples[group], cpuSample{at: at, cores: cores})
+	cutoff := at.Add(-r.window)
+	i := 0
+	for i < len(samples) && samples[i].at.Before(cutoff) {
+		i++
+	}
+	r.samples[group] = samples[i:]
+}
+
+// Prune 丢弃本次刷新中没有进程的分组（已停止或因配置重载不再存在）的采样，
+// 不再输出过期的建议值，分组名称由模板生成时也不会一直累积
+func (r *cpuRecommender) Prune(present map[string]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for group := range r.samples {
+		if present[group] == 0 {
+			delete(r.samples, group)
+		}
+	}
+}
+
+// Recommendation 返回分组在窗口内 CPU 使用量的 p95，没有采样时返回 false
+func (r *cpuRecommender) Recommendation(group string) (float64, bool) {
+	r.mu.Lock()
+	samples := r.samples[group]
+	values := make([]float64, len(samples))
+	for i, s := range samples {
+		values[i] = s.cores
+	}
+	r.mu.Unlock()
+
+	if len(values) == 0 {
+		return 0, false
+	}
+	sort.Float64s(values)
+
+	// nearest-rank 算法
+	rank := int(math.Ceil(0.95*float64(len(values)))) - 1
+	return values[rank], true
+}
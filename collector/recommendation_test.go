@@ -0,0 +1,25 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCPURecommenderPrune(t *testing.T) {
+	r := newCPURecommender(time.Hour)
+	now := time.Now()
+	r.Observe("nginx", now, 1)
+	r.Observe("mysql", now, 2)
+
+	r.Prune(map[string]int{"nginx": 3})
+
+	if got, ok := r.Recommendation("nginx"); !ok || got != 1 {
+		t.Errorf("Recommendation(nginx) = %v, %v, want 1, true", got, ok)
+	}
+	if _, ok := r.Recommendation("mysql"); ok {
+		t.Errorf("Recommendation(mysql) ok after its processes are gone")
+	}
+	if len(r.samples) != 1 {
+		t.Errorf("samples kept for %d groups, want 1", len(r.samples))
+	}
+}
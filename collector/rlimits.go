@@ -0,0 +1,22 @@
+package collector
+
+import (
+	"math"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// rlimitResources 输出的资源限制，标签值沿用 ulimit 中的名称
+var rlimitResources = map[int32]string{
+	process.RLIMIT_NOFILE:  "nofile",
+	process.RLIMIT_NPROC:   "nproc",
+	process.RLIMIT_MEMLOCK: "memlock",
+}
+
+// rlimitValue gopsutil 用 MaxUint64 表示 unlimited，输出为 +Inf，使用率计算结果为 0
+func rlimitValue(v uint64) float64 {
+	if v == math.MaxUint64 {
+		return math.Inf(1)
+	}
+	return float64(v)
+}
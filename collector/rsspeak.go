@@ -0,0 +1,42 @@
+package collector
+
+import "sync"
+
+// rssPeakTracker 按分组记录 exporter 启动以来观测到的分组 RSS 总和的最大值
+// 进程的 VmHWM 只覆盖单个进程的一次运行，worker 频繁重启或多个进程同时膨胀时需要分组级别的峰值
+type rssPeakTracker struct {
+	mu    sync.Mutex
+	peaks map[string]uint64
+}
+
+func newRSSPeakTracker() *rssPeakTracker {
+	return &rssPeakTracker{peaks: make(map[string]uint64)}
+}
+
+// Observe 记录分组当前的 RSS 总和
+func (t *rssPeakTracker) Observe(group string, rss uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rss > t.peaks[group] {
+		t.peaks[group] = rss
+	}
+}
+
+// Peak 返回分组观测到的峰值，分组还没有出现过进程时返回 false
+func (t *rssPeakTracker) Peak(group string) (uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	peak, ok := t.peaks[group]
+	return peak, ok
+}
+
+// recordRSSPeaks 汇总本次刷新各分组的 RSS，更新分组峰值
+func (c *ProcessCollector) recordRSSPeaks(newCache map[int32]CachedProcess) {
+	sums := make(map[string]uint64)
+	for _, cached := range newCache {
+		sums[cached.Group] += cached.RSS
+	}
+	for group, rss := range sums {
+		c.rssPeaks.Observe(group, rss)
+	}
+}
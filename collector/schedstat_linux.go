@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+
+	"process-exporter/internal/procfs"
+)
+
+// schedStat 进程所有线程 /proc/pid/task/tid/schedstat 之和
+type schedStat struct {
+	// runDelay 已就绪但在运行队列中等待 CPU 的时间，单位秒
+	runDelay float64
+	// timeslices 在 CPU 上运行的次数
+	timeslices uint64
+}
+
+// readSchedStat 遍历 /proc/pid/task 累加每个线程的 schedstat
+// /proc/pid/schedstat 只包含主线程，多线程进程需要逐个线程读取；线程可能在遍历过程中退出，读取失败的线程直接跳过
+func readSchedStat(pid int32) (schedStat, error) {
+	dir := procfs.PID(pid, "task")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return schedStat{}, err
+	}
+
+	var total schedStat
+	for _, entry := range entries {
+		content, err := os.ReadFile(dir + "/" + entry.Name() + "/schedstat")
+		if err != nil {
+			continue
+		}
+		// 三个字段依次为 CPU 时间（纳秒）、运行队列等待时间（纳秒）、运行次数
+		fields := bytes.Fields(content)
+		if len(fields) < 3 {
+			continue
+		}
+		delay, err1 := strconv.ParseUint(string(fields[1]), 10, 64)
+		slices, err2 := strconv.ParseUint(string(fields[2]), 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		total.runDelay += float64(delay) / 1e9
+		total.timeslices += slices
+	}
+	return total, nil
+}
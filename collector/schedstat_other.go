@@ -0,0 +1,15 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+// schedStat 进程所有线程的运行队列等待时间和运行次数，仅 Linux
+type schedStat struct {
+	runDelay   float64
+	timeslices uint64
+}
+
+func readSchedStat(pid int32) (schedStat, error) {
+	return schedStat{}, errors.ErrUnsupported
+}
@@ -0,0 +1,184 @@
+package collector
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+
+	"process-exporter/internal/encoder"
+)
+
+// scrapeTimeoutHeader Prometheus 抓取时携带的超时时间（秒）
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// deadlineCollector 将一次抓取的截止时间和需要的采集项传给 ProcessCollector
+// 每个请求创建一个，避免并发抓取之间互相影响
+type deadlineCollector struct {
+	collector  *ProcessCollector
+	deadline   time.Time
+	collectors enabledCollectors
+	// pass 不为 nil 时与其他端点共用采集结果，endpoint 为本端点的路径
+	pass     *sharedPass
+	endpoint string
+}
+
+func (d deadlineCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.collector.Describe(ch)
+}
+
+func (d deadlineCollector) Collect(ch chan<- prometheus.Metric) {
+	if d.pass != nil {
+		d.pass.collect(ch, d.endpoint, d.deadline, func(ch chan<- prometheus.Metric) {
+			d.collector.collect(ch, d.deadline, d.collectors)
+		})
+		return
+	}
+	d.collector.collect(ch, d.deadline, d.collectors)
+}
+
+// scrapeHandler 根据 Prometheus 的抓取超时（减去 offset）限制采集时间，超时后返回已采集到的部分数据
+// base 中注册的是其余不受超时限制的采集器
+type scrapeHandler struct {
+	base      prometheus.Gatherer
+	collector *ProcessCollector
+	labels    prometheus.Labels // 附加在 ProcessCollector 指标上的常量标签
+	offset    time.Duration
+	opts      promhttp.HandlerOpts
+	// snapshots 不为 nil 时保存完整抓取的快照，只请求部分指标的抓取不保存
+	snapshots *snapshotStore
+	// aggregate 去掉 pid 等进程级标签，按分组聚合后输出
+	aggregate bool
+	// carry 记录聚合 counter 中已退出进程的值，每个端点各自记录
+	carry *counterCarry
+	// ids 不为 nil 时用稳定的进程标识代替 pid 标签（-pid-label）
+	ids *processIDs
+	// pass 不为 nil 时 /metrics 和 /metrics/detailed 的完整抓取共用一次采集，endpoint 为本端点的路径
+	pass     *sharedPass
+	endpoint string
+}
+
+func (h *scrapeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	deadline := scrapeDeadline(r, h.offset)
+
+	// collect[]=<采集项> 只执行指定的采集项，name[]=<指标名> 只输出指定的指标
+	// 两者都会跳过不需要的采集项，不再读取对应的 /proc 文件
+	query := r.URL.Query()
+	enabled, err := requestedCollectors(h.collector.collectors, query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var metrics map[string]struct{}
+	if names := query["name[]"]; len(names) > 0 {
+		metrics = make(map[string]struct{}, len(names))
+		for _, name := range names {
+			metrics[name] = struct{}{}
+		}
+		enabled = enabled.forMetrics(metrics)
+	}
+
+	// 只请求部分指标的抓取单独采集，不与其他端点共用
+	gatherer := h.gatherer(deadline, enabled, metrics == nil && len(query["collect[]"]) == 0)
+	if metrics != nil {
+		gatherer = filteredGatherer{gatherer: gatherer, names: metrics}
+	} else if h.snapshots != nil && len(query["collect[]"]) == 0 {
+		gatherer = recordingGatherer{gatherer: gatherer, store: h.snapshots}
+	}
+
+	// ?format=influx|jsonl 使用其他编码器输出，供非 Prometheus 的采集管道直接拉取
+	if format := query.Get("format"); format != "" && format != "prometheus" {
+		serveEncoded(w, gatherer, format)
+		return
+	}
+	promhttp.HandlerFor(gatherer, h.opts).ServeHTTP(w, r)
+}
+
+// gatherer 返回一次采集使用的 Gatherer，合并 base 和按 deadline、enabled 采集的 ProcessCollector
+// shared 为 true 时使用 h.pass 中与其他端点共用的采集结果
+func (h *scrapeHandler) gatherer(deadline time.Time, enabled enabledCollectors, shared bool) prometheus.Gatherer {
+	dc := deadlineCollector{collector: h.collector, deadline: deadline, collectors: enabled}
+	if shared {
+		dc.pass, dc.endpoint = h.pass, h.endpoint
+	}
+	reg := prometheus.NewRegistry()
+	prometheus.WrapRegistererWith(h.labels, reg).MustRegister(dc)
+	var gatherer prometheus.Gatherer = prometheus.Gatherers{h.base, reg}
+	if len(h.collector.envLabels) > 0 {
+		gatherer = envLabelGatherer{gatherer: gatherer, collector: h.collector}
+	}
+	if h.aggregate {
+		gatherer = aggregatingGatherer{gatherer: gatherer, carry: h.carry}
+		return gatherer
+	}
+	if h.collector.maxProcsPerGroup > 0 {
+		gatherer = truncatingGatherer{gatherer: gatherer, collector: h.collector, carry: h.carry}
+	}
+	if h.ids != nil {
+		gatherer = pidLabelGatherer{gatherer: gatherer, ids: h.ids}
+	}
+	return gatherer
+}
+
+// scrapeDeadline 根据 Prometheus 的抓取超时（减去 offset）计算采集截止时间，没有超时请求头时返回零值
+func scrapeDeadline(r *http.Request, offset time.Duration) time.Time {
+	v := r.Header.Get(scrapeTimeoutHeader)
+	if v == "" {
+		return time.Time{}
+	}
+	seconds, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		slog.Warn("Invalid scrape timeout header", "header", scrapeTimeoutHeader, "value", v, "err", err)
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(seconds*float64(time.Second)) - offset)
+}
+
+// requestedCollectors 处理抓取请求中的 collect[] 参数，没有该参数时返回全部开启的采集项
+func requestedCollectors(enabled enabledCollectors, query url.Values) (enabledCollectors, error) {
+	if names := query["collect[]"]; len(names) > 0 {
+		return enabled.only(names)
+	}
+	return enabled, nil
+}
+
+// filteredGatherer 只返回指定名称的指标
+type filteredGatherer struct {
+	gatherer prometheus.Gatherer
+	names    map[string]struct{}
+}
+
+func (f filteredGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := f.gatherer.Gather()
+	filtered := families[:0]
+	for _, mf := range families {
+		if _, ok := f.names[mf.GetName()]; ok {
+			filtered = append(filtered, mf)
+		}
+	}
+	return filtered, err
+}
+
+// serveEncoded 采集一次并按指定格式编码输出
+func serveEncoded(w http.ResponseWriter, gatherer prometheus.Gatherer, format string) {
+	enc, err := encoder.New(format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		// 与 promhttp.ContinueOnError 一致，记录错误后输出已采集到的部分
+		slog.Error("Error gathering metrics", "err", err)
+	}
+	w.Header().Set("Content-Type", enc.ContentType())
+	if err := enc.Encode(w, families, time.Now()); err != nil {
+		slog.Error("Error encoding metrics", "format", format, "err", err)
+	}
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// collectStatus 一次采集中每个采集项的执行结果，用于区分 exporter 采集失败和目标进程异常
+// 多个 worker 并发记录
+type collectStatus struct {
+	mu        sync.Mutex
+	attempted map[string]int
+	failed    map[string]int
+}
+
+func newCollectStatus() *collectStatus {
+	return &collectStatus{attempted: make(map[string]int), failed: make(map[string]int)}
+}
+
+// fail 记录采集项对一个进程读取失败，进程在采集过程中退出不算失败
+func (s *collectStatus) fail(collector string, err error) {
+	if vanished(err) {
+		return
+	}
+	s.mu.Lock()
+	s.failed[collector]++
+	s.mu.Unlock()
+}
+
+// done 记录一个进程已经尝试过所有开启的采集项
+func (s *collectStatus) done(enabled enabledCollectors) {
+	s.mu.Lock()
+	for name, on := range enabled {
+		if on {
+			s.attempted[name]++
+		}
+	}
+	s.mu.Unlock()
+}
+
+// successRatio 返回采集项成功的比例，没有采集任何进程时为 1
+func (s *collectStatus) successRatio(collector string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.attempted[collector]
+	if n == 0 {
+		return 1
+	}
+	return float64(n-s.failed[collector]) / float64(n)
+}
+
+// complete 所有采集项对所有进程都成功
+func (s *collectStatus) complete() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range s.failed {
+		if n > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// vanished 进程已经退出，与 errorReason 的 vanished 分类一致
+func vanished(err error) bool {
+	return errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ESRCH) || errors.Is(err, process.ErrorProcessNotRunning)
+}
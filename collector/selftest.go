@@ -0,0 +1,241 @@
+package collector
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// selfTestChecks 每个采集项在自测时执行的读取，返回读到的值的描述
+var selfTestChecks = map[string]func(p *process.Process) (string, error){
+	"cpu": func(p *process.Process) (string, error) {
+		user, system, err := newProcReader(p).Times()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("user=%.2fs system=%.2fs", user, system), nil
+	},
+	"memory": func(p *process.Process) (string, error) {
+		rss, vms, err := newProcReader(p).Memory()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("rss=%d vms=%d", rss, vms), nil
+	},
+	"state": func(p *process.Process) (string, error) {
+		state, err := newProcReader(p).State()
+		return fmt.Sprintf("state=%s", state), err
+	},
+	"swap": func(p *process.Process) (string, error) {
+		n, err := newProcReader(p).Swap()
+		return fmt.Sprintf("swap=%d", n), err
+	},
+	"rsspeak": func(p *process.Process) (string, error) {
+		peak, rss, err := readRSSPeakBytes(p.Pid)
+		return fmt.Sprintf("peak=%d rss=%d", peak, rss), err
+	},
+	"connections": func(p *process.Process) (string, error) {
+		conns, err := readConnections(p.Pid)
+		counts := countConnections(conns)
+		return fmt.Sprintf("tcp=%d udp=%d", counts["tcp"]+counts["tcp6"], counts["udp"]+counts["udp6"]), err
+	},
+	"listen": func(p *process.Process) (string, error) {
+		conns, err := readConnections(p.Pid)
+		return fmt.Sprintf("ports=%d", len(listeningPorts(conns))), err
+	},
+	"idleconns": func(p *process.Process) (string, error) {
+		sockets, err := readTCPSockets()
+		if err != nil {
+			return "", err
+		}
+		inodes, err := readSocketInodes(p.Pid)
+		counts := countIdleConnections(inodes, sockets, []time.Duration{time.Minute})
+		return fmt.Sprintf("sockets=%d idle>1m=%d", len(inodes), counts[0]), err
+	},
+	"pathwrites": func(p *process.Process) (string, error) {
+		fds, err := readWriteFDs(p.Pid)
+		return fmt.Sprintf("write_fds=%d", len(fds)), err
+	},
+	"ioprio": func(p *process.Process) (string, error) {
+		class, prio, err := readIOPriority(p.Pid)
+		return fmt.Sprintf("class=%s prio=%d", class, prio), err
+	},
+	"ctxswitches": func(p *process.Process) (string, error) {
+		voluntary, involuntary, err := newProcReader(p).CtxSwitches()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("voluntary=%d involuntary=%d", voluntary, involuntary), nil
+	},
+	"threadstats": func(p *process.Process) (string, error) {
+		threads, err := readThreads(p.Pid)
+		return fmt.Sprintf("threads=%d", len(threads)), err
+	},
+	"threads": func(p *process.Process) (string, error) {
+		n, err := newProcReader(p).NumThreads()
+		return fmt.Sprintf("threads=%d", n), err
+	},
+	"fds": func(p *process.Process) (string, error) {
+		n, err := p.NumFDs()
+		return fmt.Sprintf("fds=%d", n), err
+	},
+	"starttime": func(p *process.Process) (string, error) {
+		t, err := p.CreateTime()
+		return fmt.Sprintf("start=%dms", t), err
+	},
+	"pagefaults": func(p *process.Process) (string, error) {
+		minor, major, err := newProcReader(p).PageFaults()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("major=%d minor=%d", major, minor), nil
+	},
+	"io": func(p *process.Process) (string, error) {
+		io, err := p.IOCounters()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("read=%d write=%d", io.ReadBytes, io.WriteBytes), nil
+	},
+	"zombies": func(p *process.Process) (string, error) {
+		n, err := countZombieChildren(p.Pid)
+		return fmt.Sprintf("zombies=%d", n), err
+	},
+	"rlimits": func(p *process.Process) (string, error) {
+		limits, err := p.Rlimit()
+		if err != nil {
+			return "", err
+		}
+		for _, l := range limits {
+			if l.Resource == process.RLIMIT_NOFILE {
+				return fmt.Sprintf("nofile=%v/%v", rlimitValue(l.Soft), rlimitValue(l.Hard)), nil
+			}
+		}
+		return "", fmt.Errorf("no RLIMIT_NOFILE")
+	},
+	"cpufreq": func(p *process.Process) (string, error) {
+		cpu, err := readLastCPU(p.Pid)
+		if err != nil {
+			return "", err
+		}
+		freq, err := readCPUFrequency(cpu)
+		return fmt.Sprintf("cpu=%d freq=%.0fHz", cpu, freq), err
+	},
+	"windows": func(p *process.Process) (string, error) {
+		wp, err := readWindowsProcess(p.Pid)
+		return fmt.Sprintf("handles=%d private=%d", wp.handles, wp.privateBytes), err
+	},
+	"mmaps": func(p *process.Process) (string, error) {
+		files, err := readMappedFiles(p.Pid)
+		return fmt.Sprintf("files=%d", len(files)), err
+	},
+	"fdtypes": func(p *process.Process) (string, error) {
+		counts, err := readFDTypes(p.Pid)
+		return fmt.Sprintf("socket=%d pipe=%d file=%d", counts["socket"], counts["pipe"], counts["file"]), err
+	},
+	"cgroup": func(p *process.Process) (string, error) {
+		cg, err := readCgroupStats(p.Pid)
+		return fmt.Sprintf("cgroup=%s memory=%.0f/%.0f throttled=%d/%d", cg.path, cg.memoryCurrent, cg.memoryMax, cg.throttledPeriods, cg.periods), err
+	},
+	"affinity": func(p *process.Process) (string, error) {
+		n, err := readAffinityCPUs(p.Pid)
+		return fmt.Sprintf("cpus=%d", n), err
+	},
+	"percpu": func(p *process.Process) (string, error) {
+		threads, err := readThreads(p.Pid)
+		cpus := make(map[int]bool)
+		for _, t := range threads {
+			cpus[t.cpu] = true
+		}
+		return fmt.Sprintf("threads=%d cpus=%d", len(threads), len(cpus)), err
+	},
+	"schedstat": func(p *process.Process) (string, error) {
+		s, err := readSchedStat(p.Pid)
+		return fmt.Sprintf("run_delay=%.3fs timeslices=%d", s.runDelay, s.timeslices), err
+	},
+	"blkio": func(p *process.Process) (string, error) {
+		d, err := newProcReader(p).BlkioDelay()
+		return fmt.Sprintf("blkio_delay=%.2fs", d), err
+	},
+	"netbytes": func(p *process.Process) (string, error) {
+		sockets, err := readTCPSockets()
+		if err != nil {
+			return "", err
+		}
+		inodes, err := readSocketInodes(p.Pid)
+		total := newNetBytesTracker().Observe(procKey{pid: p.Pid}, inodes, sockets)
+		return fmt.Sprintf("received=%d sent=%d", total.received, total.sent), err
+	},
+	"smaps": func(p *process.Process) (string, error) {
+		fields, err := readSmapsRollup(p.Pid)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("pss=%d uss=%d", fields["Pss"], fields["Private_Clean"]+fields["Private_Dirty"]), nil
+	},
+}
+
+// RunSelfTest 对 exporter 自身的进程执行一次所有开启的采集项，输出每项是否能读取以及失败原因
+// 用于在新平台或新的权限配置下做上线前检查，有失败项时返回非零退出码
+func RunSelfTest(w io.Writer, collectors map[string]bool, workingSet bool) int {
+	enabled := enabledCollectors(collectors)
+	pid := int32(os.Getpid())
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		fmt.Fprintf(w, "FAIL: cannot open own process %d: %v\n", pid, err)
+		return 1
+	}
+
+	// 刷新缓存时读取的信息，所有采集都依赖它们
+	checks := map[string]func(p *process.Process) (string, error){
+		"name": func(p *process.Process) (string, error) {
+			return p.Name()
+		},
+		"cmdline": func(p *process.Process) (string, error) {
+			return p.Cmdline()
+		},
+	}
+	for name, check := range selfTestChecks {
+		if enabled.has(name) {
+			checks[name] = check
+		}
+	}
+	if workingSet {
+		checks["workingset"] = func(p *process.Process) (string, error) {
+			b, err := readReferencedBytes(p.Pid)
+			return fmt.Sprintf("referenced=%d", b), err
+		}
+	}
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Self-test against pid %d\n", pid)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	failed := 0
+	for _, name := range names {
+		value, err := checks[name](p)
+		if err != nil {
+			failed++
+			fmt.Fprintf(tw, "FAIL\t%s\t%s: %v\n", name, errorReason(err), err)
+			continue
+		}
+		fmt.Fprintf(tw, "OK\t%s\t%s\n", name, value)
+	}
+	tw.Flush()
+
+	if failed > 0 {
+		fmt.Fprintf(w, "%d of %d checks failed\n", failed, len(names))
+		return 1
+	}
+	fmt.Fprintf(w, "All %d checks passed\n", len(names))
+	return 0
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package collector
+
+import "errors"
+
+func queryServicePIDs(names []string) (map[int32]string, error) {
+	return nil, errors.ErrUnsupported
+}
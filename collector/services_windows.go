@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// queryServicePIDs 通过服务控制管理器（SCM）查询服务当前的进程 ID，返回 PID 到服务名称的映射
+// 只需要 SC_MANAGER_CONNECT 和 SERVICE_QUERY_STATUS 权限；未运行的服务 PID 为 0，不出现在结果中
+// 多个服务共享同一个 svchost 进程时，该进程属于 names 中排在前面的服务
+func queryServicePIDs(names []string) (map[int32]string, error) {
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseServiceHandle(scm)
+
+	pids := make(map[int32]string, len(names))
+	var firstErr error
+	for _, name := range names {
+		pid, err := queryServicePID(scm, name)
+		if err != nil {
+			// 单个服务不存在时不影响其他服务
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if _, taken := pids[pid]; pid != 0 && !taken {
+			pids[pid] = name
+		}
+	}
+	return pids, firstErr
+}
+
+func queryServicePID(scm windows.Handle, name string) (int32, error) {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	svc, err := windows.OpenService(scm, namePtr, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseServiceHandle(svc)
+
+	var status windows.SERVICE_STATUS_PROCESS
+	var needed uint32
+	err = windows.QueryServiceStatusEx(svc, windows.SC_STATUS_PROCESS_INFO,
+		(*byte)(unsafe.Pointer(&status)), uint32(unsafe.Sizeof(status)), &needed)
+	if err != nil {
+		return 0, err
+	}
+	return int32(status.ProcessId), nil
+}
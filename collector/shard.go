@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// shard 多个 exporter 实例按 PID 哈希取模分担同一台主机上的进程
+// count <= 1 时不分片
+type shard struct {
+	index int
+	count int
+}
+
+// owns 判断 PID 是否属于当前分片
+func (s shard) owns(pid int32) bool {
+	if s.count <= 1 {
+		return true
+	}
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(pid))
+	h := fnv.New32a()
+	h.Write(b[:])
+	return int(h.Sum32()%uint32(s.count)) == s.index
+}
+
+// labels 附加在所有指标上的分片标签，不分片时返回 nil
+func (s shard) labels() prometheus.Labels {
+	if s.count <= 1 {
+		return nil
+	}
+	return prometheus.Labels{"shard": strconv.Itoa(s.index)}
+}
+
+// processes 只为属于当前分片的 PID 创建进程对象，避免对其余进程做无用的读取
+func (s shard) processes() ([]*process.Process, error) {
+	pids, err := process.Pids()
+	if err != nil {
+		return nil, err
+	}
+
+	procs := make([]*process.Process, 0, len(pids)/max(s.count, 1))
+	for _, pid := range pids {
+		if !s.owns(pid) {
+			continue
+		}
+		p, err := process.NewProcess(pid)
+		if err != nil {
+			// 进程已经退出
+			continue
+		}
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
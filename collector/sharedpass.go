@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sharedPass /metrics 和 /metrics/detailed 共用的采集结果
+// 开启 -metrics.aggregate 时两个端点通常都会被抓取，各自采集一次不仅读两遍 /proc，聚合值和明细也来自不同时刻，对不上
+// 端点抓取时，如果最近一次采集晚于该端点上一次抓取（即由另一个端点在这一轮中完成），直接复用，否则重新采集
+type sharedPass struct {
+	// busy 容量为 1 的信号量，等待时可以按抓取的截止时间放弃
+	busy chan struct{}
+	// last 最近一次采集的结果，at 为开始采集的时间
+	last atomic.Pointer[sampleSet]
+	// served 每个端点上一次抓取的时间，由 busy 保护
+	served map[string]time.Time
+}
+
+func newSharedPass() *sharedPass {
+	return &sharedPass{busy: make(chan struct{}, 1), served: make(map[string]time.Time)}
+}
+
+// collect 为 endpoint 的一次抓取输出指标，需要重新采集时调用 collectFn
+// 等到 deadline 仍拿不到信号量（另一个端点的采集还没结束）时输出上一次的结果
+func (s *sharedPass) collect(ch chan<- prometheus.Metric, endpoint string, deadline time.Time, collectFn func(chan<- prometheus.Metric)) {
+	if !acquire(s.busy, deadline) {
+		if set := s.last.Load(); set != nil {
+			for _, m := range set.metrics {
+				ch <- m
+			}
+		}
+		return
+	}
+	defer func() { <-s.busy }()
+
+	now := time.Now()
+	set := s.last.Load()
+	if set == nil || !set.at.After(s.served[endpoint]) {
+		metrics := make(chan prometheus.Metric, 256)
+		go func() {
+			collectFn(metrics)
+			close(metrics)
+		}()
+		set = &sampleSet{at: now}
+		for m := range metrics {
+			set.metrics = append(set.metrics, m)
+		}
+		s.last.Store(set)
+	}
+	s.served[endpoint] = now
+	for _, m := range set.metrics {
+		ch <- m
+	}
+}
+
+// acquire 获取容量为 1 的信号量 sem，deadline 为零时一直等待，超过 deadline 返回 false
+func acquire(sem chan struct{}, deadline time.Time) bool {
+	if deadline.IsZero() {
+		sem <- struct{}{}
+		return true
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
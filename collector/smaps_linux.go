@@ -0,0 +1,35 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strconv"
+
+	"process-exporter/internal/procfs"
+)
+
+// readSmapsRollup 读取 /proc/pid/smaps_rollup（Linux 4.14+），返回各字段的字节数
+// 例如 Rss、Pss、Private_Clean、Private_Dirty、Referenced
+func readSmapsRollup(pid int32) (map[string]uint64, error) {
+	content, err := os.ReadFile(procfs.PID(pid, "smaps_rollup"))
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// 每行格式为 "Pss:                 123 kB"，第一行是地址范围，跳过
+		parts := bytes.Fields(scanner.Bytes())
+		if len(parts) != 3 || !bytes.HasSuffix(parts[0], []byte(":")) {
+			continue
+		}
+		kb, err := strconv.ParseUint(string(parts[1]), 10, 64)
+		if err != nil {
+			continue
+		}
+		fields[string(bytes.TrimSuffix(parts[0], []byte(":")))] = kb * 1024
+	}
+	return fields, nil
+}
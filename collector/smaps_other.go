@@ -0,0 +1,9 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readSmapsRollup(pid int32) (map[string]uint64, error) {
+	return nil, errors.ErrUnsupported
+}
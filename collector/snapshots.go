@@ -0,0 +1,240 @@
+package collector
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// snapshotProc 一次抓取中单个进程的状态
+type snapshotProc struct {
+	PID       int32   `json:"pid"`
+	Name      string  `json:"process_name"`
+	StartTime float64 `json:"start_time_seconds,omitempty"`
+	CPU       float64 `json:"-"`
+	RSS       float64 `json:"-"`
+}
+
+// snapshot 一次抓取的结果，只保留排查需要的字段
+type snapshot struct {
+	at    time.Time
+	procs map[int32]snapshotProc
+}
+
+// snapshotStore 在内存中保留最近 K 次抓取的快照，事故现场不用查询 Prometheus 就能对比两个时间点
+type snapshotStore struct {
+	mu        sync.Mutex
+	keep      int
+	snapshots []snapshot // 按时间排序，最旧的在前
+}
+
+func newSnapshotStore(keep int) *snapshotStore {
+	return &snapshotStore{keep: keep}
+}
+
+// record 从抓取结果中提取每个进程的 CPU 时间、RSS 和启动时间
+func (s *snapshotStore) record(at time.Time, families []*dto.MetricFamily) {
+	procs := make(map[int32]snapshotProc)
+	for _, mf := range families {
+		var apply func(p *snapshotProc, v float64)
+		switch mf.GetName() {
+		case "process_cpu_user_seconds_total", "process_cpu_system_seconds_total":
+			apply = func(p *snapshotProc, v float64) { p.CPU += v }
+		case "process_memory_rss_bytes":
+			apply = func(p *snapshotProc, v float64) { p.RSS = v }
+		case "process_start_time_seconds":
+			apply = func(p *snapshotProc, v float64) { p.StartTime = v }
+		case "process_up":
+			apply = func(p *snapshotProc, v float64) {}
+		default:
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			var pid int32
+			var name string
+			for _, l := range m.GetLabel() {
+				switch l.GetName() {
+				case "pid":
+					if n, err := strconv.ParseInt(l.GetValue(), 10, 32); err == nil {
+						pid = int32(n)
+					}
+				case "process_name":
+					name = l.GetValue()
+				}
+			}
+			if pid == 0 {
+				continue
+			}
+			p := procs[pid]
+			p.PID, p.Name = pid, name
+			apply(&p, m.GetCounter().GetValue()+m.GetGauge().GetValue())
+			procs[pid] = p
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots = append(s.snapshots, snapshot{at: at, procs: procs})
+	if len(s.snapshots) > s.keep {
+		s.snapshots = s.snapshots[len(s.snapshots)-s.keep:]
+	}
+}
+
+// find 返回不晚于 t 的最后一个快照，t 早于所有快照时返回最旧的
+func (s *snapshotStore) find(t time.Time) snapshot {
+	i := sort.Search(len(s.snapshots), func(i int) bool { return s.snapshots[i].at.After(t) })
+	if i == 0 {
+		return s.snapshots[0]
+	}
+	return s.snapshots[i-1]
+}
+
+// snapshotMover 两个快照之间变化最大的进程
+type snapshotMover struct {
+	snapshotProc
+	Delta float64 `json:"delta"`
+}
+
+// snapshotDiff /debug/snapshots/diff 的返回结果
+type snapshotDiff struct {
+	From        time.Time       `json:"from"`
+	To          time.Time       `json:"to"`
+	Appeared    []snapshotProc  `json:"appeared"`
+	Disappeared []snapshotProc  `json:"disappeared"`
+	CPUMovers   []snapshotMover `json:"cpu_movers"`
+	RSSMovers   []snapshotMover `json:"rss_movers"`
+}
+
+// diff 对比两个快照，PID 相同但启动时间不同视为不同的进程
+func diff(from, to snapshot, top int) snapshotDiff {
+	d := snapshotDiff{
+		From:        from.at,
+		To:          to.at,
+		Appeared:    []snapshotProc{},
+		Disappeared: []snapshotProc{},
+	}
+	var cpu, rss []snapshotMover
+	for pid, p := range to.procs {
+		old, ok := from.procs[pid]
+		if !ok || old.StartTime != p.StartTime {
+			d.Appeared = append(d.Appeared, p)
+			continue
+		}
+		cpu = append(cpu, snapshotMover{snapshotProc: p, Delta: p.CPU - old.CPU})
+		rss = append(rss, snapshotMover{snapshotProc: p, Delta: p.RSS - old.RSS})
+	}
+	for pid, p := range from.procs {
+		if cur, ok := to.procs[pid]; !ok || cur.StartTime != p.StartTime {
+			d.Disappeared = append(d.Disappeared, p)
+		}
+	}
+	sort.Slice(d.Appeared, func(i, j int) bool { return d.Appeared[i].PID < d.Appeared[j].PID })
+	sort.Slice(d.Disappeared, func(i, j int) bool { return d.Disappeared[i].PID < d.Disappeared[j].PID })
+	d.CPUMovers = topMovers(cpu, top)
+	d.RSSMovers = topMovers(rss, top)
+	return d
+}
+
+// topMovers 按变化量的绝对值排序，返回前 top 个
+func topMovers(movers []snapshotMover, top int) []snapshotMover {
+	sort.Slice(movers, func(i, j int) bool {
+		if a, b := math.Abs(movers[i].Delta), math.Abs(movers[j].Delta); a != b {
+			return a > b
+		}
+		return movers[i].PID < movers[j].PID
+	})
+	if len(movers) > top {
+		movers = movers[:top]
+	}
+	return append([]snapshotMover{}, movers...)
+}
+
+// ServeHTTP 处理 /debug/snapshots/diff?from=<unix>&to=<unix>&top=N
+// from/to 缺省时分别为最旧和最新的快照
+func (s *snapshotStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	top := 10
+	if v := query.Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid top", http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+	parseTime := func(name string, def time.Time) (time.Time, bool) {
+		v := query.Get(name)
+		if v == "" {
+			return def, true
+		}
+		seconds, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			http.Error(w, "invalid "+name, http.StatusBadRequest)
+			return time.Time{}, false
+		}
+		return time.Unix(0, int64(seconds*float64(time.Second))), true
+	}
+
+	s.mu.Lock()
+	if len(s.snapshots) == 0 {
+		s.mu.Unlock()
+		http.Error(w, "no snapshots recorded yet", http.StatusNotFound)
+		return
+	}
+	from, ok := parseTime("from", s.snapshots[0].at)
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	to, ok := parseTime("to", s.snapshots[len(s.snapshots)-1].at)
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	d := diff(s.find(from), s.find(to), top)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(d)
+}
+
+// serveList 处理 /debug/snapshots，列出已保存快照的时间和进程数
+func (s *snapshotStore) serveList(w http.ResponseWriter, r *http.Request) {
+	type entry struct {
+		Time      time.Time `json:"time"`
+		Unix      float64   `json:"unix"`
+		Processes int       `json:"processes"`
+	}
+	s.mu.Lock()
+	list := make([]entry, len(s.snapshots))
+	for i, snap := range s.snapshots {
+		list[i] = entry{Time: snap.at, Unix: float64(snap.at.UnixNano()) / 1e9, Processes: len(snap.procs)}
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(list)
+}
+
+// recordingGatherer 在返回抓取结果的同时保存快照
+type recordingGatherer struct {
+	gatherer prometheus.Gatherer
+	store    *snapshotStore
+}
+
+func (g recordingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	g.store.record(time.Now(), families)
+	return families, err
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strconv"
+
+	"process-exporter/internal/procfs"
+)
+
+// readSwapBytes 读取 /proc/pid/status 中的 VmSwap 字段，即进程被换出到 swap 的匿名内存
+// 内核线程没有 VmSwap 字段，返回 0
+func readSwapBytes(pid int32) (uint64, error) {
+	fields, err := readStatusBytes(pid, "VmSwap")
+	return fields["VmSwap"], err
+}
+
+// readRSSPeakBytes 读取 /proc/pid/status 中的 VmHWM 和 VmRSS 字段
+// VmHWM 是内核记录的进程启动以来 RSS 的最大值，两次抓取之间的瞬时峰值也不会遗漏
+func readRSSPeakBytes(pid int32) (peak, rss uint64, err error) {
+	fields, err := readStatusBytes(pid, "VmHWM", "VmRSS")
+	return fields["VmHWM"], fields["VmRSS"], err
+}
+
+// readStatusBytes 读取 /proc/pid/status 中以 kB 为单位的字段，返回字节数
+// 不存在的字段（例如内核线程没有 Vm* 字段）不出现在结果中
+func readStatusBytes(pid int32, names ...string) (map[string]uint64, error) {
+	content, err := os.ReadFile(procfs.PID(pid, "status"))
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]uint64, len(names))
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() && len(fields) < len(names) {
+		// 格式为 "VmSwap:      123 kB"
+		key, value, ok := bytes.Cut(scanner.Bytes(), []byte(":"))
+		if !ok {
+			continue
+		}
+		for _, name := range names {
+			if string(key) != name {
+				continue
+			}
+			parts := bytes.Fields(value)
+			if len(parts) < 1 {
+				break
+			}
+			kb, err := strconv.ParseUint(string(parts[0]), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = kb * 1024
+		}
+	}
+	return fields, nil
+}
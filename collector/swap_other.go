@@ -0,0 +1,13 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readSwapBytes(pid int32) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func readRSSPeakBytes(pid int32) (peak, rss uint64, err error) {
+	return 0, 0, errors.ErrUnsupported
+}
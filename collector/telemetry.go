@@ -0,0 +1,152 @@
+package collector
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// telemetry exporter 自身的运行指标，失败不再只体现在日志里
+type telemetry struct {
+	refreshDuration    prometheus.Gauge
+	refreshLastSuccess prometheus.Gauge
+	cachedProcesses    prometheus.Gauge
+	scrapeDuration     prometheus.Gauge
+	deadlineExceeded   prometheus.Counter
+	refreshReadBytes   prometheus.Counter
+	refreshReadCalls   prometheus.Counter
+	firstCollection    prometheus.Gauge
+	errors             *prometheus.CounterVec
+	processDuration    prometheus.Histogram
+	collectorDuration  *prometheus.HistogramVec
+
+	// lastRefresh 最近一次缓存刷新完成的时间（UnixNano），warmedUp 预热是否完成，供首页和 /-/ready 读取
+	lastRefresh atomic.Int64
+	warmedUp    atomic.Bool
+}
+
+func newTelemetry() *telemetry {
+	return &telemetry{
+		refreshDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "process_exporter_cache_refresh_duration_seconds",
+			Help: "Duration of the last process cache refresh.",
+		}),
+		refreshLastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "process_exporter_cache_last_refresh_success_timestamp_seconds",
+			Help: "Timestamp of the last successful process cache refresh.",
+		}),
+		cachedProcesses: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "process_exporter_cached_processes",
+			Help: "Number of matched processes currently held in the cache.",
+		}),
+		scrapeDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "process_exporter_scrape_collect_duration_seconds",
+			Help: "Duration of collecting per-process metrics in the current scrape.",
+		}),
+		deadlineExceeded: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "process_exporter_scrape_deadline_exceeded_total",
+			Help: "Number of scrapes that hit the scrape timeout and returned partial per-process metrics.",
+		}),
+		refreshReadBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "process_exporter_cache_refresh_read_bytes_total",
+			Help: "Total bytes read from /proc and other files by process cache refreshes. Linux only.",
+		}),
+		refreshReadCalls: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "process_exporter_cache_refresh_read_syscalls_total",
+			Help: "Total read syscalls (syscr of the refreshing thread) issued by process cache refreshes. This is not a count of files: a file may take one or several reads, and the kernel does not expose per-thread open counts. Linux only.",
+		}),
+		firstCollection: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "process_exporter_first_collection_complete",
+			Help: "Whether the startup warm-up (a second cache refresh for CPU usage baselines and a full collection) has completed (1) or not (0). Dashboards can hide values while it is 0.",
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "process_exporter_process_errors_total",
+			Help: "Errors while reading process information, by reason (permission_denied, vanished, other).",
+		}, []string{"reason"}),
+		processDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "process_exporter_process_collect_duration_seconds",
+			Help:    "Time spent collecting the metrics of a single process, one observation per process per scrape.",
+			Buckets: collectDurationBuckets,
+		}),
+		collectorDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "process_exporter_collector_duration_seconds",
+			Help:    "Time a collector spent on a single process, one observation per process per scrape. Reads shared by several collectors are attributed to the first enabled one.",
+			Buckets: collectDurationBuckets,
+		}, []string{"collector"}),
+	}
+}
+
+// collectDurationBuckets 单个进程采集耗时的分桶，正常进程在毫秒以内，打开几十万个文件的进程可能需要数秒
+var collectDurationBuckets = []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5}
+
+// observeCollectTime 记录采集一个进程的总耗时和每个采集项的耗时
+func (t *telemetry) observeCollectTime(timer *collectTimer) {
+	t.processDuration.Observe(timer.total().Seconds())
+	for collector, d := range timer.durations {
+		t.collectorDuration.WithLabelValues(collector).Observe(d.Seconds())
+	}
+}
+
+// observeError 按原因统计读取进程信息时的错误
+func (t *telemetry) observeError(err error) {
+	t.errors.WithLabelValues(errorReason(err)).Inc()
+}
+
+// errorReason 将错误归类为权限不足、进程已退出或其他
+func errorReason(err error) string {
+	switch {
+	case errors.Is(err, os.ErrPermission), errors.Is(err, syscall.EPERM):
+		return "permission_denied"
+	case vanished(err):
+		return "vanished"
+	default:
+		return "other"
+	}
+}
+
+// observeRefresh 记录一次缓存刷新的结果
+func (t *telemetry) observeRefresh(start time.Time, cached int) {
+	t.refreshDuration.Set(time.Since(start).Seconds())
+	now := time.Now()
+	t.refreshLastSuccess.Set(float64(now.Unix()))
+	t.lastRefresh.Store(now.UnixNano())
+	t.cachedProcesses.Set(float64(cached))
+}
+
+// observeRefreshIO 累加一次缓存刷新读取的字节数和系统调用次数
+func (t *telemetry) observeRefreshIO(io threadIO) {
+	t.refreshReadBytes.Add(float64(io.readBytes))
+	t.refreshReadCalls.Add(float64(io.readSyscalls))
+}
+
+func (t *telemetry) Describe(ch chan<- *prometheus.Desc) {
+	t.refreshDuration.Describe(ch)
+	t.refreshLastSuccess.Describe(ch)
+	t.cachedProcesses.Describe(ch)
+	t.scrapeDuration.Describe(ch)
+	t.deadlineExceeded.Describe(ch)
+	t.refreshReadBytes.Describe(ch)
+	t.refreshReadCalls.Describe(ch)
+	t.firstCollection.Describe(ch)
+	t.errors.Describe(ch)
+	t.processDuration.Describe(ch)
+	t.collectorDuration.Describe(ch)
+}
+
+func (t *telemetry) Collect(ch chan<- prometheus.Metric) {
+	t.refreshDuration.Collect(ch)
+	t.refreshLastSuccess.Collect(ch)
+	t.cachedProcesses.Collect(ch)
+	t.scrapeDuration.Collect(ch)
+	t.deadlineExceeded.Collect(ch)
+	t.refreshReadBytes.Collect(ch)
+	t.refreshReadCalls.Collect(ch)
+	t.firstCollection.Collect(ch)
+	t.errors.Collect(ch)
+	t.processDuration.Collect(ch)
+	t.collectorDuration.Collect(ch)
+}
@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+// threadIO 当前线程累计的读取量，来自 /proc/thread-self/io
+type threadIO struct {
+	readBytes    uint64 // rchar：read 类系统调用读到的字节数，包括 /proc 这类伪文件
+	readSyscalls uint64 // syscr
+}
+
+// readThreadIO 读取当前线程的 IO 统计，调用方需要先 runtime.LockOSThread
+func readThreadIO() (threadIO, error) {
+	content, err := os.ReadFile("/proc/thread-self/io")
+	if err != nil {
+		return threadIO{}, err
+	}
+
+	var io threadIO
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, ok := bytes.Cut(scanner.Bytes(), []byte(": "))
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(string(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch string(key) {
+		case "rchar":
+			io.readBytes = n
+		case "syscr":
+			io.readSyscalls = n
+		}
+	}
+	return io, nil
+}
+
+// measureThreadIO 在锁定的 OS 线程上执行 fn，返回 fn 期间该线程读取的字节数和 read 系统调用次数
+// fn 内的 gopsutil 调用都是同步的，因此线程级的统计就是这次刷新本身的读取开销
+func measureThreadIO(fn func()) (threadIO, bool) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	before, err := readThreadIO()
+	fn()
+	if err != nil {
+		return threadIO{}, false
+	}
+	after, err := readThreadIO()
+	if err != nil {
+		return threadIO{}, false
+	}
+	return threadIO{
+		readBytes:    after.readBytes - before.readBytes,
+		readSyscalls: after.readSyscalls - before.readSyscalls,
+	}, true
+}
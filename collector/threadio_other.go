@@ -0,0 +1,14 @@
+//go:build !linux
+
+package collector
+
+type threadIO struct {
+	readBytes    uint64
+	readSyscalls uint64
+}
+
+// measureThreadIO 非 Linux 平台无法统计线程级的读取量，只执行 fn
+func measureThreadIO(fn func()) (threadIO, bool) {
+	fn()
+	return threadIO{}, false
+}
@@ -0,0 +1,12 @@
+package collector
+
+// threadStat 单个线程的名称、状态和 CPU 时间
+type threadStat struct {
+	tid    string
+	name   string
+	state  string
+	user   float64
+	system float64
+	// cpu 线程最后一次运行所在的 CPU，-1 表示未知
+	cpu int
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+
+	"process-exporter/internal/procfs"
+)
+
+// userHZ /proc/pid/stat 中 CPU 时间的单位，Linux 对用户态固定为 100
+const userHZ = 100
+
+// threadStates /proc/pid/task/tid/stat 中状态字符对应的名称
+var threadStates = map[byte]string{
+	'R': "running",
+	'S': "sleeping",
+	'D': "disk-sleep",
+	'T': "stopped",
+	't': "tracing-stop",
+	'Z': "zombie",
+	'X': "dead",
+	'I': "idle",
+}
+
+// readThreads 遍历 /proc/pid/task，读取每个线程的 stat
+// 线程可能在遍历过程中退出，读取失败的线程直接跳过
+func readThreads(pid int32) ([]threadStat, error) {
+	dir := procfs.PID(pid, "task")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	threads := make([]threadStat, 0, len(entries))
+	for _, entry := range entries {
+		content, err := os.ReadFile(dir + "/" + entry.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		if t, ok := parseThreadStat(content); ok {
+			t.tid = entry.Name()
+			threads = append(threads, t)
+		}
+	}
+	return threads, nil
+}
+
+// parseThreadStat 解析 stat 内容，线程名可能包含空格和括号，以最后一个 ')' 为界
+func parseThreadStat(content []byte) (threadStat, bool) {
+	open := bytes.IndexByte(content, '(')
+	end := bytes.LastIndexByte(content, ')')
+	if open < 0 || end < open {
+		return threadStat{}, false
+	}
+	// ')' 之后依次是 state(3) ... utime(14) stime(15)
+	fields := bytes.Fields(content[end+1:])
+	if len(fields) < 13 {
+		return threadStat{}, false
+	}
+	utime, err := strconv.ParseUint(string(fields[11]), 10, 64)
+	if err != nil {
+		return threadStat{}, false
+	}
+	stime, err := strconv.ParseUint(string(fields[12]), 10, 64)
+	if err != nil {
+		return threadStat{}, false
+	}
+	state, ok := threadStates[fields[0][0]]
+	if !ok {
+		state = string(fields[0])
+	}
+	// processor(39) 在很老的内核上没有
+	cpu := -1
+	if len(fields) >= 37 {
+		if n, err := strconv.Atoi(string(fields[36])); err == nil {
+			cpu = n
+		}
+	}
+	return threadStat{
+		// 线程名可以被 prctl(PR_SET_NAME) 设置为任意字节，非 UTF-8 的标签值会让 MustNewConstMetric panic
+		name:   strings.ToValidUTF8(string(content[open+1:end]), "\uFFFD"),
+		state:  state,
+		user:   float64(utime) / userHZ,
+		system: float64(stime) / userHZ,
+		cpu:    cpu,
+	}, true
+}
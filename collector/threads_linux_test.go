@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+// statLine 构造 /proc/pid/task/tid/stat 的内容，utime=150、stime=50，processor=3
+func statLine(name string) string {
+	fields := []string{"S", "1", "1", "1", "0", "-1", "4194560", "100", "0", "0", "0", "150", "50"}
+	for len(fields) < 36 {
+		fields = append(fields, "0")
+	}
+	fields = append(fields, "3")
+	return "1234 (" + name + ") " + strings.Join(fields, " ") + "\n"
+}
+
+func TestParseThreadStat(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantName string
+		wantOK   bool
+	}{
+		{"plain name", statLine("java"), "java", true},
+		{"name with spaces and parentheses", statLine("GC (Thread) 1"), "GC (Thread) 1", true},
+		{"invalid utf-8 name", statLine("worker\xff\xfe"), "worker�", true},
+		{"missing parentheses", "1234 java S 1", "", false},
+		{"truncated", "1234 (java) S 1 1", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseThreadStat([]byte(tt.content))
+			if ok != tt.wantOK {
+				t.Fatalf("parseThreadStat() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got.name != tt.wantName {
+				t.Errorf("name = %q, want %q", got.name, tt.wantName)
+			}
+			if got.state != "sleeping" || got.user != 1.5 || got.system != 0.5 || got.cpu != 3 {
+				t.Errorf("parseThreadStat() = %+v", got)
+			}
+		})
+	}
+}
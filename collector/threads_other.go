@@ -0,0 +1,9 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readThreads(pid int32) ([]threadStat, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// 进程进入 top 列表的原因，作为 reason 标签
+const (
+	topReasonCPU    = "top_cpu"
+	topReasonMemory = "top_mem"
+)
+
+// topCPUSample 一次刷新时读取的进程累计 CPU 时间
+type topCPUSample struct {
+	start int64
+	cpu   float64
+	at    time.Time
+}
+
+// topProcess 一个进入 top 列表的进程
+type topProcess struct {
+	reason string
+	name   string
+	pid    int32
+	// cores 两次刷新之间的平均 CPU 使用量（核），第一次看到该进程时为 0
+	cores float64
+	rss   uint64
+}
+
+// topTracker 每次刷新缓存时读取所有进程（不只是匹配的进程）的 CPU 时间和 RSS，
+// 保存 CPU 使用量最高和 RSS 最大的各 n 个进程，不在监控列表中的进程占满主机时也能看到
+type topTracker struct {
+	n int
+
+	// prev 上一次刷新的 CPU 时间，由 refreshMu 保护
+	prev map[int32]topCPUSample
+
+	mu  sync.Mutex
+	top []topProcess
+}
+
+func newTopTracker(n int) *topTracker {
+	return &topTracker{n: n, prev: make(map[int32]topCPUSample)}
+}
+
+// update 读取 procs 的 CPU 时间和 RSS 并重新选出 top 列表，读取失败（通常是进程已退出）的进程跳过
+func (t *topTracker) update(procs []*process.Process, exclusions scanExclusions) {
+	type candidate struct {
+		topProcess
+		sampled bool
+	}
+	now := time.Now()
+	next := make(map[int32]topCPUSample, len(procs))
+	candidates := make([]candidate, 0, len(procs))
+	for _, p := range procs {
+		if exclusions.excluded(p.Pid) {
+			continue
+		}
+		name, err := p.Name()
+		if err != nil {
+			continue
+		}
+		start, err := p.CreateTime()
+		if err != nil {
+			continue
+		}
+		c := candidate{topProcess: topProcess{name: name, pid: p.Pid}}
+		if times, err := p.Times(); err == nil {
+			cpu := times.User + times.System
+			next[p.Pid] = topCPUSample{start: start, cpu: cpu, at: now}
+			if old, ok := t.prev[p.Pid]; ok && old.start == start {
+				if elapsed := now.Sub(old.at).Seconds(); elapsed > 0 {
+					c.cores = max(cpu-old.cpu, 0) / elapsed
+					c.sampled = true
+				}
+			}
+		}
+		if mem, err := p.MemoryInfo(); err == nil {
+			c.rss = mem.RSS
+		}
+		candidates = append(candidates, c)
+	}
+	t.prev = next
+
+	var top []topProcess
+	// 第一次刷新没有 CPU 使用量，只输出内存
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].cores > candidates[j].cores })
+	for _, c := range candidates[:min(t.n, len(candidates))] {
+		if c.sampled {
+			c.reason = topReasonCPU
+			top = append(top, c.topProcess)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].rss > candidates[j].rss })
+	for _, c := range candidates[:min(t.n, len(candidates))] {
+		c.reason = topReasonMemory
+		top = append(top, c.topProcess)
+	}
+
+	t.mu.Lock()
+	t.top = top
+	t.mu.Unlock()
+}
+
+// collectTop 输出最近一次刷新选出的 top 进程
+func (c *ProcessCollector) collectTop(ch chan<- prometheus.Metric) {
+	c.top.mu.Lock()
+	top := c.top.top
+	c.top.mu.Unlock()
+	for _, p := range top {
+		pid := strconv.Itoa(int(p.pid))
+		ch <- prometheus.MustNewConstMetric(c.topCPU, prometheus.GaugeValue, p.cores, p.reason, p.name, pid)
+		ch <- prometheus.MustNewConstMetric(c.topRSS, prometheus.GaugeValue, float64(p.rss), p.reason, p.name, pid)
+	}
+}
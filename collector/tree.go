@@ -0,0 +1,123 @@
+package collector
+
+import (
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// recordTree 读取所有进程的父进程，为缓存中的进程记录 PPID，
+// 并为每个根进程（父进程不在缓存中的已匹配进程）汇总整棵子树（包括未匹配的辅助进程）的 CPU 时间和 RSS
+// RSS 和进程数只统计刷新时仍在运行的后代进程；CPU 时间加上已退出后代的 CPU 时间，见 treeCPUTracker
+func (c *ProcessCollector) recordTree(cache map[int32]CachedProcess, procs []*process.Process) {
+	byPID := make(map[int32]*process.Process, len(procs))
+	children := make(map[int32][]int32)
+	parents := make(map[int32]int32, len(procs))
+	for _, p := range procs {
+		ppid, err := p.Ppid()
+		if err != nil {
+			continue
+		}
+		byPID[p.Pid] = p
+		parents[p.Pid] = ppid
+		children[ppid] = append(children[ppid], p.Pid)
+	}
+
+	for pid, cached := range cache {
+		ppid, ok := parents[pid]
+		if !ok {
+			continue
+		}
+		cached.PPID = ppid
+		cache[pid] = cached
+	}
+
+	roots := make(map[procKey]struct{})
+	for pid, cached := range cache {
+		if _, ok := parents[pid]; !ok {
+			continue
+		}
+		if _, ok := cache[cached.PPID]; ok {
+			continue
+		}
+		// 按 PID 去重，防止 PID 复用导致的环
+		visited := map[int32]struct{}{}
+		root := procKey{pid: pid, start: cached.StartTime}
+		members := make(map[int32]float64)
+		stack := []int32{pid}
+		for len(stack) > 0 {
+			cur := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if _, ok := visited[cur]; ok {
+				continue
+			}
+			visited[cur] = struct{}{}
+			stack = append(stack, children[cur]...)
+
+			p := byPID[cur]
+			if member, ok := cache[cur]; ok && !member.SampledAt.IsZero() {
+				members[cur] = member.CPUTime
+			} else if times, err := p.Times(); err == nil {
+				members[cur] = times.User + times.System
+			} else if last, ok := c.treeCPUs.last(root, cur); ok {
+				// 本次读取失败时沿用上一次的值，避免被当作退出后又重复计入
+				members[cur] = last
+			}
+			if mem, err := p.MemoryInfo(); err == nil {
+				cached.TreeRSS += mem.RSS
+			}
+		}
+		roots[root] = struct{}{}
+		cached.TreeCPU = c.treeCPUs.Observe(root, members)
+		cached.TreeRoot = true
+		cached.TreeProcs = len(visited)
+		cache[pid] = cached
+	}
+	c.treeCPUs.Prune(roots)
+}
+
+// treeCPUTracker 记录每棵进程树上一次刷新时各成员的 CPU 时间
+// 成员退出（或被重新挂到树外）后把它最后的 CPU 时间计入 exited，树的 CPU 时间因此只增不减，可以按计数器使用 rate()
+type treeCPUTracker struct {
+	// members 根进程 -> 成员 PID -> 上一次刷新时的累计 CPU 时间
+	members map[procKey]map[int32]float64
+	// exited 根进程 -> 已离开的成员最后的 CPU 时间之和
+	exited map[procKey]float64
+}
+
+func newTreeCPUTracker() *treeCPUTracker {
+	return &treeCPUTracker{members: make(map[procKey]map[int32]float64), exited: make(map[procKey]float64)}
+}
+
+// Observe 记录根进程 root 本次的成员及其 CPU 时间，返回整棵树的累计 CPU 时间
+// 成员的 CPU 时间比上一次小说明 PID 被复用，按旧进程退出处理
+func (t *treeCPUTracker) Observe(root procKey, members map[int32]float64) float64 {
+	exited := t.exited[root]
+	for pid, last := range t.members[root] {
+		if cur, ok := members[pid]; !ok || cur < last {
+			exited += last
+		}
+	}
+	t.exited[root] = exited
+	t.members[root] = members
+
+	total := exited
+	for _, v := range members {
+		total += v
+	}
+	return total
+}
+
+// last 返回成员上一次刷新时的 CPU 时间
+func (t *treeCPUTracker) last(root procKey, pid int32) (float64, bool) {
+	v, ok := t.members[root][pid]
+	return v, ok
+}
+
+// Prune 删除本次刷新中已不是根进程的状态
+func (t *treeCPUTracker) Prune(roots map[procKey]struct{}) {
+	for root := range t.members {
+		if _, ok := roots[root]; !ok {
+			delete(t.members, root)
+			delete(t.exited, root)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package collector
+
+import "testing"
+
+func TestTreeCPUTracker(t *testing.T) {
+	root := procKey{pid: 1, start: 100}
+	steps := []struct {
+		name    string
+		members map[int32]float64
+		want    float64
+	}{
+		{"root and two children", map[int32]float64{1: 10, 2: 5, 3: 1}, 16},
+		{"children keep running", map[int32]float64{1: 11, 2: 7, 3: 2}, 20},
+		{"exited child carried forward", map[int32]float64{1: 12, 2: 8}, 22},
+		{"new child added", map[int32]float64{1: 12, 2: 8, 4: 0.5}, 22.5},
+		{"pid of an exited child used again", map[int32]float64{1: 12, 2: 8, 4: 0.5, 3: 0.1}, 22.6},
+		{"pid reused between refreshes", map[int32]float64{1: 12, 2: 8, 4: 0.5, 3: 0.05}, 22.65},
+		{"all children exited", map[int32]float64{1: 13}, 23.65},
+	}
+	tracker := newTreeCPUTracker()
+	prev := 0.0
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			got := tracker.Observe(root, step.members)
+			if diff := got - step.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("Observe() = %v, want %v", got, step.want)
+			}
+			if got < prev {
+				t.Errorf("tree CPU went down from %v to %v", prev, got)
+			}
+			prev = got
+		})
+	}
+
+	tracker.Prune(map[procKey]struct{}{{pid: 1, start: 200}: {}})
+	if len(tracker.members) != 0 || len(tracker.exited) != 0 {
+		t.Errorf("state of a restarted root kept after Prune")
+	}
+}
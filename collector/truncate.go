@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// truncatedPIDs 返回进程数超过 max 的分组中所有进程的 PID
+func truncatedPIDs(targets []CachedProcess, running map[string]int, max int) map[string]struct{} {
+	pids := make(map[string]struct{})
+	for _, t := range targets {
+		if running[t.Group] > max {
+			pids[strconv.Itoa(int(t.Proc.Pid))] = struct{}{}
+		}
+	}
+	return pids
+}
+
+// truncatingGatherer 进程数超过 -max-procs-per-group 的分组不再输出进程级序列，
+// 按 aggregatingGatherer 的方式去掉 pid 等标签聚合，其余分组不受影响
+// 被截断的进程由最近一次采集记录，与输出的进程级指标一致
+type truncatingGatherer struct {
+	gatherer  prometheus.Gatherer
+	collector *ProcessCollector
+	carry     *counterCarry
+}
+
+func (t truncatingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := t.gatherer.Gather()
+	pids := t.collector.truncated.Load()
+	if pids == nil || len(*pids) == 0 {
+		return families, err
+	}
+	pass := t.carry.begin()
+	for _, mf := range families {
+		if hasPIDLabel(mf) {
+			aggregateFamily(mf, func(m *dto.Metric) bool {
+				for _, lp := range m.Label {
+					if lp.GetName() == "pid" {
+						_, ok := (*pids)[lp.GetValue()]
+						return ok
+					}
+				}
+				return false
+			}, pass)
+		}
+	}
+	return dropEmpty(families), err
+}
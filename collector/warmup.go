@@ -0,0 +1,34 @@
+package collector
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WarmUp 启动后的预热：间隔 delay 再刷新一次缓存，再完整采集一次
+// 第一次刷新只有进程列表，CPU 使用量（EWMA、配额建议）、CPU 频率、工作集等需要两次刷新之间的差值，
+// 完整采集一次则让 gopsutil 读取过所有采集项，之后的第一次抓取即有完整的数据
+// 完成后 process_exporter_first_collection_complete 变为 1
+func (c *ProcessCollector) WarmUp(delay time.Duration) {
+	start := time.Now()
+	if delay > 0 {
+		time.Sleep(delay)
+		c.refreshProcessCache()
+	}
+
+	ch := make(chan prometheus.Metric, 256)
+	go func() {
+		c.collectNow(ch, time.Time{}, c.collectors)
+		close(ch)
+	}()
+	series := 0
+	for range ch {
+		series++
+	}
+
+	c.telemetry.firstCollection.Set(1)
+	c.telemetry.warmedUp.Store(true)
+	slog.Debug("Warm-up finished", "series", series, "duration", time.Since(start))
+}
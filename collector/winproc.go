@@ -0,0 +1,12 @@
+package collector
+
+// windowsProcess 通过 Windows API 读取的进程句柄数、内存和 IO 计数
+// NumFDs 在 Windows 上没有意义，句柄数才是对应的资源
+type windowsProcess struct {
+	handles    uint32
+	workingSet uint64
+	// privateBytes 进程独占的已提交内存（Private Bytes），比工作集更适合发现内存泄漏
+	privateBytes uint64
+	// otherOps、otherBytes 读写以外的 IO（如 DeviceIoControl），读写部分由 io 采集项输出
+	otherOps, otherBytes uint64
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package collector
+
+import "errors"
+
+func readWindowsProcess(pid int32) (windowsProcess, error) {
+	return windowsProcess{}, errors.ErrUnsupported
+}
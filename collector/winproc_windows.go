@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32                 = windows.NewLazySystemDLL("kernel32.dll")
+	procGetProcessHandleCount   = modkernel32.NewProc("GetProcessHandleCount")
+	procGetProcessIoCounters    = modkernel32.NewProc("GetProcessIoCounters")
+	procK32GetProcessMemoryInfo = modkernel32.NewProc("K32GetProcessMemoryInfo")
+)
+
+// processMemoryCountersEx PROCESS_MEMORY_COUNTERS_EX
+type processMemoryCountersEx struct {
+	cb                         uint32
+	pageFaultCount             uint32
+	peakWorkingSetSize         uintptr
+	workingSetSize             uintptr
+	quotaPeakPagedPoolUsage    uintptr
+	quotaPagedPoolUsage        uintptr
+	quotaPeakNonPagedPoolUsage uintptr
+	quotaNonPagedPoolUsage     uintptr
+	pagefileUsage              uintptr
+	peakPagefileUsage          uintptr
+	privateUsage               uintptr
+}
+
+// readWindowsProcess 以 PROCESS_QUERY_LIMITED_INFORMATION 打开进程读取句柄数、内存和 IO 计数
+// 只需要受限查询权限，服务等其他用户的进程也能读取
+func readWindowsProcess(pid int32) (windowsProcess, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return windowsProcess{}, err
+	}
+	defer windows.CloseHandle(h)
+
+	var wp windowsProcess
+	if ret, _, err := procGetProcessHandleCount.Call(uintptr(h), uintptr(unsafe.Pointer(&wp.handles))); ret == 0 {
+		return windowsProcess{}, err
+	}
+
+	mem := processMemoryCountersEx{cb: uint32(unsafe.Sizeof(processMemoryCountersEx{}))}
+	if ret, _, err := procK32GetProcessMemoryInfo.Call(uintptr(h), uintptr(unsafe.Pointer(&mem)), uintptr(mem.cb)); ret == 0 {
+		return windowsProcess{}, err
+	}
+	wp.workingSet = uint64(mem.workingSetSize)
+	wp.privateBytes = uint64(mem.privateUsage)
+
+	var io windows.IO_COUNTERS
+	if ret, _, err := procGetProcessIoCounters.Call(uintptr(h), uintptr(unsafe.Pointer(&io))); ret == 0 {
+		return windowsProcess{}, err
+	}
+	wp.otherOps = io.OtherOperationCount
+	wp.otherBytes = io.OtherTransferCount
+	return wp, nil
+}
@@ -0,0 +1,28 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+
+	"process-exporter/internal/procfs"
+)
+
+// readReferencedBytes 读取 smaps_rollup 中的 Referenced 字段
+// 即自上次清除 referenced 标记以来被访问过的内存大小
+func readReferencedBytes(pid int32) (uint64, error) {
+	fields, err := readSmapsRollup(pid)
+	if err != nil {
+		return 0, err
+	}
+	referenced, ok := fields["Referenced"]
+	if !ok {
+		return 0, fmt.Errorf("no Referenced field in smaps_rollup of pid %d", pid)
+	}
+	return referenced, nil
+}
+
+// clearReferenced 向 /proc/pid/clear_refs 写入 1，清除所有页面的 referenced/accessed 标记
+// 注意：这会影响内核的页面回收判断，所以只在显式开启时使用
+func clearReferenced(pid int32) error {
+	return os.WriteFile(procfs.PID(pid, "clear_refs"), []byte("1"), 0)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+func readReferencedBytes(pid int32) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func clearReferenced(pid int32) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"process-exporter/internal/procfs"
+)
+
+// countZombieChildren 统计进程处于 Z 状态（已退出但未被回收）的子进程数量
+// 子进程列表来自 /proc/pid/task/*/children，需要内核开启 CONFIG_PROC_CHILDREN
+func countZombieChildren(pid int32) (int, error) {
+	files, err := filepath.Glob(procfs.PID(pid, "task", "*", "children"))
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, fmt.Errorf("no children files for pid %d", pid)
+	}
+
+	zombies := 0
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, field := range bytes.Fields(content) {
+			child, err := strconv.Atoi(string(field))
+			if err != nil {
+				continue
+			}
+			stat, err := os.ReadFile(procfs.PID(int32(child), "stat"))
+			if err != nil {
+				continue
+			}
+			// 状态字符在进程名的 ')' 之后
+			if end := bytes.LastIndexByte(stat, ')'); end >= 0 && end+2 < len(stat) && stat[end+2] == 'Z' {
+				zombies++
+			}
+		}
+	}
+	return zombies, nil
+}
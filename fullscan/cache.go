@@ -0,0 +1,140 @@
+package fullscan
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+
+	"process-exporter/matcher"
+)
+
+// CacheTTLs 各类进程属性的缓存时间，按 (PID, 启动时间) 缓存，PID 被复用时不会读到旧进程的值
+// 负数表示不缓存（每次抓取都读取），0 表示一直缓存到进程退出
+// CPU、内存、文件数和磁盘读写每次抓取都读取，不缓存
+type CacheTTLs struct {
+	// Static 名称、命令行和可执行文件路径，进程运行期间不变
+	Static time.Duration
+	// Slow 用户名和 cgroup，只在 setuid 或迁移 cgroup 时变化
+	Slow time.Duration
+}
+
+// DefaultCacheTTLs 静态属性一直缓存，用户名和 cgroup 缓存 1 分钟
+var DefaultCacheTTLs = CacheTTLs{Static: 0, Slow: time.Minute}
+
+// procKey 一次进程运行
+type procKey struct {
+	pid   int32
+	start int64
+}
+
+// cachedField 一个属性的缓存值，只缓存读取成功的值，进程刚启动或没有权限时下次抓取重新读取
+type cachedField[T any] struct {
+	valid   bool
+	value   T
+	expires time.Time // 零值表示不过期
+}
+
+type cacheEntry struct {
+	name, exe, cmdline, username cachedField[string]
+	cgroups                      cachedField[[]string]
+}
+
+// fieldCache 跨抓取缓存进程属性，减少每次抓取读取 /proc 的次数
+type fieldCache struct {
+	ttls CacheTTLs
+
+	mu      sync.Mutex
+	entries map[procKey]*cacheEntry
+	// seen 本轮采集中出现过的进程，采集结束时清理已经退出的进程
+	seen map[procKey]struct{}
+}
+
+func newFieldCache(ttls CacheTTLs) *fieldCache {
+	return &fieldCache{ttls: ttls, entries: make(map[procKey]*cacheEntry), seen: make(map[procKey]struct{})}
+}
+
+// process 返回带缓存的进程包装，未命中时通过 matcher.FromProcessWithUsers 读取，同一次抓取中每个属性最多读取一次
+func (c *fieldCache) process(p *process.Process, start int64, users *matcher.UserCache) matcher.Process {
+	key := procKey{pid: p.Pid, start: start}
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &cacheEntry{}
+		c.entries[key] = entry
+	}
+	c.seen[key] = struct{}{}
+	c.mu.Unlock()
+	return &cachedProcess{
+		cache: c,
+		entry: entry,
+		read:  matcher.FromProcessWithUsers(p, users),
+		now:   time.Now(),
+	}
+}
+
+// sweep 删除本轮采集中没有出现的进程
+func (c *fieldCache) sweep() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if _, ok := c.seen[key]; !ok {
+			delete(c.entries, key)
+		}
+	}
+	c.seen = make(map[procKey]struct{})
+}
+
+// cachedProcess 实现 matcher.Process，匹配规则和标签共用
+type cachedProcess struct {
+	cache *fieldCache
+	entry *cacheEntry
+	read  matcher.Process
+	now   time.Time
+}
+
+// get 返回未过期的缓存值，否则调用 read 读取并按 ttl 缓存
+func get[T any](cp *cachedProcess, field *cachedField[T], ttl time.Duration, read func() (T, error)) (T, error) {
+	if ttl < 0 {
+		return read()
+	}
+	cp.cache.mu.Lock()
+	if field.valid && (field.expires.IsZero() || cp.now.Before(field.expires)) {
+		value := field.value
+		cp.cache.mu.Unlock()
+		return value, nil
+	}
+	cp.cache.mu.Unlock()
+
+	value, err := read()
+	if err != nil {
+		return value, err
+	}
+	cp.cache.mu.Lock()
+	*field = cachedField[T]{valid: true, value: value}
+	if ttl > 0 {
+		field.expires = cp.now.Add(ttl)
+	}
+	cp.cache.mu.Unlock()
+	return value, nil
+}
+
+func (cp *cachedProcess) Name() (string, error) {
+	return get(cp, &cp.entry.name, cp.cache.ttls.Static, cp.read.Name)
+}
+
+func (cp *cachedProcess) Exe() (string, error) {
+	return get(cp, &cp.entry.exe, cp.cache.ttls.Static, cp.read.Exe)
+}
+
+func (cp *cachedProcess) Cmdline() (string, error) {
+	return get(cp, &cp.entry.cmdline, cp.cache.ttls.Static, cp.read.Cmdline)
+}
+
+func (cp *cachedProcess) Username() (string, error) {
+	return get(cp, &cp.entry.username, cp.cache.ttls.Slow, cp.read.Username)
+}
+
+func (cp *cachedProcess) Cgroups() ([]string, error) {
+	return get(cp, &cp.entry.cgroups, cp.cache.ttls.Slow, cp.read.Cgroups)
+}
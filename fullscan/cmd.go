@@ -0,0 +1,160 @@
+package fullscan
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// CmdRules cmd 标签的改写规则，去掉命令行中的密码、令牌等敏感信息和每次启动都不同的临时路径
+// 按 Replace、Redact、MaxArgs、MaxLength 的顺序应用，Hash 在 MaxArgs 之前计算
+//
+//	replace:
+//	  - regex: '/tmp/[^ ]+'
+//	    replacement: '/tmp/*'
+//	redact:
+//	  - '--password=\S+'
+//	max_args: 5
+//	max_length: 200
+//	hash: true
+type CmdRules struct {
+	// Replace 正则替换，replacement 中可以用 $1 引用分组
+	Replace []CmdReplace `yaml:"replace"`
+	// Redact 匹配的部分替换为 <redacted>
+	Redact []string `yaml:"redact"`
+	// MaxArgs 大于 0 时只保留程序名和前 MaxArgs 个参数，其余参数替换为 ...
+	MaxArgs int `yaml:"max_args"`
+	// MaxLength 大于 0 时超过 MaxLength 个字符的部分替换为 ...，避免很长的 Java classpath 原样进入标签值
+	MaxLength int `yaml:"max_length"`
+	// Hash 在末尾追加完整命令行（替换和脱敏之后）的短哈希，截断后仍能区分不同的调用
+	Hash bool `yaml:"hash"`
+}
+
+// CmdReplace 一条正则替换规则
+type CmdReplace struct {
+	Regex       string `yaml:"regex"`
+	Replacement string `yaml:"replacement"`
+}
+
+// redacted 敏感信息的替换文本
+const redacted = "<redacted>"
+
+// CmdRewriter 编译后的 cmd 标签改写规则，nil 表示不改写
+type CmdRewriter struct {
+	replace   []*regexp.Regexp
+	with      []string
+	redact    []*regexp.Regexp
+	maxArgs   int
+	maxLength int
+	hash      bool
+}
+
+// LoadCmdRules 读取 cmd 标签的改写规则文件
+func LoadCmdRules(path string) (CmdRules, error) {
+	var rules CmdRules
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+	if err := yaml.UnmarshalStrict(content, &rules); err != nil {
+		return rules, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// LoadCmdRewriter 读取并编译 cmd 标签的改写规则文件
+func LoadCmdRewriter(path string) (*CmdRewriter, error) {
+	rules, err := LoadCmdRules(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := rules.Compile()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// NewCmdRewriter 读取规则文件（path 为空时不读取），maxLength 大于 0 或 hash 为 true 时覆盖文件中的对应设置
+// 没有任何规则时返回 nil
+func NewCmdRewriter(path string, maxLength int, hash bool) (*CmdRewriter, error) {
+	var rules CmdRules
+	if path != "" {
+		var err error
+		if rules, err = LoadCmdRules(path); err != nil {
+			return nil, err
+		}
+	} else if maxLength == 0 && !hash {
+		return nil, nil
+	}
+	if maxLength != 0 {
+		rules.MaxLength = maxLength
+	}
+	rules.Hash = rules.Hash || hash
+	r, err := rules.Compile()
+	if err != nil && path != "" {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, err
+}
+
+// Compile 编译改写规则
+func (rules CmdRules) Compile() (*CmdRewriter, error) {
+	if rules.MaxArgs < 0 {
+		return nil, fmt.Errorf("max_args must not be negative")
+	}
+	if rules.MaxLength < 0 {
+		return nil, fmt.Errorf("max_length must not be negative")
+	}
+	r := &CmdRewriter{maxArgs: rules.MaxArgs, maxLength: rules.MaxLength, hash: rules.Hash}
+	for i, rep := range rules.Replace {
+		re, err := regexp.Compile(rep.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("replace[%d]: %w", i, err)
+		}
+		r.replace = append(r.replace, re)
+		r.with = append(r.with, rep.Replacement)
+	}
+	for i, pattern := range rules.Redact {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact[%d]: %w", i, err)
+		}
+		r.redact = append(r.redact, re)
+	}
+	return r, nil
+}
+
+// Rewrite 改写命令行，r 为 nil 时原样返回
+func (r *CmdRewriter) Rewrite(cmdline string) string {
+	if r == nil || cmdline == "" {
+		return cmdline
+	}
+	for i, re := range r.replace {
+		cmdline = re.ReplaceAllString(cmdline, r.with[i])
+	}
+	for _, re := range r.redact {
+		cmdline = re.ReplaceAllLiteralString(cmdline, redacted)
+	}
+	var sum string
+	if r.hash {
+		h := fnv.New32a()
+		h.Write([]byte(cmdline))
+		sum = fmt.Sprintf(" #%08x", h.Sum32())
+	}
+	if r.maxArgs > 0 {
+		if fields := strings.Fields(cmdline); len(fields) > r.maxArgs+1 {
+			cmdline = strings.Join(fields[:r.maxArgs+1], " ") + " ..."
+		}
+	}
+	if r.maxLength > 0 {
+		if runes := []rune(cmdline); len(runes) > r.maxLength {
+			cmdline = string(runes[:r.maxLength]) + "..."
+		}
+	}
+	return cmdline + sum
+}
@@ -0,0 +1,282 @@
+// Package fullscan 每次抓取都扫描全部进程的采集器（full 模式），导出 node_process_* 指标
+// 与 process-exporter 默认的缓存模式相比，不需要预先配置进程，适合临时排查或进程数量不多的主机
+package fullscan
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/process"
+
+	"process-exporter/matcher"
+)
+
+// Namespace 和 Subsystem 组成指标名前缀 node_process_
+const (
+	Namespace = "node"
+	Subsystem = "process"
+)
+
+// ProcessLabels 进程指标的标签，远程采集在此基础上增加 host
+var ProcessLabels = []string{"name", "pid", "cmd", "user"}
+
+// ProcessCollector 实现了 prometheus.Collector 接口
+type ProcessCollector struct {
+	CPU             *prometheus.Desc
+	CPURatio        *prometheus.Desc
+	Memory          *prometheus.Desc
+	OpenFiles       *prometheus.Desc
+	ReadBytesTotal  *prometheus.Desc
+	WriteBytesTotal *prometheus.Desc
+	// matcher 为 nil 时采集所有进程，配置重载时整体原子替换
+	matcher    atomic.Pointer[matcher.Matcher]
+	collectors Collectors
+	cpu        *cpuTracker
+	// users 匹配规则和 user 标签共用的用户名缓存
+	users *matcher.UserCache
+	// cmd cmd 标签的改写规则，nil 时使用原始命令行
+	cmd *CmdRewriter
+	// fields 名称、命令行等属性的跨抓取缓存
+	fields *fieldCache
+}
+
+// Collectors 各采集项的开关，OpenFiles 和 IOCounters 开销较大时可以关闭
+type Collectors struct {
+	CPU       bool
+	Memory    bool
+	OpenFiles bool
+	IO        bool
+}
+
+// NewProcessCollector 创建一个新的 ProcessCollector
+func NewProcessCollector(m *matcher.Matcher, collectors Collectors, users *matcher.UserCache) *ProcessCollector {
+	pc := &ProcessCollector{
+		CPU: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, Subsystem, "cpu_usage_percent"),
+			"Process CPU usage percentage.",
+			ProcessLabels,
+			nil,
+		),
+		CPURatio: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, Subsystem, "cpu_usage_ratio"),
+			"Process CPU usage between the previous and the current scrape, 1 means one fully used core.",
+			ProcessLabels,
+			nil,
+		),
+		Memory: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, Subsystem, "memory_usage_percent"),
+			"Process memory usage percentage.",
+			ProcessLabels,
+			nil,
+		),
+		OpenFiles: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, Subsystem, "open_files_count"),
+			"Number of open files by the process.",
+			ProcessLabels,
+			nil,
+		),
+		ReadBytesTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, Subsystem, "read_bytes_total"),
+			"Total number of bytes read by the process.",
+			ProcessLabels,
+			nil,
+		),
+		WriteBytesTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, Subsystem, "write_bytes_total"),
+			"Total number of bytes written by the process.",
+			ProcessLabels,
+			nil,
+		),
+		collectors: collectors,
+		cpu:        newCPUTracker(),
+		users:      users,
+		fields:     newFieldCache(DefaultCacheTTLs),
+	}
+	pc.matcher.Store(m)
+	return pc
+}
+
+// SetMatcher 替换匹配规则，m 为 nil 时采集所有进程
+func (pc *ProcessCollector) SetMatcher(m *matcher.Matcher) {
+	pc.matcher.Store(m)
+}
+
+// SetCmdRewriter 设置 cmd 标签的改写规则，需要在注册之前调用
+func (pc *ProcessCollector) SetCmdRewriter(r *CmdRewriter) {
+	pc.cmd = r
+}
+
+// SetCacheTTLs 设置进程属性的缓存时间，需要在注册之前调用，默认为 DefaultCacheTTLs
+func (pc *ProcessCollector) SetCacheTTLs(ttls CacheTTLs) {
+	pc.fields = newFieldCache(ttls)
+}
+
+// Describe 将所有指标的描述符发送到提供的 channel
+func (pc *ProcessCollector) Describe(ch chan<- *prometheus.Desc) {
+	if pc.collectors.CPU {
+		ch <- pc.CPU
+		ch <- pc.CPURatio
+	}
+	if pc.collectors.Memory {
+		ch <- pc.Memory
+	}
+	if pc.collectors.OpenFiles {
+		ch <- pc.OpenFiles
+	}
+	if pc.collectors.IO {
+		ch <- pc.ReadBytesTotal
+		ch <- pc.WriteBytesTotal
+	}
+}
+
+// Collect 收集所有进程的指标数据
+func (pc *ProcessCollector) Collect(ch chan<- prometheus.Metric) {
+	processes, err := process.Processes()
+	if err != nil {
+		slog.Error("Failed to list processes", "err", err)
+		return
+	}
+	if pc.collectors.CPU {
+		defer pc.cpu.sweep()
+	}
+	defer pc.fields.sweep()
+	m := pc.matcher.Load()
+
+	for _, proc := range processes {
+		pid := proc.Pid
+		// 启动时间区分 PID 复用，读取失败时进程通常已经退出
+		startTime, err := proc.CreateTime()
+		if err != nil {
+			slog.Debug("Failed to get process start time", "pid", pid, "err", err)
+			continue
+		}
+
+		// 匹配和标签共用同一个包装，静态属性跨抓取缓存
+		mp := pc.fields.process(proc, startTime, pc.users)
+		name, err := mp.Name()
+		if err != nil {
+			// 进程可能刚退出或没有权限，每次抓取都会重复出现，只在 debug 级别输出
+			slog.Debug("Failed to get process name", "pid", pid, "err", err)
+			continue
+		}
+		if m != nil {
+			if _, ok := m.Match(mp); !ok {
+				continue
+			}
+		}
+
+		cmdline, err := mp.Cmdline()
+		if err != nil {
+			slog.Debug("Failed to get process cmdline", "pid", pid, "name", name, "err", err)
+			cmdline = ""
+		}
+
+		user, err := mp.Username()
+		if err != nil {
+			slog.Debug("Failed to get process username", "pid", pid, "name", name, "err", err)
+			user = "unknown"
+		}
+
+		// 创建标签值
+		labelValues := []string{name, strconv.Itoa(int(pid)), pc.cmd.Rewrite(cmdline), user}
+
+		// 获取并注册 CPU 指标
+		if pc.collectors.CPU {
+			if cpuPercent, err := proc.CPUPercent(); err == nil {
+				if cpuPercent > 0 {
+					ch <- prometheus.MustNewConstMetric(pc.CPU, prometheus.GaugeValue, cpuPercent, labelValues...)
+				}
+			} else {
+				slog.Debug("Failed to get process CPU usage", "pid", pid, "name", name, "err", err)
+			}
+			// 两次采集之间的使用率，第一次采集到的进程没有上一次的数据，不输出
+			if times, err := proc.Times(); err == nil {
+				sample := cpuSample{startTime: startTime, cpuTime: times.User + times.System, sampledAt: time.Now()}
+				if ratio, ok := pc.cpu.observe(pid, sample); ok {
+					ch <- prometheus.MustNewConstMetric(pc.CPURatio, prometheus.GaugeValue, ratio, labelValues...)
+				}
+			}
+		}
+
+		// 获取并注册内存指标
+		if pc.collectors.Memory {
+			if memPercent, err := getProcMemoryPercent(proc); err == nil {
+				if memPercent > 0 {
+					ch <- prometheus.MustNewConstMetric(pc.Memory, prometheus.GaugeValue, memPercent, labelValues...)
+				}
+			} else {
+				slog.Debug("Failed to get process memory usage", "pid", pid, "name", name, "err", err)
+			}
+		}
+
+		// 获取并注册文件打开数指标
+		if pc.collectors.OpenFiles {
+			if openFiles, err := proc.OpenFiles(); err == nil {
+				count := len(openFiles)
+				if count > 0 {
+					ch <- prometheus.MustNewConstMetric(pc.OpenFiles, prometheus.GaugeValue, float64(count), labelValues...)
+				}
+			} else {
+				slog.Debug("Failed to get process open files", "pid", pid, "name", name, "err", err)
+			}
+		}
+
+		// 获取并注册磁盘读写
+		if pc.collectors.IO {
+			if ioCounters, err := proc.IOCounters(); err == nil {
+				ch <- prometheus.MustNewConstMetric(pc.ReadBytesTotal, prometheus.CounterValue, float64(ioCounters.ReadBytes), labelValues...)
+				ch <- prometheus.MustNewConstMetric(pc.WriteBytesTotal, prometheus.CounterValue, float64(ioCounters.WriteBytes), labelValues...)
+			} else {
+				slog.Debug("Failed to get process IO counters", "pid", pid, "name", name, "err", err)
+			}
+		}
+	}
+}
+
+// NewNameMatcher 进程名称忽略大小写和 .exe 后缀后完全相同才匹配，没有名称时返回 nil，即采集所有进程
+func NewNameMatcher(names []string) *matcher.Matcher {
+	m, _ := NewNameMatcherWithMode(names, matcher.Exact)
+	return m
+}
+
+// NewNameMatcherWithMode 与 NewNameMatcher 相同，名称按 mode 匹配，regex 模式下正则无效时返回错误
+func NewNameMatcherWithMode(names []string, mode matcher.NameMode) (*matcher.Matcher, error) {
+	m := matcher.New()
+	for _, n := range names {
+		t := strings.TrimSpace(n)
+		if t == "" {
+			continue
+		}
+		rule, err := matcher.NameRule(t, matcher.NameOptions{Mode: mode, Normalize: true})
+		if err != nil {
+			return nil, fmt.Errorf("name %q: %w", t, err)
+		}
+		m.Add(t, 0, rule)
+	}
+	if m.Len() == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// getProcMemoryPercent 计算单个进程的内存使用百分比
+func getProcMemoryPercent(proc *process.Process) (float64, error) {
+	procMem, err := proc.MemoryInfo()
+	if err != nil {
+		return 0, err
+	}
+
+	nodeMem, err := mem.VirtualMemory()
+	if err != nil {
+		return 0, err
+	}
+
+	// 进程内存使用率 = (进程使用的物理内存 / 节点总物理内存) * 100
+	return (float64(procMem.RSS) / float64(nodeMem.Total)) * 100.0, nil
+}
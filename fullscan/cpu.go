@@ -0,0 +1,55 @@
+package fullscan
+
+import (
+	"sync"
+	"time"
+)
+
+// cpuSample 一次采集时进程的累计 CPU 时间
+type cpuSample struct {
+	startTime int64 // 进程启动时间（毫秒），PID 被复用时不与旧进程比较
+	cpuTime   float64
+	sampledAt time.Time
+}
+
+// cpuTracker 保存上一次采集的 CPU 时间，用两次采集之间的增量计算使用率
+// proc.CPUPercent() 第一次调用返回的是进程启动以来的平均值，长期运行的进程突然忙起来时几乎看不出变化
+type cpuTracker struct {
+	mu      sync.Mutex
+	samples map[int32]cpuSample
+	// seen 本轮采集中出现过的 PID，采集结束时清理已经退出的进程
+	seen map[int32]struct{}
+}
+
+func newCPUTracker() *cpuTracker {
+	return &cpuTracker{samples: make(map[int32]cpuSample), seen: make(map[int32]struct{})}
+}
+
+// observe 记录本次采样并返回与上次采样之间的使用率（1 表示占满一个核），第一次采样时返回 false
+func (t *cpuTracker) observe(pid int32, sample cpuSample) (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev, ok := t.samples[pid]
+	t.samples[pid] = sample
+	t.seen[pid] = struct{}{}
+	if !ok || prev.startTime != sample.startTime {
+		return 0, false
+	}
+	elapsed := sample.sampledAt.Sub(prev.sampledAt).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return max(sample.cpuTime-prev.cpuTime, 0) / elapsed, true
+}
+
+// sweep 删除本轮采集中没有出现的进程
+func (t *cpuTracker) sweep() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for pid := range t.samples {
+		if _, ok := t.seen[pid]; !ok {
+			delete(t.samples, pid)
+		}
+	}
+	t.seen = make(map[int32]struct{})
+}
@@ -0,0 +1,62 @@
+// Package encoder 将采集到的指标编码为不同的输出格式
+// 同一套采集逻辑可以同时输出给 Prometheus 和其他监控管道
+package encoder
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Encoder 指标编码器
+type Encoder interface {
+	// Encode 将指标写入 w，ts 用于指标本身没有时间戳的样本
+	Encode(w io.Writer, families []*dto.MetricFamily, ts time.Time) error
+	// ContentType 通过 HTTP 输出时使用的 Content-Type
+	ContentType() string
+}
+
+// Formats 支持的输出格式
+var Formats = []string{"prometheus", "influx", "jsonl"}
+
+// New 按格式名称创建编码器
+func New(format string) (Encoder, error) {
+	switch format {
+	case "", "prometheus":
+		return prometheusEncoder{}, nil
+	case "influx":
+		return influxEncoder{}, nil
+	case "jsonl":
+		return jsonLinesEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, supported: %v", format, Formats)
+	}
+}
+
+// prometheusEncoder Prometheus 文本格式
+type prometheusEncoder struct{}
+
+func (prometheusEncoder) Encode(w io.Writer, families []*dto.MetricFamily, _ time.Time) error {
+	enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (prometheusEncoder) ContentType() string {
+	return string(expfmt.NewFormat(expfmt.TypeTextPlain))
+}
+
+// sampleTime 返回样本的时间戳，样本没有时间戳时使用 ts
+func sampleTime(m *dto.Metric, ts time.Time) time.Time {
+	if m.TimestampMs != nil {
+		return time.UnixMilli(m.GetTimestampMs())
+	}
+	return ts
+}
@@ -0,0 +1,86 @@
+package encoder
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// influxEncoder InfluxDB line protocol，与 Telegraf prometheus 输入插件 v1 的格式一致：
+// measurement 为指标名，标签作为 tag；gauge/counter 的值写在 value 字段，
+// histogram/summary 写 sum、count 以及以 le/quantile 为名的字段
+type influxEncoder struct{}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+func (influxEncoder) Encode(w io.Writer, families []*dto.MetricFamily, ts time.Time) error {
+	bw := bufio.NewWriter(w)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			fields := influxFields(mf.GetType(), m)
+			if len(fields) == 0 {
+				continue
+			}
+
+			bw.WriteString(measurementEscaper.Replace(mf.GetName()))
+			for _, lp := range m.GetLabel() {
+				// line protocol 不允许空的 tag 值
+				if lp.GetValue() == "" {
+					continue
+				}
+				bw.WriteByte(',')
+				bw.WriteString(tagEscaper.Replace(lp.GetName()))
+				bw.WriteByte('=')
+				bw.WriteString(tagEscaper.Replace(lp.GetValue()))
+			}
+			bw.WriteByte(' ')
+			bw.WriteString(strings.Join(fields, ","))
+			bw.WriteByte(' ')
+			bw.WriteString(strconv.FormatInt(sampleTime(m, ts).UnixNano(), 10))
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+func (influxEncoder) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+// influxFields 将一个样本转换为 line protocol 的字段列表
+func influxFields(t dto.MetricType, m *dto.Metric) []string {
+	field := func(k string, v float64) string {
+		return tagEscaper.Replace(k) + "=" + strconv.FormatFloat(v, 'g', -1, 64)
+	}
+
+	switch t {
+	case dto.MetricType_GAUGE:
+		return []string{field("value", m.GetGauge().GetValue())}
+	case dto.MetricType_COUNTER:
+		return []string{field("value", m.GetCounter().GetValue())}
+	case dto.MetricType_UNTYPED:
+		return []string{field("value", m.GetUntyped().GetValue())}
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		fields := []string{field("sum", s.GetSampleSum()), field("count", float64(s.GetSampleCount()))}
+		for _, q := range s.GetQuantile() {
+			fields = append(fields, field(strconv.FormatFloat(q.GetQuantile(), 'g', -1, 64), q.GetValue()))
+		}
+		return fields
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		h := m.GetHistogram()
+		fields := []string{field("sum", h.GetSampleSum()), field("count", float64(h.GetSampleCount()))}
+		for _, b := range h.GetBucket() {
+			fields = append(fields, field(strconv.FormatFloat(b.GetUpperBound(), 'g', -1, 64), float64(b.GetCumulativeCount())))
+		}
+		return fields
+	}
+	return nil
+}
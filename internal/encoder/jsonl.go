@@ -0,0 +1,107 @@
+package encoder
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// jsonLinesEncoder 每行一个 JSON 对象，每个样本一行
+type jsonLinesEncoder struct{}
+
+// jsonSample JSON lines 中的一行
+type jsonSample struct {
+	Name      string             `json:"name"`
+	Type      string             `json:"type"`
+	Labels    map[string]string  `json:"labels,omitempty"`
+	Value     *float64           `json:"value,omitempty"`
+	Sum       *float64           `json:"sum,omitempty"`
+	Count     *uint64            `json:"count,omitempty"`
+	Buckets   map[string]uint64  `json:"buckets,omitempty"`
+	Quantiles map[string]float64 `json:"quantiles,omitempty"`
+	Timestamp int64              `json:"timestamp_ms"`
+}
+
+func (jsonLinesEncoder) Encode(w io.Writer, families []*dto.MetricFamily, ts time.Time) error {
+	enc := json.NewEncoder(w)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			s := jsonSample{
+				Name:      mf.GetName(),
+				Type:      jsonType(mf.GetType()),
+				Timestamp: sampleTime(m, ts).UnixMilli(),
+			}
+			if len(m.GetLabel()) > 0 {
+				s.Labels = make(map[string]string, len(m.GetLabel()))
+				for _, lp := range m.GetLabel() {
+					s.Labels[lp.GetName()] = lp.GetValue()
+				}
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_GAUGE:
+				s.Value = jsonFloat(m.GetGauge().GetValue())
+			case dto.MetricType_COUNTER:
+				s.Value = jsonFloat(m.GetCounter().GetValue())
+			case dto.MetricType_UNTYPED:
+				s.Value = jsonFloat(m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				sm := m.GetSummary()
+				count := sm.GetSampleCount()
+				s.Sum, s.Count = jsonFloat(sm.GetSampleSum()), &count
+				s.Quantiles = make(map[string]float64, len(sm.GetQuantile()))
+				for _, q := range sm.GetQuantile() {
+					if !math.IsNaN(q.GetValue()) {
+						s.Quantiles[strconv.FormatFloat(q.GetQuantile(), 'g', -1, 64)] = q.GetValue()
+					}
+				}
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := m.GetHistogram()
+				count := h.GetSampleCount()
+				s.Sum, s.Count = jsonFloat(h.GetSampleSum()), &count
+				s.Buckets = make(map[string]uint64, len(h.GetBucket()))
+				for _, b := range h.GetBucket() {
+					s.Buckets[strconv.FormatFloat(b.GetUpperBound(), 'g', -1, 64)] = b.GetCumulativeCount()
+				}
+			}
+
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (jsonLinesEncoder) ContentType() string {
+	return "application/x-ndjson"
+}
+
+// jsonFloat JSON 无法表示 NaN/Inf，这类值直接省略
+func jsonFloat(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+func jsonType(t dto.MetricType) string {
+	switch t {
+	case dto.MetricType_GAUGE:
+		return "gauge"
+	case dto.MetricType_COUNTER:
+		return "counter"
+	case dto.MetricType_SUMMARY:
+		return "summary"
+	case dto.MetricType_HISTOGRAM:
+		return "histogram"
+	case dto.MetricType_GAUGE_HISTOGRAM:
+		return "gaugehistogram"
+	default:
+		return "untyped"
+	}
+}
@@ -0,0 +1,57 @@
+// Package logging 两个 exporter 共用的 slog 日志配置
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Config -log.level 和 -log.format 的值
+type Config struct {
+	Level  string
+	Format string
+}
+
+// RegisterFlags 注册 -log.level 和 -log.format，flag.Parse 之后调用 Setup
+func RegisterFlags(fs *flag.FlagSet) *Config {
+	c := &Config{}
+	fs.StringVar(&c.Level, "log.level", "info", "Only log messages with the given severity or above. One of: debug, info, warn, error.")
+	fs.StringVar(&c.Format, "log.format", "text", "Output format of log messages. One of: text, json.")
+	return c
+}
+
+// Setup 按配置创建 logger 并设置为 slog 的默认 logger，标准库 log 包的输出也会经过它
+func (c *Config) Setup() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return fmt.Errorf("invalid -log.level %q", c.Level)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(c.Format) {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("invalid -log.format %q, must be text or json", c.Format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// ErrorLogger 以 error 级别输出的标准库 logger，用于 promhttp.HandlerOpts.ErrorLog 等只接受 *log.Logger 的地方
+func ErrorLogger() *log.Logger {
+	return slog.NewLogLogger(slog.Default().Handler(), slog.LevelError)
+}
+
+// Fatal 输出 error 级别日志后退出
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,33 @@
+// Package procfs 拼接 procfs 中文件的路径
+// 根目录与 gopsutil 一致取自 HOST_PROC 环境变量，容器中挂载了宿主机的 /proc 时
+// exporter 自己读取的文件和 gopsutil 读取的文件来自同一个 procfs
+package procfs
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Root procfs 的挂载位置，默认 /proc
+func Root() string {
+	if root := os.Getenv("HOST_PROC"); root != "" {
+		return root
+	}
+	return "/proc"
+}
+
+// SetRoot 修改 procfs 的挂载位置，需要在开始采集前调用
+func SetRoot(root string) error {
+	return os.Setenv("HOST_PROC", root)
+}
+
+// Path 返回 procfs 中的路径，例如 Path("net", "tcp")
+func Path(elem ...string) string {
+	return filepath.Join(append([]string{Root()}, elem...)...)
+}
+
+// PID 返回进程目录下的路径，例如 PID(1, "status") 为 /proc/1/status
+func PID(pid int32, elem ...string) string {
+	return filepath.Join(append([]string{Root(), strconv.Itoa(int(pid))}, elem...)...)
+}
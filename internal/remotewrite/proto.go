@@ -0,0 +1,125 @@
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// WriteRequest 中用到的字段编号，见 prometheus/prompb/remote.proto 和 types.proto
+const (
+	writeRequestTimeseries = 1
+	timeSeriesLabels       = 1
+	timeSeriesSamples      = 2
+	labelName              = 1
+	labelValue             = 2
+	sampleValue            = 1
+	sampleTimestamp        = 2
+)
+
+type label struct {
+	name, value string
+}
+
+// encodeWriteRequest 将指标编码为 prometheus.WriteRequest
+// histogram 和 summary 按文本格式的方式展开为 _bucket/_sum/_count 以及 quantile 序列
+// 样本没有时间戳时使用 ts
+func encodeWriteRequest(families []*dto.MetricFamily, ts time.Time, external map[string]string) []byte {
+	var buf []byte
+	for _, mf := range families {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			at := ts.UnixMilli()
+			if m.TimestampMs != nil {
+				at = m.GetTimestampMs()
+			}
+			base := seriesLabels(m.GetLabel(), external)
+			add := func(suffix string, value float64, extra ...label) {
+				buf = appendTimeSeries(buf, name+suffix, base, extra, value, at)
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add("", q.GetValue(), label{"quantile", formatFloat(q.GetQuantile())})
+				}
+				add("_sum", s.GetSampleSum())
+				add("_count", float64(s.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					add("_bucket", float64(b.GetCumulativeCount()), label{"le", formatFloat(b.GetUpperBound())})
+				}
+				add("_bucket", float64(h.GetSampleCount()), label{"le", "+Inf"})
+				add("_sum", h.GetSampleSum())
+				add("_count", float64(h.GetSampleCount()))
+			}
+		}
+	}
+	return buf
+}
+
+// seriesLabels 合并序列标签和外部标签，同名时以序列标签为准
+func seriesLabels(pairs []*dto.LabelPair, external map[string]string) []label {
+	labels := make([]label, 0, len(pairs)+len(external))
+	own := make(map[string]struct{}, len(pairs))
+	for _, lp := range pairs {
+		labels = append(labels, label{lp.GetName(), lp.GetValue()})
+		own[lp.GetName()] = struct{}{}
+	}
+	for name, value := range external {
+		if _, ok := own[name]; !ok {
+			labels = append(labels, label{name, value})
+		}
+	}
+	return labels
+}
+
+// appendTimeSeries 追加一条只有一个样本的 TimeSeries，标签按名称排序（remote-write 协议要求）
+func appendTimeSeries(buf []byte, name string, base, extra []label, value float64, ts int64) []byte {
+	labels := make([]label, 0, len(base)+len(extra)+1)
+	labels = append(labels, label{"__name__", name})
+	labels = append(labels, base...)
+	labels = append(labels, extra...)
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+
+	var series []byte
+	for _, l := range labels {
+		// 空值标签等同于不存在，不发送
+		if l.value == "" {
+			continue
+		}
+		var lb []byte
+		lb = protowire.AppendTag(lb, labelName, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.name)
+		lb = protowire.AppendTag(lb, labelValue, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.value)
+		series = protowire.AppendTag(series, timeSeriesLabels, protowire.BytesType)
+		series = protowire.AppendBytes(series, lb)
+	}
+	var sample []byte
+	sample = protowire.AppendTag(sample, sampleValue, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, sampleTimestamp, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(ts))
+	series = protowire.AppendTag(series, timeSeriesSamples, protowire.BytesType)
+	series = protowire.AppendBytes(series, sample)
+
+	buf = protowire.AppendTag(buf, writeRequestTimeseries, protowire.BytesType)
+	return protowire.AppendBytes(buf, series)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
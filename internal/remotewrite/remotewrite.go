@@ -0,0 +1,127 @@
+// Package remotewrite 通过 Prometheus remote-write 协议（v1，snappy 压缩的 protobuf）主动推送指标
+// 用于没有本地 Prometheus 的环境，直接推送到 Mimir、VictoriaMetrics 等远端存储
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// Config 推送配置
+type Config struct {
+	URL string
+	// Interval 推送间隔，每次推送前重新采集一次
+	Interval time.Duration
+	// Timeout 单次推送的 HTTP 超时
+	Timeout time.Duration
+	// ExternalLabels 附加在所有序列上的标签，与序列自身的标签同名时以序列为准
+	ExternalLabels map[string]string
+	// Username 不为空时使用 basic auth
+	Username string
+	Password string
+}
+
+// Client remote-write 客户端
+type Client struct {
+	cfg    Config
+	client *http.Client
+}
+
+// New 创建客户端
+func New(cfg Config) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("remote write URL is empty")
+	}
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("remote write interval must be positive")
+	}
+	for name := range cfg.ExternalLabels {
+		if name == "" || name == "__name__" {
+			return nil, fmt.Errorf("invalid external label name %q", name)
+		}
+	}
+	return &Client{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}, nil
+}
+
+// Run 每隔 Interval 从 gatherer 采集一次并推送，直到 ctx 结束
+// 推送失败只记录日志，不重试：下一次推送会带上最新的数据，计数器类指标不会丢失增量
+func (c *Client) Run(ctx context.Context, gatherer prometheus.Gatherer) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		c.pushOnce(ctx, gatherer)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) pushOnce(ctx context.Context, gatherer prometheus.Gatherer) {
+	start := time.Now()
+	families, err := gatherer.Gather()
+	if err != nil {
+		// 与 promhttp.ContinueOnError 一致，记录错误后推送已采集到的部分
+		slog.Error("Error gathering metrics for remote write", "err", err)
+	}
+	req := encodeWriteRequest(families, start, c.cfg.ExternalLabels)
+	if err := c.Push(ctx, req); err != nil {
+		slog.Error("Error pushing metrics via remote write", "url", c.cfg.URL, "err", err)
+		return
+	}
+	slog.Debug("Remote write finished", "bytes", len(req), "duration", time.Since(start))
+}
+
+// Push 发送一个已编码（未压缩）的 WriteRequest
+func (c *Client) Push(ctx context.Context, writeRequest []byte) error {
+	body := snappy.Encode(nil, writeRequest)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Encoding", "snappy")
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	httpReq.Header.Set("User-Agent", "process-exporter")
+	if c.cfg.Username != "" {
+		httpReq.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("server returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// ParseLabels 解析 name=value,name=value 形式的外部标签
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || !model.LabelName(name).IsValid() {
+			return nil, fmt.Errorf("invalid label %q, expected name=value", pair)
+		}
+		labels[name] = value
+	}
+	return labels, nil
+}
@@ -0,0 +1,286 @@
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// fixture up{job="a"} 1 @1000 编码后的 WriteRequest，按 remote.proto 手工展开
+var fixture = []byte{
+	0x0a, 0x28, // timeseries，40 字节
+	0x0a, 0x0e, // labels，14 字节
+	0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_',
+	0x12, 0x02, 'u', 'p',
+	0x0a, 0x08, // labels，8 字节
+	0x0a, 0x03, 'j', 'o', 'b',
+	0x12, 0x01, 'a',
+	0x12, 0x0c, // samples，12 字节
+	0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, // value 1.0
+	0x10, 0xe8, 0x07, // timestamp 1000
+}
+
+func gauge(name string, value float64, labels ...string) *dto.MetricFamily {
+	m := &dto.Metric{Gauge: &dto.Gauge{Value: proto.Float64(value)}}
+	for i := 0; i+1 < len(labels); i += 2 {
+		m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(labels[i]), Value: proto.String(labels[i+1])})
+	}
+	return &dto.MetricFamily{Name: proto.String(name), Type: dto.MetricType_GAUGE.Enum(), Metric: []*dto.Metric{m}}
+}
+
+// decode 把 WriteRequest 解码为 name{label="value",...} value @timestamp 形式的字符串，便于比较
+func decode(t *testing.T, b []byte) []string {
+	t.Helper()
+	var series []string
+	for len(b) > 0 {
+		ts := consumeBytes(t, &b, writeRequestTimeseries)
+		var labels []string
+		var sample string
+		for len(ts) > 0 {
+			num, _, _ := protowire.ConsumeTag(ts)
+			switch num {
+			case timeSeriesLabels:
+				lb := consumeBytes(t, &ts, timeSeriesLabels)
+				name := string(consumeBytes(t, &lb, labelName))
+				value := string(consumeBytes(t, &lb, labelValue))
+				labels = append(labels, fmt.Sprintf("%s=%q", name, value))
+			case timeSeriesSamples:
+				s := consumeBytes(t, &ts, timeSeriesSamples)
+				s = s[1:] // sampleValue 的 tag
+				v, n := protowire.ConsumeFixed64(s)
+				s = s[n+1:] // sampleTimestamp 的 tag
+				at, _ := protowire.ConsumeVarint(s)
+				sample = fmt.Sprintf("%s @%d", formatFloat(math.Float64frombits(v)), int64(at))
+			default:
+				t.Fatalf("unexpected field %d in TimeSeries", num)
+			}
+		}
+		series = append(series, fmt.Sprintf("{%s} %s", strings.Join(labels, ","), sample))
+	}
+	return series
+}
+
+func consumeBytes(t *testing.T, b *[]byte, want protowire.Number) []byte {
+	t.Helper()
+	num, typ, n := protowire.ConsumeTag(*b)
+	if n < 0 || num != want || typ != protowire.BytesType {
+		t.Fatalf("field = %d (type %d), want %d", num, typ, want)
+	}
+	v, m := protowire.ConsumeBytes((*b)[n:])
+	if m < 0 {
+		t.Fatalf("invalid length of field %d", want)
+	}
+	*b = (*b)[n+m:]
+	return v
+}
+
+func TestEncodeWriteRequestFixture(t *testing.T) {
+	got := encodeWriteRequest([]*dto.MetricFamily{gauge("up", 1, "job", "a")}, time.UnixMilli(1000), nil)
+	if !bytes.Equal(got, fixture) {
+		t.Errorf("encodeWriteRequest() = % x\nwant % x", got, fixture)
+	}
+}
+
+func TestEncodeWriteRequest(t *testing.T) {
+	ts := time.UnixMilli(1000)
+	tests := []struct {
+		name     string
+		families []*dto.MetricFamily
+		external map[string]string
+		want     []string
+	}{
+		{
+			name:     "labels sorted by name",
+			families: []*dto.MetricFamily{gauge("process_up", 1, "process_name", "nginx", "group", "web")},
+			want:     []string{`{__name__="process_up",group="web",process_name="nginx"} 1 @1000`},
+		},
+		{
+			name:     "external labels added",
+			families: []*dto.MetricFamily{gauge("up", 1)},
+			external: map[string]string{"cluster": "prod"},
+			want:     []string{`{__name__="up",cluster="prod"} 1 @1000`},
+		},
+		{
+			name:     "series label wins over external label",
+			families: []*dto.MetricFamily{gauge("up", 1, "cluster", "dev")},
+			external: map[string]string{"cluster": "prod"},
+			want:     []string{`{__name__="up",cluster="dev"} 1 @1000`},
+		},
+		{
+			name:     "empty label values dropped",
+			families: []*dto.MetricFamily{gauge("up", 1, "job", "")},
+			want:     []string{`{__name__="up"} 1 @1000`},
+		},
+		{
+			name: "sample timestamp kept",
+			families: []*dto.MetricFamily{{
+				Name:   proto.String("up"),
+				Type:   dto.MetricType_COUNTER.Enum(),
+				Metric: []*dto.Metric{{Counter: &dto.Counter{Value: proto.Float64(2)}, TimestampMs: proto.Int64(500)}},
+			}},
+			want: []string{`{__name__="up"} 2 @500`},
+		},
+		{
+			name: "summary expanded",
+			families: []*dto.MetricFamily{{
+				Name: proto.String("latency"),
+				Type: dto.MetricType_SUMMARY.Enum(),
+				Metric: []*dto.Metric{{Summary: &dto.Summary{
+					SampleCount: proto.Uint64(3),
+					SampleSum:   proto.Float64(1.5),
+					Quantile:    []*dto.Quantile{{Quantile: proto.Float64(0.5), Value: proto.Float64(0.25)}},
+				}}},
+			}},
+			want: []string{
+				`{__name__="latency",quantile="0.5"} 0.25 @1000`,
+				`{__name__="latency_sum"} 1.5 @1000`,
+				`{__name__="latency_count"} 3 @1000`,
+			},
+		},
+		{
+			name: "histogram expanded",
+			families: []*dto.MetricFamily{{
+				Name: proto.String("latency"),
+				Type: dto.MetricType_HISTOGRAM.Enum(),
+				Metric: []*dto.Metric{{Histogram: &dto.Histogram{
+					SampleCount: proto.Uint64(3),
+					SampleSum:   proto.Float64(1.5),
+					Bucket:      []*dto.Bucket{{UpperBound: proto.Float64(0.1), CumulativeCount: proto.Uint64(1)}},
+				}}},
+			}},
+			want: []string{
+				`{__name__="latency_bucket",le="0.1"} 1 @1000`,
+				`{__name__="latency_bucket",le="+Inf"} 3 @1000`,
+				`{__name__="latency_sum"} 1.5 @1000`,
+				`{__name__="latency_count"} 3 @1000`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decode(t, encodeWriteRequest(tt.families, ts, tt.external))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("encodeWriteRequest() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestPush(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c, err := New(Config{URL: server.URL, Interval: time.Minute, Username: "user", Password: "pass"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := c.Push(context.Background(), fixture); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	decoded, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatalf("body is not snappy encoded: %v", err)
+	}
+	if !bytes.Equal(decoded, fixture) {
+		t.Errorf("decoded body = % x, want % x", decoded, fixture)
+	}
+	for name, want := range map[string]string{
+		"Content-Encoding":                  "snappy",
+		"Content-Type":                      "application/x-protobuf",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if !strings.HasPrefix(header.Get("Authorization"), "Basic ") {
+		t.Errorf("Authorization = %q, want basic auth", header.Get("Authorization"))
+	}
+}
+
+func TestPushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	c, err := New(Config{URL: server.URL, Interval: time.Minute})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	err = c.Push(context.Background(), fixture)
+	if err == nil || !strings.Contains(err.Error(), "out of order sample") {
+		t.Errorf("Push() error = %v, want the server's message", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"valid", Config{URL: "http://localhost/api/v1/push", Interval: time.Minute}, false},
+		{"empty URL", Config{Interval: time.Minute}, true},
+		{"zero interval", Config{URL: "http://localhost/api/v1/push"}, true},
+		{"empty external label name", Config{URL: "http://localhost/api/v1/push", Interval: time.Minute, ExternalLabels: map[string]string{"": "a"}}, true},
+		{"external __name__", Config{URL: "http://localhost/api/v1/push", Interval: time.Minute, ExternalLabels: map[string]string{"__name__": "a"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", input: "", want: map[string]string{}},
+		{name: "single", input: "cluster=prod", want: map[string]string{"cluster": "prod"}},
+		{name: "multiple", input: "cluster=prod,region=cn-east", want: map[string]string{"cluster": "prod", "region": "cn-east"}},
+		{name: "spaces and empty pairs", input: " cluster=prod , ,region=cn ", want: map[string]string{"cluster": "prod", "region": "cn"}},
+		{name: "empty value", input: "cluster=", want: map[string]string{"cluster": ""}},
+		{name: "value with equals sign", input: "query=a=b", want: map[string]string{"query": "a=b"}},
+		{name: "missing equals sign", input: "cluster", wantErr: true},
+		{name: "empty name", input: "=prod", wantErr: true},
+		{name: "invalid name", input: "\xff=prod", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLabels(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLabels(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLabels(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+// Package sysfs 拼接 sysfs 中文件的路径
+// 根目录与 gopsutil 一致取自 HOST_SYS 环境变量，容器中挂载了宿主机的 /sys 时
+// exporter 自己读取的 cpufreq、cgroup 文件和 gopsutil 读取的文件来自同一个 sysfs
+package sysfs
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Root sysfs 的挂载位置，默认 /sys
+func Root() string {
+	if root := os.Getenv("HOST_SYS"); root != "" {
+		return root
+	}
+	return "/sys"
+}
+
+// SetRoot 修改 sysfs 的挂载位置，需要在开始采集前调用
+func SetRoot(root string) error {
+	return os.Setenv("HOST_SYS", root)
+}
+
+// Path 返回 sysfs 中的路径，例如 Path("fs", "cgroup") 为 /sys/fs/cgroup
+func Path(elem ...string) string {
+	return filepath.Join(append([]string{Root()}, elem...)...)
+}
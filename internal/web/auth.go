@@ -0,0 +1,172 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BearerTokenEnv 未在配置文件中设置 token 时，从该环境变量读取
+const BearerTokenEnv = "PROCESS_EXPORTER_BEARER_TOKEN"
+
+// HeaderToken 校验自定义请求头，例如 Prometheus 按 job 配置的 X-Scrape-Token
+type HeaderToken struct {
+	Job       string `yaml:"job"`
+	Header    string `yaml:"header"`
+	Token     string `yaml:"token"`
+	TokenFile string `yaml:"token_file"`
+}
+
+// authHandler 对请求做 basic auth / bearer token / 自定义请求头校验
+// 配置了多种方式时，任意一种通过即可访问
+type authHandler struct {
+	users        map[string]string // 用户名 -> bcrypt 哈希
+	bearerToken  string
+	headerTokens []HeaderToken // token_file 已在加载时读入 Token
+	handler      http.Handler
+
+	// dummyHash 用户名不存在时用来比较的哈希，与配置的哈希开销相同，避免按响应时间区分用户名是否存在
+	dummyHash []byte
+
+	// bcrypt 校验很慢，缓存已校验通过的凭据，key 为 sha256(user:password)
+	verified sync.Map
+}
+
+// bearerToken 按 bearer_token > bearer_token_file > 环境变量的顺序取 token
+func (c *Config) bearerToken() (string, error) {
+	if c.BearerToken != "" {
+		return c.BearerToken, nil
+	}
+	if c.BearerTokenFile != "" {
+		b, err := os.ReadFile(c.BearerTokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return os.Getenv(BearerTokenEnv), nil
+}
+
+// withAuth 返回加上认证的 handler，没有配置任何认证时原样返回
+func (c *Config) withAuth(handler http.Handler) (http.Handler, error) {
+	token, err := c.bearerToken()
+	if err != nil {
+		return nil, err
+	}
+
+	headerTokens := make([]HeaderToken, 0, len(c.HeaderTokens))
+	for _, ht := range c.HeaderTokens {
+		if ht.Header == "" {
+			return nil, fmt.Errorf("header_tokens: job %q has no header", ht.Job)
+		}
+		if ht.Token == "" && ht.TokenFile != "" {
+			b, err := os.ReadFile(ht.TokenFile)
+			if err != nil {
+				return nil, err
+			}
+			ht.Token = strings.TrimSpace(string(b))
+		}
+		if ht.Token == "" {
+			return nil, fmt.Errorf("header_tokens: job %q has no token", ht.Job)
+		}
+		headerTokens = append(headerTokens, ht)
+	}
+
+	if len(c.BasicAuthUsers) == 0 && token == "" && len(headerTokens) == 0 {
+		return handler, nil
+	}
+	h := &authHandler{
+		users:        c.BasicAuthUsers,
+		bearerToken:  token,
+		headerTokens: headerTokens,
+		handler:      handler,
+	}
+	if len(c.BasicAuthUsers) > 0 {
+		if h.dummyHash, err = dummyHash(c.BasicAuthUsers); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// dummyHash 生成一个随机密码的 bcrypt 哈希，开销取配置中最大的，无法解析的哈希按默认开销
+func dummyHash(users map[string]string) ([]byte, error) {
+	cost := 0
+	for _, hashed := range users {
+		if c, err := bcrypt.Cost([]byte(hashed)); err == nil && c > cost {
+			cost = c
+		}
+	}
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	password := make([]byte, 16)
+	if _, err := rand.Read(password); err != nil {
+		return nil, err
+	}
+	return bcrypt.GenerateFromPassword(password, cost)
+}
+
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.authorized(r) {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	if len(h.users) > 0 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="process-exporter"`)
+	} else {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+func (h *authHandler) authorized(r *http.Request) bool {
+	if h.bearerToken != "" {
+		auth := r.Header.Get("Authorization")
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(h.bearerToken)) == 1 {
+				return true
+			}
+		}
+	}
+
+	for _, ht := range h.headerTokens {
+		if v := r.Header.Get(ht.Header); v != "" {
+			if subtle.ConstantTimeCompare([]byte(v), []byte(ht.Token)) == 1 {
+				return true
+			}
+		}
+	}
+
+	if len(h.users) > 0 {
+		user, pass, ok := r.BasicAuth()
+		if !ok {
+			return false
+		}
+		hashed, ok := h.users[user]
+		if !ok {
+			// 用户名不存在时同样做一次 bcrypt 比较，失败的耗时与密码错误一致
+			bcrypt.CompareHashAndPassword(h.dummyHash, []byte(pass))
+			return false
+		}
+
+		key := sha256.Sum256([]byte(user + ":" + pass))
+		if _, ok := h.verified.Load(key); ok {
+			return true
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hashed), []byte(pass)) == nil {
+			h.verified.Store(key, struct{}{})
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,191 @@
+package web
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// okHandler 认证通过后调用的处理器
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+func hashPassword(t *testing.T, pass string) string {
+	t.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	return string(hashed)
+}
+
+// request 构造一个抓取请求，header 为需要设置的请求头
+func request(header map[string]string, user, pass string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+	if user != "" {
+		r.SetBasicAuth(user, pass)
+	}
+	return r
+}
+
+func TestAuth(t *testing.T) {
+	t.Setenv(BearerTokenEnv, "")
+	users := map[string]string{"prometheus": hashPassword(t, "secret")}
+	tokenFile := writeFile(t, "token", "file-token\n")
+
+	tests := []struct {
+		name       string
+		config     Config
+		header     map[string]string
+		user, pass string
+		wantStatus int
+		wantAuth   string
+	}{
+		{"no auth configured", Config{}, nil, "", "", http.StatusOK, ""},
+		{"basic auth accepted", Config{BasicAuthUsers: users}, nil, "prometheus", "secret", http.StatusOK, ""},
+		{"basic auth wrong password", Config{BasicAuthUsers: users}, nil, "prometheus", "wrong", http.StatusUnauthorized, `Basic realm="process-exporter"`},
+		{"basic auth unknown user", Config{BasicAuthUsers: users}, nil, "admin", "secret", http.StatusUnauthorized, `Basic realm="process-exporter"`},
+		{"basic auth missing credentials", Config{BasicAuthUsers: users}, nil, "", "", http.StatusUnauthorized, `Basic realm="process-exporter"`},
+		{"bearer token accepted", Config{BearerToken: "token"}, map[string]string{"Authorization": "Bearer token"}, "", "", http.StatusOK, ""},
+		{"bearer token rejected", Config{BearerToken: "token"}, map[string]string{"Authorization": "Bearer other"}, "", "", http.StatusUnauthorized, "Bearer"},
+		{"bearer token requires scheme", Config{BearerToken: "token"}, map[string]string{"Authorization": "token"}, "", "", http.StatusUnauthorized, "Bearer"},
+		{"bearer token from file is trimmed", Config{BearerTokenFile: tokenFile}, map[string]string{"Authorization": "Bearer file-token"}, "", "", http.StatusOK, ""},
+		{"bearer token before basic auth", Config{BasicAuthUsers: users, BearerToken: "token"}, map[string]string{"Authorization": "Bearer token"}, "", "", http.StatusOK, ""},
+		{"basic auth alongside bearer token", Config{BasicAuthUsers: users, BearerToken: "token"}, nil, "prometheus", "secret", http.StatusOK, ""},
+		{"header token accepted", Config{HeaderTokens: []HeaderToken{{Job: "a", Header: "X-Scrape-Token", Token: "a-token"}}}, map[string]string{"X-Scrape-Token": "a-token"}, "", "", http.StatusOK, ""},
+		{"header token of another job", Config{HeaderTokens: []HeaderToken{{Job: "a", Header: "X-Scrape-Token", Token: "a-token"}, {Job: "b", Header: "X-Scrape-Token", Token: "b-token"}}}, map[string]string{"X-Scrape-Token": "b-token"}, "", "", http.StatusOK, ""},
+		{"header token rejected", Config{HeaderTokens: []HeaderToken{{Job: "a", Header: "X-Scrape-Token", Token: "a-token"}}}, map[string]string{"X-Scrape-Token": "other"}, "", "", http.StatusUnauthorized, "Bearer"},
+		{"header token in wrong header", Config{HeaderTokens: []HeaderToken{{Job: "a", Header: "X-Scrape-Token", Token: "a-token"}}}, map[string]string{"X-Other-Token": "a-token"}, "", "", http.StatusUnauthorized, "Bearer"},
+		{"header token from file", Config{HeaderTokens: []HeaderToken{{Job: "a", Header: "X-Scrape-Token", TokenFile: tokenFile}}}, map[string]string{"X-Scrape-Token": "file-token"}, "", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := tt.config.withAuth(okHandler)
+			if err != nil {
+				t.Fatalf("withAuth() error = %v", err)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request(tt.header, tt.user, tt.pass))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantAuth {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestAuthBearerTokenEnv(t *testing.T) {
+	t.Setenv(BearerTokenEnv, "env-token")
+	tests := []struct {
+		name       string
+		config     Config
+		token      string
+		wantStatus int
+	}{
+		{"env token accepted", Config{}, "env-token", http.StatusOK},
+		{"env token rejected", Config{}, "other", http.StatusUnauthorized},
+		{"config token overrides env", Config{BearerToken: "token"}, "env-token", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := tt.config.withAuth(okHandler)
+			if err != nil {
+				t.Fatalf("withAuth() error = %v", err)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request(map[string]string{"Authorization": "Bearer " + tt.token}, "", ""))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWithAuthErrors(t *testing.T) {
+	t.Setenv(BearerTokenEnv, "")
+	missing := filepath.Join(t.TempDir(), "missing")
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"missing bearer token file", Config{BearerTokenFile: missing}},
+		{"header token without header", Config{HeaderTokens: []HeaderToken{{Job: "a", Token: "a-token"}}}},
+		{"header token without token", Config{HeaderTokens: []HeaderToken{{Job: "a", Header: "X-Scrape-Token"}}}},
+		{"header token with empty file", Config{HeaderTokens: []HeaderToken{{Job: "a", Header: "X-Scrape-Token", TokenFile: writeFile(t, "empty", "\n")}}}},
+		{"missing header token file", Config{HeaderTokens: []HeaderToken{{Job: "a", Header: "X-Scrape-Token", TokenFile: missing}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.config.withAuth(okHandler); err == nil {
+				t.Error("withAuth() error = nil, want error")
+			}
+		})
+	}
+}
+
+// 验证通过的用户名和密码缓存 sha256，之后的请求不再计算 bcrypt；验证失败的不缓存
+func TestBasicAuthCache(t *testing.T) {
+	t.Setenv(BearerTokenEnv, "")
+	c := Config{BasicAuthUsers: map[string]string{"prometheus": hashPassword(t, "secret")}}
+	handler, err := c.withAuth(okHandler)
+	if err != nil {
+		t.Fatalf("withAuth() error = %v", err)
+	}
+	h := handler.(*authHandler)
+
+	if h.authorized(request(nil, "prometheus", "wrong")) {
+		t.Fatal("wrong password authorized")
+	}
+	if _, ok := h.verified.Load(sha256.Sum256([]byte("prometheus:wrong"))); ok {
+		t.Error("rejected credentials are cached")
+	}
+	if !h.authorized(request(nil, "prometheus", "secret")) {
+		t.Fatal("correct password rejected")
+	}
+	if _, ok := h.verified.Load(sha256.Sum256([]byte("prometheus:secret"))); !ok {
+		t.Fatal("accepted credentials are not cached")
+	}
+
+	// 换成无效的哈希后仍然通过，说明命中了缓存而不是重新比较 bcrypt
+	h.users["prometheus"] = "invalid"
+	if !h.authorized(request(nil, "prometheus", "secret")) {
+		t.Error("cached credentials rejected")
+	}
+	if h.authorized(request(nil, "prometheus", "wrong")) {
+		t.Error("wrong password authorized after caching")
+	}
+}
+
+// 用户名不存在时与哈希开销相同的 dummyHash 比较，开销取配置中最大的
+func TestDummyHashCost(t *testing.T) {
+	tests := []struct {
+		name  string
+		users map[string]string
+		want  int
+	}{
+		{"configured cost", map[string]string{"prometheus": hashPassword(t, "secret")}, bcrypt.MinCost},
+		{"highest configured cost", map[string]string{"a": hashPassword(t, "a"), "b": "$2a$05$" + hashPassword(t, "b")[7:]}, bcrypt.MinCost + 1},
+		{"invalid hashes use default cost", map[string]string{"prometheus": "invalid"}, bcrypt.DefaultCost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hashed, err := dummyHash(tt.users)
+			if err != nil {
+				t.Fatalf("dummyHash() error = %v", err)
+			}
+			if cost, _ := bcrypt.Cost(hashed); cost != tt.want {
+				t.Errorf("cost = %d, want %d", cost, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// unixPrefix 监听地址使用该前缀时监听 Unix domain socket，例如 unix:///run/process-exporter.sock
+const unixPrefix = "unix://"
+
+// unixSocketMode socket 文件的权限，属组中的反向代理或 sidecar 可以连接
+const unixSocketMode = 0o660
+
+// Listen 按地址创建监听，支持 host:port 形式的 TCP 地址和 unix:// 开头的 socket 路径
+// socket 文件已存在时（上次异常退出遗留）先删除，路径上已有其他类型的文件时返回错误
+func Listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("empty unix socket path in %q", addr)
+	}
+
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// listenFDsStart systemd 传入的第一个 socket 的文件描述符，见 sd_listen_fds(3)
+const listenFDsStart = 3
+
+// SystemdListeners 返回 systemd socket activation 传入的监听 socket
+// LISTEN_PID 不是当前进程或没有传入 socket 时返回错误；读取后清除相关环境变量，避免被子进程继承
+func SystemdListeners() ([]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("no sockets passed by systemd (LISTEN_PID is not set to this process)")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, errors.New("no sockets passed by systemd (LISTEN_FDS is empty)")
+	}
+
+	listeners := make([]net.Listener, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), fmt.Sprintf("LISTEN_FD_%d", fd))
+		ln, err := net.FileListener(f)
+		// FileListener 复制了文件描述符，原来的可以关闭
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("socket %d passed by systemd: %w", fd, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
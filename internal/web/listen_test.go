@@ -0,0 +1,116 @@
+//go:build !windows
+
+package web
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, path string)
+		wantErr bool
+	}{
+		{name: "new socket", setup: func(*testing.T, string) {}},
+		{
+			name: "stale socket is replaced",
+			setup: func(t *testing.T, path string) {
+				ln, err := net.Listen("unix", path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// 关闭时不删除 socket 文件，模拟上次异常退出遗留的 socket
+				ln.(*net.UnixListener).SetUnlinkOnClose(false)
+				ln.Close()
+			},
+		},
+		{
+			name: "regular file is kept",
+			setup: func(t *testing.T, path string) {
+				if err := os.WriteFile(path, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "exporter.sock")
+			tt.setup(t, path)
+
+			ln, err := Listen(unixPrefix + path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Listen() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if fi, err := os.Lstat(path); err != nil || !fi.Mode().IsRegular() {
+					t.Errorf("existing file was removed or replaced")
+				}
+				return
+			}
+			defer ln.Close()
+
+			if ln.Addr().Network() != "unix" {
+				t.Errorf("network = %s, want unix", ln.Addr().Network())
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if perm := fi.Mode().Perm(); perm != unixSocketMode {
+				t.Errorf("socket mode = %o, want %o", perm, unixSocketMode)
+			}
+
+			// 确认可以连接
+			done := make(chan error, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err == nil {
+					conn.Close()
+				}
+				done <- err
+			}()
+			conn, err := net.Dial("unix", path)
+			if err != nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			conn.Close()
+			if err := <-done; err != nil {
+				t.Errorf("Accept() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	tests := []struct {
+		name        string
+		addr        string
+		wantNetwork string
+		wantErr     bool
+	}{
+		{name: "tcp", addr: "127.0.0.1:0", wantNetwork: "tcp"},
+		{name: "empty unix path", addr: unixPrefix, wantErr: true},
+		{name: "unix socket in missing directory", addr: unixPrefix + "/nonexistent/exporter.sock", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := Listen(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Listen(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer ln.Close()
+			if ln.Addr().Network() != tt.wantNetwork {
+				t.Errorf("network = %s, want %s", ln.Addr().Network(), tt.wantNetwork)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ServeUntilSignal 与 ListenAndServe 相同，但收到 SIGTERM 或 SIGINT 时优雅退出：
+// 先调用 stop（可以为 nil，用于停止后台刷新等任务），再通过 server.Shutdown 停止接受新连接，
+// 最多等待 timeout 让进行中的抓取完成，避免滚动重启时 Prometheus 拿到被截断的响应
+// 正常退出时返回 nil，超时仍有未完成的请求时返回错误
+func ServeUntilSignal(server *http.Server, configPath string, systemdSocket bool, timeout time.Duration, stop func()) error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sig)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ListenAndServe(server, configPath, systemdSocket)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case s := <-sig:
+		slog.Info("Shutting down", "signal", s, "timeout", timeout)
+	}
+
+	if stop != nil {
+		stop()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("waiting for in-flight requests: %w", err)
+	}
+	return nil
+}
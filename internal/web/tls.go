@@ -0,0 +1,177 @@
+// Package web 提供两个 exporter 共用的 HTTP 服务能力，例如 TLS 配置
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// TLSConfig 对应 web 配置文件中的 tls_server_config 段
+type TLSConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ClientAuthType string `yaml:"client_auth_type"`
+	ClientCAFile   string `yaml:"client_ca_file"`
+	MinVersion     string `yaml:"min_version"`
+}
+
+// Config 是 --web.config.file 指向的 YAML 文件结构
+type Config struct {
+	TLSConfig TLSConfig `yaml:"tls_server_config"`
+
+	// BasicAuthUsers 用户名 -> bcrypt 哈希后的密码
+	BasicAuthUsers  map[string]string `yaml:"basic_auth_users"`
+	BearerToken     string            `yaml:"bearer_token"`
+	BearerTokenFile string            `yaml:"bearer_token_file"`
+
+	// HeaderTokens 每个租户/抓取任务各自配置的请求头 token
+	HeaderTokens []HeaderToken `yaml:"header_tokens"`
+}
+
+// LoadConfig 读取并解析 web 配置文件
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(content, c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// enabled 只要配置了证书就认为需要启用 TLS
+func (t *TLSConfig) enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// build 根据配置生成 *tls.Config
+func (t *TLSConfig) build() (*tls.Config, error) {
+	if t.CertFile == "" {
+		return nil, errors.New("missing cert_file")
+	}
+	if t.KeyFile == "" {
+		return nil, errors.New("missing key_file")
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load key pair: %w", err)
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	switch t.MinVersion {
+	case "", "TLS12":
+	case "TLS13":
+		cfg.MinVersion = tls.VersionTLS13
+	case "TLS11":
+		cfg.MinVersion = tls.VersionTLS11
+	case "TLS10":
+		cfg.MinVersion = tls.VersionTLS10
+	default:
+		return nil, fmt.Errorf("unknown min_version: %s", t.MinVersion)
+	}
+
+	switch t.ClientAuthType {
+	case "", "NoClientCert":
+		cfg.ClientAuth = tls.NoClientCert
+	case "RequestClientCert":
+		cfg.ClientAuth = tls.RequestClientCert
+	case "RequireAnyClientCert":
+		cfg.ClientAuth = tls.RequireAnyClientCert
+	case "VerifyClientCertIfGiven":
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case "RequireAndVerifyClientCert":
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client_auth_type: %s", t.ClientAuthType)
+	}
+
+	if t.ClientCAFile != "" {
+		pem, err := os.ReadFile(t.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client_ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", t.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+	} else if cfg.ClientAuth == tls.VerifyClientCertIfGiven || cfg.ClientAuth == tls.RequireAndVerifyClientCert {
+		return nil, errors.New("client_ca_file is required to verify client certificates")
+	}
+
+	return cfg, nil
+}
+
+// ListenAndServe 按照 web 配置启动 HTTP 服务
+// configPath 为空时只检查环境变量中的 bearer token，其余等同于 server.ListenAndServe()
+// server.Addr 可以是 unix:// 开头的 socket 路径，见 Listen
+// systemdSocket 为 true 时使用 systemd socket activation 传入的 socket，忽略 server.Addr
+func ListenAndServe(server *http.Server, configPath string, systemdSocket bool) error {
+	c := &Config{}
+	if configPath != "" {
+		var err error
+		if c, err = LoadConfig(configPath); err != nil {
+			return err
+		}
+	}
+
+	handler := server.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	handler, err := c.withAuth(handler)
+	if err != nil {
+		return err
+	}
+	server.Handler = handler
+
+	var tlsConfig *tls.Config
+	if c.TLSConfig.enabled() {
+		if tlsConfig, err = c.TLSConfig.build(); err != nil {
+			return err
+		}
+	}
+
+	var listeners []net.Listener
+	if systemdSocket {
+		if listeners, err = SystemdListeners(); err != nil {
+			return err
+		}
+	} else {
+		ln, err := Listen(server.Addr)
+		if err != nil {
+			return err
+		}
+		listeners = []net.Listener{ln}
+	}
+	server.TLSConfig = tlsConfig
+
+	// systemd 可能传入多个 socket（例如同时监听 IPv4 和 IPv6），每个 socket 一个 goroutine，任意一个出错即返回
+	errs := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		go func() {
+			if tlsConfig == nil {
+				errs <- server.Serve(ln)
+				return
+			}
+			// 证书已经加载到 TLSConfig 中，这里无需再传文件路径
+			errs <- server.ServeTLS(ln, "", "")
+		}()
+	}
+	return <-errs
+}
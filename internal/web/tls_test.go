@@ -0,0 +1,166 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFile 在临时目录中写入测试文件，返回其路径
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// selfSignedCert 生成自签名证书和私钥，返回两者的文件路径
+func selfSignedCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "process-exporter"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = writeFile(t, "cert.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	keyFile = writeFile(t, "key.pem", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	return certFile, keyFile
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+		check   func(t *testing.T, c *Config)
+	}{
+		{
+			name: "full config",
+			content: `tls_server_config:
+  cert_file: /etc/cert.pem
+  key_file: /etc/key.pem
+  client_auth_type: RequireAndVerifyClientCert
+  client_ca_file: /etc/ca.pem
+  min_version: TLS13
+basic_auth_users:
+  prometheus: $2y$10$hash
+bearer_token_file: /etc/token
+header_tokens:
+  - job: node
+    header: X-Scrape-Token
+    token_file: /etc/node-token
+`,
+			check: func(t *testing.T, c *Config) {
+				want := TLSConfig{CertFile: "/etc/cert.pem", KeyFile: "/etc/key.pem", ClientAuthType: "RequireAndVerifyClientCert", ClientCAFile: "/etc/ca.pem", MinVersion: "TLS13"}
+				if c.TLSConfig != want {
+					t.Errorf("TLSConfig = %+v, want %+v", c.TLSConfig, want)
+				}
+				if c.BasicAuthUsers["prometheus"] != "$2y$10$hash" {
+					t.Errorf("BasicAuthUsers = %v", c.BasicAuthUsers)
+				}
+				if c.BearerTokenFile != "/etc/token" {
+					t.Errorf("BearerTokenFile = %q", c.BearerTokenFile)
+				}
+				if len(c.HeaderTokens) != 1 || c.HeaderTokens[0] != (HeaderToken{Job: "node", Header: "X-Scrape-Token", TokenFile: "/etc/node-token"}) {
+					t.Errorf("HeaderTokens = %+v", c.HeaderTokens)
+				}
+			},
+		},
+		{
+			name:    "empty file",
+			content: "",
+			check: func(t *testing.T, c *Config) {
+				if c.TLSConfig.enabled() {
+					t.Error("TLS enabled without cert_file")
+				}
+			},
+		},
+		{name: "unknown field", content: "tls_server_config:\n  cert: /etc/cert.pem\n", wantErr: true},
+		{name: "invalid yaml", content: "basic_auth_users: [", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := LoadConfig(writeFile(t, "web.yml", tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, c)
+			}
+		})
+	}
+
+	if _, err := LoadConfig(writeFile(t, "web.yml", "") + ".missing"); err == nil {
+		t.Error("LoadConfig() of a missing file error = nil, want error")
+	}
+}
+
+func TestTLSConfigBuild(t *testing.T) {
+	certFile, keyFile := selfSignedCert(t)
+	tests := []struct {
+		name           string
+		config         TLSConfig
+		wantErr        bool
+		wantMinVersion uint16
+		wantClientAuth tls.ClientAuthType
+	}{
+		{name: "defaults", config: TLSConfig{CertFile: certFile, KeyFile: keyFile}, wantMinVersion: tls.VersionTLS12, wantClientAuth: tls.NoClientCert},
+		{name: "min version TLS13", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "TLS13"}, wantMinVersion: tls.VersionTLS13, wantClientAuth: tls.NoClientCert},
+		{name: "request client cert", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuthType: "RequestClientCert"}, wantMinVersion: tls.VersionTLS12, wantClientAuth: tls.RequestClientCert},
+		{name: "verify client cert with CA", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuthType: "RequireAndVerifyClientCert", ClientCAFile: certFile}, wantMinVersion: tls.VersionTLS12, wantClientAuth: tls.RequireAndVerifyClientCert},
+		{name: "verify client cert without CA", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuthType: "VerifyClientCertIfGiven"}, wantErr: true},
+		{name: "client CA without certificates", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: writeFile(t, "ca.pem", "not a certificate")}, wantErr: true},
+		{name: "missing cert_file", config: TLSConfig{KeyFile: keyFile}, wantErr: true},
+		{name: "missing key_file", config: TLSConfig{CertFile: certFile}, wantErr: true},
+		{name: "mismatched key pair", config: TLSConfig{CertFile: keyFile, KeyFile: certFile}, wantErr: true},
+		{name: "unknown min_version", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "SSL3"}, wantErr: true},
+		{name: "unknown client_auth_type", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuthType: "Always"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.config.build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.MinVersion != tt.wantMinVersion {
+				t.Errorf("MinVersion = %x, want %x", cfg.MinVersion, tt.wantMinVersion)
+			}
+			if cfg.ClientAuth != tt.wantClientAuth {
+				t.Errorf("ClientAuth = %v, want %v", cfg.ClientAuth, tt.wantClientAuth)
+			}
+			if len(cfg.Certificates) != 1 {
+				t.Errorf("Certificates = %d, want 1", len(cfg.Certificates))
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package matcher
+
+import (
+	"os"
+	"strings"
+
+	"process-exporter/internal/procfs"
+)
+
+// ReadCgroups 读取 /proc/pid/cgroup，返回进程所在的各个 cgroup 路径
+// 每行格式为 hierarchy-ID:controller-list:cgroup-path，cgroup v2 只有一行 0::/path
+func ReadCgroups(pid int32) ([]string, error) {
+	content, err := os.ReadFile(procfs.PID(pid, "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) == 3 {
+			paths = append(paths, parts[2])
+		}
+	}
+	return paths, nil
+}
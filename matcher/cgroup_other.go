@@ -0,0 +1,10 @@
+//go:build !linux
+
+package matcher
+
+import "errors"
+
+// ReadCgroups 只在 Linux 上可用
+func ReadCgroups(pid int32) ([]string, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,117 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RuleConfig 配置文件中的匹配规则，字段含义与 Rule 相同
+//
+//	match:
+//	  any:
+//	    - exe: /usr/bin/python3
+//	      cmdline: "worker\\.py"
+//	    - name: celery
+//	      user: app
+//	  exclude:
+//	    - cmdline: "flower"
+type RuleConfig struct {
+	Name string `yaml:"name"`
+	// NameMode name 的匹配方式：exact、prefix、substring 或 regex，未设置时使用 Compile 的默认值
+	NameMode string       `yaml:"name_mode"`
+	Exe      string       `yaml:"exe"`
+	Cmdline  string       `yaml:"cmdline"`
+	User     string       `yaml:"user"`
+	Cgroup   string       `yaml:"cgroup"`
+	All      []RuleConfig `yaml:"all"`
+	Any      []RuleConfig `yaml:"any"`
+	Exclude  []RuleConfig `yaml:"exclude"`
+}
+
+// Compile 校验配置并编译其中的正则，name 按子串匹配
+func (c RuleConfig) Compile() (Rule, error) {
+	return c.CompileWith(NameOptions{})
+}
+
+// CompileWith 与 Compile 相同，name 按 opts 匹配，name_mode 覆盖本条规则及其子规则的匹配方式
+func (c RuleConfig) CompileWith(opts NameOptions) (Rule, error) {
+	if c.NameMode != "" {
+		var err error
+		if opts.Mode, err = ParseNameMode(c.NameMode); err != nil {
+			return Rule{}, err
+		}
+	}
+	r := Rule{Exe: c.Exe, User: c.User, Cgroup: c.Cgroup}
+	if c.Name != "" {
+		name, err := NameRule(c.Name, opts)
+		if err != nil {
+			return Rule{}, err
+		}
+		r.Name, r.NameMode, r.Normalize, r.nameRE = name.Name, name.NameMode, name.Normalize, name.nameRE
+	}
+	if c.Cmdline != "" {
+		re, err := regexp.Compile(c.Cmdline)
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid cmdline: %w", err)
+		}
+		r.Cmdline = re
+	}
+	for i, sub := range c.All {
+		compiled, err := sub.CompileWith(opts)
+		if err != nil {
+			return Rule{}, fmt.Errorf("all[%d]: %w", i, err)
+		}
+		r.All = append(r.All, compiled)
+	}
+	for i, sub := range c.Any {
+		compiled, err := sub.CompileWith(opts)
+		if err != nil {
+			return Rule{}, fmt.Errorf("any[%d]: %w", i, err)
+		}
+		r.Any = append(r.Any, compiled)
+	}
+	if r.empty() {
+		return Rule{}, fmt.Errorf("rule has no conditions")
+	}
+	exclude, err := CompileRules(c.Exclude, opts)
+	if err != nil {
+		return Rule{}, fmt.Errorf("exclude%w", err)
+	}
+	r.Exclude = exclude
+	return r, nil
+}
+
+// CompileRules 按 CompileWith 编译一组规则，错误信息带有出错规则的下标
+func CompileRules(configs []RuleConfig, opts NameOptions) ([]Rule, error) {
+	var rules []Rule
+	for i, c := range configs {
+		rule, err := c.CompileWith(opts)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// ExcludeNames 由名称列表生成排除规则，进程名称或命令行包含其中任意一个值即排除
+// 例如 -names=python 时用 some-vendor-agent 排除 python3 /usr/bin/some-vendor-agent
+// 总是按子串匹配，opts.Mode 不起作用；opts.Normalize 与 -names.normalize 一致，名称按 NormalizeName 比较，命令行忽略大小写
+func ExcludeNames(names []string, opts NameOptions) []Rule {
+	var rules []Rule
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		cmdline := regexp.QuoteMeta(name)
+		if opts.Normalize {
+			cmdline = "(?i)" + regexp.QuoteMeta(NormalizeName(name))
+		}
+		rules = append(rules, Rule{Any: []Rule{
+			{Name: name, NameMode: Substring, Normalize: opts.Normalize},
+			{Cmdline: regexp.MustCompile(cmdline)},
+		}})
+	}
+	return rules
+}
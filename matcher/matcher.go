@@ -0,0 +1,297 @@
+// Package matcher 提供两个 exporter 共用的进程匹配规则
+//
+// 一条 Rule 可以按进程名称、可执行文件、命令行、用户和 cgroup 匹配，
+// 并通过 All/Any 组合成 AND/OR 表达式，Exclude 从匹配结果中排除进程。Matcher 保存一组命名规则，
+// 按优先级从高到低依次尝试，返回第一条匹配的规则名称。
+package matcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NameMode 进程名称的匹配方式
+type NameMode int
+
+const (
+	// Substring 进程名称包含 Rule.Name 即匹配，process-exporter 的默认行为
+	Substring NameMode = iota
+	// Exact 进程名称与 Rule.Name 完全相同才匹配，node-process 的默认行为
+	Exact
+	// Prefix 进程名称以 Rule.Name 开头即匹配
+	Prefix
+	// Regex Rule.Name 为正则，进程名称匹配即可，需要完整匹配时自行加上 ^ 和 $
+	// 正则在 NameRule 中编译，直接构造的 Rule 不匹配任何进程
+	Regex
+)
+
+// nameModes 配置和命令行参数中使用的匹配方式名称
+var nameModes = map[string]NameMode{
+	"substring": Substring,
+	"exact":     Exact,
+	"prefix":    Prefix,
+	"regex":     Regex,
+}
+
+// ParseNameMode 解析匹配方式名称：exact、prefix、substring 或 regex
+func ParseNameMode(s string) (NameMode, error) {
+	if mode, ok := nameModes[s]; ok {
+		return mode, nil
+	}
+	return 0, fmt.Errorf("unknown name match mode %q, must be exact, prefix, substring or regex", s)
+}
+
+func (m NameMode) String() string {
+	for name, mode := range nameModes {
+		if mode == m {
+			return name
+		}
+	}
+	return fmt.Sprintf("NameMode(%d)", int(m))
+}
+
+// NameOptions 进程名称的匹配方式，对应 Rule 的 NameMode 和 Normalize
+type NameOptions struct {
+	Mode      NameMode
+	Normalize bool
+}
+
+// NameRule 按进程名称匹配的规则，Regex 模式下编译 name 中的正则
+func NameRule(name string, opts NameOptions) (Rule, error) {
+	r := Rule{Name: name, NameMode: opts.Mode, Normalize: opts.Normalize}
+	if opts.Mode == Regex {
+		re, err := regexp.Compile(name)
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid name regex: %w", err)
+		}
+		r.nameRE = re
+	}
+	return r, nil
+}
+
+// Rule 一条匹配规则
+//
+// 同一条规则中设置的多个条件需要同时满足（AND）；All 中的子规则需要全部满足，
+// Any 中的子规则至少满足一条（OR）。其余条件都满足后再判断 Exclude，
+// 任意一条子规则匹配时整条规则不匹配。没有设置任何条件（不计 Exclude）的规则不匹配任何进程，
+// 避免配置写错时意外监控整台主机。
+type Rule struct {
+	// Name 进程名称，按 NameMode 匹配
+	Name     string
+	NameMode NameMode
+	// Normalize 比较名称前先转为小写并去掉 .exe 后缀，Linux 和 Windows 上可以使用同一份配置
+	// Regex 模式下只转换进程名称，正则本身不变
+	Normalize bool
+	// nameRE Regex 模式下编译后的 Name
+	nameRE *regexp.Regexp
+
+	// Exe 可执行文件的完整路径，不含 / 时只比较文件名
+	Exe string
+	// Cmdline 命令行（参数以空格连接）需要匹配的正则
+	Cmdline *regexp.Regexp
+	// User 进程所属的用户名
+	User string
+	// Cgroup 进程所在 cgroup 路径的前缀，任意一个 cgroup 匹配即可
+	Cgroup string
+
+	All []Rule
+	Any []Rule
+	// Exclude 包含条件匹配之后判断，任意一条匹配时排除该进程
+	Exclude []Rule
+}
+
+// empty 规则是否没有设置任何条件
+func (r Rule) empty() bool {
+	return r.Name == "" && r.Exe == "" && r.Cmdline == nil && r.User == "" && r.Cgroup == "" &&
+		len(r.All) == 0 && len(r.Any) == 0
+}
+
+// Match 判断进程是否满足规则，读取进程属性失败视为不匹配
+// 条件按读取开销从低到高判断，前面的条件不满足时不会读取后面的属性
+func (r Rule) Match(p Process) bool {
+	if r.empty() {
+		return false
+	}
+	if r.Name != "" {
+		name, err := p.Name()
+		if err != nil || !r.matchName(name) {
+			return false
+		}
+	}
+	if r.Exe != "" {
+		exe, err := p.Exe()
+		if err != nil {
+			return false
+		}
+		if strings.Contains(r.Exe, "/") {
+			if exe != r.Exe {
+				return false
+			}
+		} else if filepath.Base(exe) != r.Exe {
+			return false
+		}
+	}
+	if r.User != "" {
+		user, err := p.Username()
+		if err != nil || user != r.User {
+			return false
+		}
+	}
+	if r.Cgroup != "" {
+		cgroups, err := p.Cgroups()
+		if err != nil || !hasPrefix(cgroups, r.Cgroup) {
+			return false
+		}
+	}
+	if r.Cmdline != nil {
+		cmdline, err := p.Cmdline()
+		if err != nil || !r.Cmdline.MatchString(cmdline) {
+			return false
+		}
+	}
+	for _, sub := range r.All {
+		if !sub.Match(p) {
+			return false
+		}
+	}
+	if len(r.Any) > 0 {
+		matched := false
+		for _, sub := range r.Any {
+			if sub.Match(p) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, sub := range r.Exclude {
+		if sub.Match(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// String 规则的文本形式，例如 name~nginx(substring) user=www-data exclude(cmdline=~"debug")
+// 多个条件以空格连接（AND），any(...) 中以 | 连接（OR），用于检查配置时输出解析后的规则
+func (r Rule) String() string {
+	var parts []string
+	if r.Name != "" {
+		mode := r.NameMode.String()
+		if r.Normalize {
+			mode += ",normalize"
+		}
+		parts = append(parts, fmt.Sprintf("name~%s(%s)", r.Name, mode))
+	}
+	if r.Exe != "" {
+		parts = append(parts, "exe="+r.Exe)
+	}
+	if r.Cmdline != nil {
+		parts = append(parts, fmt.Sprintf("cmdline=~%q", r.Cmdline.String()))
+	}
+	if r.User != "" {
+		parts = append(parts, "user="+r.User)
+	}
+	if r.Cgroup != "" {
+		parts = append(parts, "cgroup="+r.Cgroup+"*")
+	}
+	for _, sub := range r.All {
+		parts = append(parts, "("+sub.String()+")")
+	}
+	if len(r.Any) > 0 {
+		parts = append(parts, "any("+joinRules(r.Any, " | ")+")")
+	}
+	if len(r.Exclude) > 0 {
+		parts = append(parts, "exclude("+joinRules(r.Exclude, " | ")+")")
+	}
+	if len(parts) == 0 {
+		return "<none>"
+	}
+	return strings.Join(parts, " ")
+}
+
+func joinRules(rules []Rule, sep string) string {
+	s := make([]string, len(rules))
+	for i, r := range rules {
+		s[i] = r.String()
+	}
+	return strings.Join(s, sep)
+}
+
+func (r Rule) matchName(name string) bool {
+	pattern := r.Name
+	if r.Normalize {
+		name, pattern = NormalizeName(name), NormalizeName(pattern)
+	}
+	switch r.NameMode {
+	case Exact:
+		return name == pattern
+	case Prefix:
+		return strings.HasPrefix(name, pattern)
+	case Regex:
+		return r.nameRE != nil && r.nameRE.MatchString(name)
+	default:
+		return strings.Contains(name, pattern)
+	}
+}
+
+func hasPrefix(paths []string, prefix string) bool {
+	for _, path := range paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeName 转为小写并去掉 .exe 后缀
+func NormalizeName(n string) string {
+	return strings.TrimSuffix(strings.ToLower(n), ".exe")
+}
+
+// Matcher 一组命名规则
+//
+// 优先级规则：Priority 大的规则先尝试，相同优先级按添加顺序，第一条匹配的规则胜出。
+// 因此一个进程最多属于一个分组。
+type Matcher struct {
+	entries []entry
+}
+
+type entry struct {
+	name     string
+	priority int
+	rule     Rule
+}
+
+// New 创建一个空的 Matcher
+func New() *Matcher {
+	return &Matcher{}
+}
+
+// Add 添加一条命名规则
+func (m *Matcher) Add(name string, priority int, rule Rule) {
+	m.entries = append(m.entries, entry{name: name, priority: priority, rule: rule})
+	sort.SliceStable(m.entries, func(i, j int) bool {
+		return m.entries[i].priority > m.entries[j].priority
+	})
+}
+
+// Len 返回规则数量
+func (m *Matcher) Len() int {
+	return len(m.entries)
+}
+
+// Match 返回进程匹配到的规则名称
+func (m *Matcher) Match(p Process) (string, bool) {
+	for _, e := range m.entries {
+		if e.rule.Match(p) {
+			return e.name, true
+		}
+	}
+	return "", false
+}
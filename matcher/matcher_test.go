@@ -0,0 +1,333 @@
+package matcher
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+// fakeProcess 测试用的进程，记录每个属性被读取的次数
+type fakeProcess struct {
+	name, exe, cmdline, user string
+	cgroups                  []string
+	err                      error
+	reads                    map[string]int
+}
+
+func (f *fakeProcess) read(field string) error {
+	if f.reads == nil {
+		f.reads = make(map[string]int)
+	}
+	f.reads[field]++
+	return f.err
+}
+
+func (f *fakeProcess) Name() (string, error)      { return f.name, f.read("name") }
+func (f *fakeProcess) Exe() (string, error)       { return f.exe, f.read("exe") }
+func (f *fakeProcess) Cmdline() (string, error)   { return f.cmdline, f.read("cmdline") }
+func (f *fakeProcess) Username() (string, error)  { return f.user, f.read("user") }
+func (f *fakeProcess) Cgroups() ([]string, error) { return f.cgroups, f.read("cgroups") }
+
+func nginx() *fakeProcess {
+	return &fakeProcess{
+		name:    "nginx",
+		exe:     "/usr/sbin/nginx",
+		cmdline: "nginx: worker process",
+		user:    "www-data",
+		cgroups: []string{"/system.slice/nginx.service"},
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{"empty rule matches nothing", Rule{}, false},
+		{"name substring", Rule{Name: "ngin"}, true},
+		{"name substring mismatch", Rule{Name: "mysql"}, false},
+		{"name exact", Rule{Name: "nginx", NameMode: Exact}, true},
+		{"name exact rejects substring", Rule{Name: "ngin", NameMode: Exact}, false},
+		{"name exact is case sensitive", Rule{Name: "NGINX", NameMode: Exact}, false},
+		{"name exact normalized", Rule{Name: "NGINX.exe", NameMode: Exact, Normalize: true}, true},
+		{"name prefix", Rule{Name: "ngi", NameMode: Prefix}, true},
+		{"name prefix rejects substring", Rule{Name: "gin", NameMode: Prefix}, false},
+		{"name prefix normalized", Rule{Name: "NGI", NameMode: Prefix, Normalize: true}, true},
+		{"name regex without NameRule matches nothing", Rule{Name: "nginx", NameMode: Regex}, false},
+		{"exe full path", Rule{Exe: "/usr/sbin/nginx"}, true},
+		{"exe full path mismatch", Rule{Exe: "/usr/local/sbin/nginx"}, false},
+		{"exe base name", Rule{Exe: "nginx"}, true},
+		{"cmdline regexp", Rule{Cmdline: regexp.MustCompile(`worker process$`)}, true},
+		{"cmdline regexp mismatch", Rule{Cmdline: regexp.MustCompile(`master`)}, false},
+		{"user", Rule{User: "www-data"}, true},
+		{"user mismatch", Rule{User: "root"}, false},
+		{"cgroup prefix", Rule{Cgroup: "/system.slice"}, true},
+		{"cgroup prefix mismatch", Rule{Cgroup: "/kubepods"}, false},
+		{"conditions are ANDed", Rule{Name: "nginx", User: "root"}, false},
+		{"all requires every sub rule", Rule{All: []Rule{{Name: "nginx"}, {User: "www-data"}}}, true},
+		{"all fails on one sub rule", Rule{All: []Rule{{Name: "nginx"}, {User: "root"}}}, false},
+		{"any requires one sub rule", Rule{Any: []Rule{{Name: "mysql"}, {User: "www-data"}}}, true},
+		{"any fails when none match", Rule{Any: []Rule{{Name: "mysql"}, {User: "root"}}}, false},
+		{"condition and any combined", Rule{Name: "nginx", Any: []Rule{{User: "root"}, {Cgroup: "/system.slice"}}}, true},
+		{"nested composition", Rule{Any: []Rule{{All: []Rule{{Name: "nginx"}, {Exe: "nginx"}}}, {Name: "mysql"}}}, true},
+		{"exclude rejects match", Rule{Name: "nginx", Exclude: []Rule{{Cmdline: regexp.MustCompile(`worker`)}}}, false},
+		{"exclude not matching keeps match", Rule{Name: "nginx", Exclude: []Rule{{User: "root"}}}, true},
+		{"exclude after any", Rule{Any: []Rule{{Name: "mysql"}, {User: "www-data"}}, Exclude: []Rule{{Name: "nginx"}}}, false},
+		{"exclude alone matches nothing", Rule{Exclude: []Rule{{Name: "mysql"}}}, false},
+		{"exclude names by cmdline", Rule{Name: "nginx", Exclude: ExcludeNames([]string{"worker process"}, NameOptions{})}, false},
+		{"exclude names by name", Rule{Name: "ngin", Exclude: ExcludeNames([]string{"nginx"}, NameOptions{})}, false},
+		{"exclude names skips empty values", Rule{Name: "nginx", Exclude: ExcludeNames([]string{"", " "}, NameOptions{})}, true},
+		{"exclude names is case sensitive", Rule{Name: "nginx", Exclude: ExcludeNames([]string{"NGINX"}, NameOptions{})}, true},
+		{"exclude names normalized by name", Rule{Name: "nginx", Exclude: ExcludeNames([]string{"NGINX.exe"}, NameOptions{Normalize: true})}, false},
+		{"exclude names normalized by cmdline", Rule{Name: "nginx", Exclude: ExcludeNames([]string{"Worker Process"}, NameOptions{Normalize: true})}, false},
+		{"exclude names ignore match mode", Rule{Name: "nginx", Exclude: ExcludeNames([]string{"gin"}, NameOptions{Mode: Exact})}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Match(nginx()); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		mode      NameMode
+		normalize bool
+		want      bool
+	}{
+		{"substring", "gin", Substring, false, true},
+		{"exact", "nginx", Exact, false, true},
+		{"prefix", "ngin", Prefix, false, true},
+		{"regex", "^ng.nx$", Regex, false, true},
+		{"regex unanchored", "gin", Regex, false, true},
+		{"regex mismatch", "^gin", Regex, false, false},
+		{"substring is case sensitive", "GIN", Substring, false, false},
+		{"substring normalized", "GIN", Substring, true, true},
+		{"exact normalized strips exe", "Nginx.EXE", Exact, true, true},
+		{"regex normalized matches lowercase name", "^nginx$", Regex, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := NameRule(tt.pattern, NameOptions{Mode: tt.mode, Normalize: tt.normalize})
+			if err != nil {
+				t.Fatalf("NameRule() error = %v", err)
+			}
+			if got := rule.Match(nginx()); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// 同一个 -names 值在 Windows 上也能匹配
+	windows := &fakeProcess{name: "Nginx.exe"}
+	for _, mode := range []NameMode{Substring, Exact, Prefix} {
+		rule, err := NameRule("nginx", NameOptions{Mode: mode, Normalize: true})
+		if err != nil {
+			t.Fatalf("NameRule() error = %v", err)
+		}
+		if !rule.Match(windows) {
+			t.Errorf("normalized %v rule does not match %q", mode, windows.name)
+		}
+	}
+	if _, err := NameRule("(", NameOptions{Mode: Regex}); err == nil {
+		t.Error("NameRule() error = nil for invalid regex, want error")
+	}
+}
+
+func TestParseNameMode(t *testing.T) {
+	for _, mode := range []NameMode{Substring, Exact, Prefix, Regex} {
+		got, err := ParseNameMode(mode.String())
+		if err != nil || got != mode {
+			t.Errorf("ParseNameMode(%q) = %v, %v, want %v", mode.String(), got, err, mode)
+		}
+	}
+	if _, err := ParseNameMode("glob"); err == nil {
+		t.Error("ParseNameMode(\"glob\") error = nil, want error")
+	}
+}
+
+func TestRuleMatchReadError(t *testing.T) {
+	p := nginx()
+	p.err = errors.New("permission denied")
+	if (Rule{Name: "nginx"}).Match(p) {
+		t.Error("Match() = true on read error, want false")
+	}
+}
+
+func TestRuleMatchShortCircuits(t *testing.T) {
+	p := nginx()
+	(Rule{Name: "mysql", Cmdline: regexp.MustCompile(`.`)}).Match(p)
+	if p.reads["cmdline"] != 0 {
+		t.Errorf("cmdline read %d times after name mismatch, want 0", p.reads["cmdline"])
+	}
+
+	p = nginx()
+	(Rule{Name: "mysql", Exclude: []Rule{{Cmdline: regexp.MustCompile(`.`)}}}).Match(p)
+	if p.reads["cmdline"] != 0 {
+		t.Errorf("cmdline read %d times for exclude after name mismatch, want 0", p.reads["cmdline"])
+	}
+}
+
+func TestMatcherPrecedence(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(m *Matcher)
+		want  string
+		found bool
+	}{
+		{
+			name:  "no rules",
+			setup: func(m *Matcher) {},
+			found: false,
+		},
+		{
+			name: "first added wins on equal priority",
+			setup: func(m *Matcher) {
+				m.Add("web", 0, Rule{Name: "ngin"})
+				m.Add("proxy", 0, Rule{Name: "nginx"})
+			},
+			want:  "web",
+			found: true,
+		},
+		{
+			name: "higher priority wins regardless of order",
+			setup: func(m *Matcher) {
+				m.Add("web", 0, Rule{Name: "ngin"})
+				m.Add("workers", 10, Rule{Name: "nginx", Cmdline: regexp.MustCompile(`worker`)})
+			},
+			want:  "workers",
+			found: true,
+		},
+		{
+			name: "falls back to lower priority when higher does not match",
+			setup: func(m *Matcher) {
+				m.Add("master", 10, Rule{Name: "nginx", Cmdline: regexp.MustCompile(`master`)})
+				m.Add("web", 0, Rule{Name: "nginx"})
+			},
+			want:  "web",
+			found: true,
+		},
+		{
+			name: "negative priority is tried last",
+			setup: func(m *Matcher) {
+				m.Add("catch-all", -1, Rule{Cgroup: "/"})
+				m.Add("web", 0, Rule{Name: "nginx"})
+			},
+			want:  "web",
+			found: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			tt.setup(m)
+			got, found := m.Match(nginx())
+			if got != tt.want || found != tt.found {
+				t.Errorf("Match() = %q, %v, want %q, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestRuleConfigCompile(t *testing.T) {
+	rule, err := RuleConfig{
+		Name: "nginx",
+		Any: []RuleConfig{
+			{Cmdline: "master"},
+			{User: "www-data"},
+		},
+	}.Compile()
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if !rule.Match(nginx()) {
+		t.Error("compiled rule does not match")
+	}
+
+	// 子规则继承 CompileWithMode 的匹配方式，name_mode 可以单独覆盖
+	rule, err = RuleConfig{Any: []RuleConfig{{Name: "ngin"}}}.CompileWith(NameOptions{Mode: Exact})
+	if err != nil {
+		t.Fatalf("CompileWith() error = %v", err)
+	}
+	if rule.Match(nginx()) {
+		t.Error("exact mode is not applied to sub rules")
+	}
+	rule, err = RuleConfig{Name: "^ngin", NameMode: "regex"}.CompileWith(NameOptions{Mode: Exact})
+	if err != nil {
+		t.Fatalf("CompileWith() error = %v", err)
+	}
+	if !rule.Match(nginx()) {
+		t.Error("name_mode does not override the default mode")
+	}
+
+	rule, err = RuleConfig{Name: "nginx", Exclude: []RuleConfig{{Cmdline: "worker"}}}.Compile()
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if rule.Match(nginx()) {
+		t.Error("compiled rule matches excluded process")
+	}
+
+	invalid := []struct {
+		name string
+		cfg  RuleConfig
+	}{
+		{"empty", RuleConfig{}},
+		{"bad regexp", RuleConfig{Cmdline: "("}},
+		{"empty sub rule", RuleConfig{Name: "nginx", All: []RuleConfig{{}}}},
+		{"bad nested regexp", RuleConfig{Any: []RuleConfig{{Cmdline: "["}}}},
+		{"empty exclude rule", RuleConfig{Name: "nginx", Exclude: []RuleConfig{{}}}},
+		{"bad exclude regexp", RuleConfig{Name: "nginx", Exclude: []RuleConfig{{Cmdline: "("}}}},
+		{"unknown name mode", RuleConfig{Name: "nginx", NameMode: "glob"}},
+		{"bad name regexp", RuleConfig{Name: "(", NameMode: "regex"}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.cfg.Compile(); err == nil {
+				t.Error("Compile() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := map[string]string{
+		"nginx":       "nginx",
+		"NGINX.EXE":   "nginx",
+		"svchost.exe": "svchost",
+		"a.exe.exe":   "a.exe",
+	}
+	for in, want := range tests {
+		if got := NormalizeName(in); got != want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRuleString(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{}, "<none>"},
+		{Rule{Name: "nginx"}, "name~nginx(substring)"},
+		{Rule{Name: "java", NameMode: Exact, Normalize: true, User: "app"}, "name~java(exact,normalize) user=app"},
+		{
+			Rule{
+				Exe:     "/usr/bin/python3",
+				Any:     []Rule{{Cmdline: regexp.MustCompile("celery")}, {Cgroup: "/system.slice"}},
+				Exclude: []Rule{{Name: "debug", NameMode: Prefix}},
+			},
+			`exe=/usr/bin/python3 any(cmdline=~"celery" | cgroup=/system.slice*) exclude(name~debug(prefix))`,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
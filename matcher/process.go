@@ -0,0 +1,64 @@
+package matcher
+
+import (
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// Process 匹配时需要读取的进程属性，只有规则用到的属性才会被读取
+type Process interface {
+	Name() (string, error)
+	Exe() (string, error)
+	Cmdline() (string, error)
+	Username() (string, error)
+	Cgroups() ([]string, error)
+}
+
+// FromProcess 将 gopsutil 的进程包装为 Process，每个属性最多读取一次
+// 同一个进程依次尝试多条规则时不会重复读取 /proc
+func FromProcess(p *process.Process) Process {
+	return &lazyProcess{p: p}
+}
+
+// FromProcessWithUsers 与 FromProcess 相同，用户名通过 users 缓存解析
+func FromProcessWithUsers(p *process.Process, users *UserCache) Process {
+	return &lazyProcess{p: p, users: users}
+}
+
+type lazyProcess struct {
+	p     *process.Process
+	users *UserCache
+
+	name, exe, cmdline, username lazy[string]
+	cgroups                      lazy[[]string]
+}
+
+// lazy 缓存一次读取的结果，包括错误
+type lazy[T any] struct {
+	done  bool
+	value T
+	err   error
+}
+
+func (l *lazy[T]) get(read func() (T, error)) (T, error) {
+	if !l.done {
+		l.value, l.err = read()
+		l.done = true
+	}
+	return l.value, l.err
+}
+
+func (lp *lazyProcess) Name() (string, error)    { return lp.name.get(lp.p.Name) }
+func (lp *lazyProcess) Exe() (string, error)     { return lp.exe.get(lp.p.Exe) }
+func (lp *lazyProcess) Cmdline() (string, error) { return lp.cmdline.get(lp.p.Cmdline) }
+func (lp *lazyProcess) Username() (string, error) {
+	return lp.username.get(func() (string, error) {
+		if lp.users == nil {
+			return lp.p.Username()
+		}
+		return lp.users.Username(lp.p)
+	})
+}
+
+func (lp *lazyProcess) Cgroups() ([]string, error) {
+	return lp.cgroups.get(func() ([]string, error) { return ReadCgroups(lp.p.Pid) })
+}
@@ -0,0 +1,91 @@
+package matcher
+
+import (
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// UserCache 缓存 UID 到用户名的解析结果，避免每个进程每次采集都查询 passwd（可能经过 NSS/LDAP）
+// 可以被多个采集器共用
+type UserCache struct {
+	ttl time.Duration
+	// lookup 查询用户，默认为 user.LookupId
+	lookup func(uid string) (*user.User, error)
+
+	mu    sync.Mutex
+	users map[uint32]cachedUser
+	// pending 正在查询的 UID，同一个 UID 的并发查询等待第一个查询的结果
+	pending map[uint32]*pendingUser
+}
+
+type cachedUser struct {
+	name    string
+	expires time.Time
+}
+
+type pendingUser struct {
+	done chan struct{}
+	name string
+}
+
+// NewUserCache 创建缓存，解析结果在 ttl 后过期重新查询
+func NewUserCache(ttl time.Duration) *UserCache {
+	return &UserCache{
+		ttl:     ttl,
+		lookup:  user.LookupId,
+		users:   make(map[uint32]cachedUser),
+		pending: make(map[uint32]*pendingUser),
+	}
+}
+
+// Lookup 返回 UID 对应的用户名，查不到时（例如容器内的 UID）使用数字本身，同样会被缓存
+// 查询 passwd 时不持有锁，NSS/LDAP 响应慢时不会阻塞其他已缓存 UID 的查询
+func (c *UserCache) Lookup(uid uint32) string {
+	now := time.Now()
+	c.mu.Lock()
+	if u, ok := c.users[uid]; ok && now.Before(u.expires) {
+		c.mu.Unlock()
+		return u.name
+	}
+	if p, ok := c.pending[uid]; ok {
+		c.mu.Unlock()
+		<-p.done
+		return p.name
+	}
+	p := &pendingUser{done: make(chan struct{})}
+	c.pending[uid] = p
+	c.mu.Unlock()
+
+	name := strconv.FormatUint(uint64(uid), 10)
+	if u, err := c.lookup(name); err == nil {
+		name = u.Username
+	}
+
+	c.mu.Lock()
+	p.name = name
+	delete(c.pending, uid)
+	close(p.done)
+	now = time.Now()
+	c.users[uid] = cachedUser{name: name, expires: now.Add(c.ttl)}
+	// 顺便清理过期的条目，UID 数量有限，不需要单独的清理协程
+	for uid, u := range c.users {
+		if !now.Before(u.expires) {
+			delete(c.users, uid)
+		}
+	}
+	c.mu.Unlock()
+	return name
+}
+
+// Username 返回进程的用户名（real UID），不支持读取 UID 的平台（Windows）直接调用 p.Username()
+func (c *UserCache) Username(p *process.Process) (string, error) {
+	uids, err := p.Uids()
+	if err != nil || len(uids) == 0 {
+		return p.Username()
+	}
+	return c.Lookup(uids[0]), nil
+}
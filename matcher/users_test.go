@@ -0,0 +1,70 @@
+package matcher
+
+import (
+	"errors"
+	"os/user"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUserCacheLookupUnlocked(t *testing.T) {
+	c := NewUserCache(time.Minute)
+	release := make(chan struct{})
+	var calls atomic.Int32
+	c.lookup = func(uid string) (*user.User, error) {
+		calls.Add(1)
+		switch uid {
+		case "1000":
+			<-release
+			return &user.User{Username: "alice"}, nil
+		case "0":
+			return &user.User{Username: "root"}, nil
+		}
+		return nil, errors.New("unknown user")
+	}
+	if got := c.Lookup(0); got != "root" {
+		t.Fatalf("Lookup(0) = %q, want root", got)
+	}
+
+	// 两个并发查询同一个慢 UID，只查询一次 passwd
+	var wg sync.WaitGroup
+	results := make([]string, 2)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.Lookup(1000)
+		}()
+	}
+
+	// 慢查询期间已缓存的 UID 和其他 UID 不被阻塞
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if got := c.Lookup(0); got != "root" {
+			t.Errorf("Lookup(0) = %q, want root", got)
+		}
+		if got := c.Lookup(4242); got != "4242" {
+			t.Errorf("Lookup(4242) = %q, want 4242", got)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lookup blocked by a slow lookup of another UID")
+	}
+
+	close(release)
+	wg.Wait()
+	for _, got := range results {
+		if got != "alice" {
+			t.Errorf("Lookup(1000) = %q, want alice", got)
+		}
+	}
+	// 0、1000、4242 各一次
+	if n := calls.Load(); n != 3 {
+		t.Errorf("lookups = %d, want 3", n)
+	}
+}
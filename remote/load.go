@@ -0,0 +1,29 @@
+package remote
+
+// Load 读取 SSH 和 WinRM 配置文件（为空的跳过）创建 Collector
+func Load(sshConfigFile, winrmConfigFile string) (*Collector, error) {
+	rc := NewCollector()
+	if sshConfigFile != "" {
+		sshConfig, err := LoadSSHConfig(sshConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range sshConfig.Hosts {
+			source, err := NewSSHSource(h)
+			if err != nil {
+				return nil, err
+			}
+			rc.Add(source, h.Names)
+		}
+	}
+	if winrmConfigFile != "" {
+		winrmConfig, err := LoadWinRMConfig(winrmConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range winrmConfig.Hosts {
+			rc.Add(NewWinRMSource(h), h.Names)
+		}
+	}
+	return rc, nil
+}
@@ -0,0 +1,140 @@
+// Package remote 通过 SSH 或 WinRM 读取远程主机的进程列表，适用于无法安装 exporter 的设备
+package remote
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"process-exporter/fullscan"
+	"process-exporter/matcher"
+)
+
+// remoteProcess 从远程主机读取到的一个进程，实现 matcher.Process 以便复用匹配规则
+type remoteProcess struct {
+	pid, user, name, cmdline string
+	cpu, mem                 float64
+}
+
+func (p remoteProcess) Name() (string, error)     { return p.name, nil }
+func (p remoteProcess) Exe() (string, error)      { return "", fmt.Errorf("exe is not available remotely") }
+func (p remoteProcess) Cmdline() (string, error)  { return p.cmdline, nil }
+func (p remoteProcess) Username() (string, error) { return p.user, nil }
+func (p remoteProcess) Cgroups() ([]string, error) {
+	return nil, fmt.Errorf("cgroups are not available remotely")
+}
+
+// Source 一台远程主机，每次抓取读取一次进程列表
+type Source interface {
+	// host 作为 host 标签的值
+	host() string
+	// method 采集方式，用于日志输出
+	method() string
+	processes() ([]remoteProcess, error)
+}
+
+// remoteTarget 一台远程主机及其进程名称过滤
+type remoteTarget struct {
+	source  Source
+	include *matcher.Matcher
+}
+
+// Collector 通过 SSH 或 WinRM 读取远程主机的进程列表，导出带 host 标签的 node_process 指标
+// 适用于无法安装 exporter 的设备，每次抓取都重新建立连接
+type Collector struct {
+	targets []remoteTarget
+
+	CPU    *prometheus.Desc
+	Memory *prometheus.Desc
+	Up     *prometheus.Desc
+
+	// cmd cmd 标签的改写规则，nil 时使用原始命令行
+	cmd *fullscan.CmdRewriter
+}
+
+// NewCollector 创建一个没有主机的 Collector，主机通过 Add 添加
+func NewCollector() *Collector {
+	labels := append(append([]string{}, fullscan.ProcessLabels...), "host")
+	return &Collector{
+		CPU: prometheus.NewDesc(
+			prometheus.BuildFQName(fullscan.Namespace, fullscan.Subsystem, "cpu_usage_percent"),
+			"Process CPU usage percentage. On SSH hosts this is ps pcpu, the CPU time divided by the time since the process started, a lifetime average rather than current usage. On WinRM hosts it is the current processor time divided by the number of logical CPUs.",
+			labels, nil,
+		),
+		Memory: prometheus.NewDesc(
+			prometheus.BuildFQName(fullscan.Namespace, fullscan.Subsystem, "memory_usage_percent"),
+			"Process memory usage percentage.",
+			labels, nil,
+		),
+		Up: prometheus.NewDesc(
+			prometheus.BuildFQName(fullscan.Namespace, fullscan.Subsystem, "remote_up"),
+			"Whether the last remote collection from the host succeeded (1) or not (0).",
+			[]string{"host"}, nil,
+		),
+	}
+}
+
+// Add 添加一台远程主机，names 为空时采集所有进程
+func (rc *Collector) Add(source Source, names []string) {
+	rc.targets = append(rc.targets, remoteTarget{source: source, include: fullscan.NewNameMatcher(names)})
+}
+
+// SetCmdRewriter 设置 cmd 标签的改写规则，需要在注册之前调用
+func (rc *Collector) SetCmdRewriter(r *fullscan.CmdRewriter) {
+	rc.cmd = r
+}
+
+func (rc *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- rc.CPU
+	ch <- rc.Memory
+	ch <- rc.Up
+}
+
+// Collect 并发采集所有远程主机
+func (rc *Collector) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+	for _, t := range rc.targets {
+		wg.Add(1)
+		go func(t remoteTarget) {
+			defer wg.Done()
+			up := 1.0
+			if err := rc.collectTarget(ch, t); err != nil {
+				slog.Warn("Failed to collect from remote host", "host", t.source.host(), "method", t.source.method(), "err", err)
+				up = 0
+			}
+			ch <- prometheus.MustNewConstMetric(rc.Up, prometheus.GaugeValue, up, t.source.host())
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (rc *Collector) collectTarget(ch chan<- prometheus.Metric, t remoteTarget) error {
+	procs, err := t.source.processes()
+	if err != nil {
+		return err
+	}
+	host := t.source.host()
+	for _, p := range procs {
+		if t.include != nil {
+			if _, ok := t.include.Match(p); !ok {
+				continue
+			}
+		}
+		labelValues := []string{labelValue(p.name), labelValue(p.pid), labelValue(rc.cmd.Rewrite(p.cmdline)), labelValue(p.user), host}
+		if p.cpu > 0 {
+			ch <- prometheus.MustNewConstMetric(rc.CPU, prometheus.GaugeValue, p.cpu, labelValues...)
+		}
+		if p.mem > 0 {
+			ch <- prometheus.MustNewConstMetric(rc.Memory, prometheus.GaugeValue, p.mem, labelValues...)
+		}
+	}
+	return nil
+}
+
+// labelValue 远程主机输出的进程名、用户和命令行可能不是合法的 UTF-8，替换为 U+FFFD 后才能作为标签值，否则 MustNewConstMetric 会 panic
+func labelValue(s string) string {
+	return strings.ToValidUTF8(s, "\uFFFD")
+}
@@ -0,0 +1,54 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// fakeSource 返回固定进程列表的远程主机
+type fakeSource struct {
+	procs []remoteProcess
+}
+
+func (f fakeSource) host() string                        { return "db-1" }
+func (f fakeSource) method() string                      { return "fake" }
+func (f fakeSource) processes() ([]remoteProcess, error) { return f.procs, nil }
+
+func TestCollectInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		name  string
+		proc  remoteProcess
+		label string
+		want  string
+	}{
+		{"name", remoteProcess{pid: "1", name: "app\xff", user: "root", cmdline: "app", cpu: 1}, "name", "app�"},
+		{"user", remoteProcess{pid: "1", name: "app", user: "r\xfeot", cmdline: "app", cpu: 1}, "user", "r�ot"},
+		{"args", remoteProcess{pid: "1", name: "app", user: "root", cmdline: "app --dir=/data/\xff", cpu: 1}, "cmd", "app --dir=/data/�"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := NewCollector()
+			rc.Add(fakeSource{procs: []remoteProcess{tt.proc}}, nil)
+			reg := prometheus.NewPedanticRegistry()
+			reg.MustRegister(rc)
+
+			families, err := reg.Gather()
+			if err != nil {
+				t.Fatalf("Gather() error = %v", err)
+			}
+			for _, mf := range families {
+				if mf.GetName() != "node_process_cpu_usage_percent" {
+					continue
+				}
+				for _, lp := range mf.Metric[0].Label {
+					if lp.GetName() == tt.label && lp.GetValue() != tt.want {
+						t.Errorf("%s = %q, want %q", tt.label, lp.GetValue(), tt.want)
+					}
+				}
+				return
+			}
+			t.Error("no node_process_cpu_usage_percent series")
+		})
+	}
+}
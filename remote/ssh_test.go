@@ -0,0 +1,65 @@
+package remote
+
+import (
+	"fmt"
+	"testing"
+)
+
+// psLine 按 remoteCommand 的列宽构造一行 ps 输出
+func psLine(pid, cpu, mem, user, comm, args string) string {
+	return fmt.Sprintf("%5s %4s %4s %-*s %-*s %s", pid, cpu, mem, psUserWidth, user, psCommWidth, comm, args)
+}
+
+func TestParsePSLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   remoteProcess
+		wantOK bool
+	}{
+		{
+			name:   "plain process",
+			line:   psLine("1234", "12.5", "0.3", "www-data", "nginx", "nginx: worker process"),
+			want:   remoteProcess{pid: "1234", cpu: 12.5, mem: 0.3, user: "www-data", name: "nginx", cmdline: "nginx: worker process"},
+			wantOK: true,
+		},
+		{
+			name:   "comm with spaces",
+			line:   psLine("42", "0.0", "0.1", "root", "tmux: server", "tmux new-session -d"),
+			want:   remoteProcess{pid: "42", cpu: 0, mem: 0.1, user: "root", name: "tmux: server", cmdline: "tmux new-session -d"},
+			wantOK: true,
+		},
+		{
+			name:   "kernel thread",
+			line:   psLine("7", "0.0", "0.0", "root", "kworker/0:1-events", "[kworker/0:1-events]"),
+			want:   remoteProcess{pid: "7", user: "root", name: "kworker/0:1-events", cmdline: "[kworker/0:1-events]"},
+			wantOK: true,
+		},
+		{
+			name:   "long user name",
+			line:   psLine("99999", "1.0", "2.0", "svc-postgres-replication-account", "postgres", "postgres: walsender"),
+			want:   remoteProcess{pid: "99999", cpu: 1, mem: 2, user: "svc-postgres-replication-account", name: "postgres", cmdline: "postgres: walsender"},
+			wantOK: true,
+		},
+		{
+			name:   "empty args",
+			line:   psLine("5", "0.0", "0.0", "root", "init", ""),
+			want:   remoteProcess{pid: "5", user: "root", name: "init"},
+			wantOK: true,
+		},
+		{name: "invalid cpu", line: psLine("5", "abc", "0.0", "root", "init", "init"), wantOK: false},
+		{name: "missing columns", line: "  5  0.0", wantOK: false},
+		{name: "empty line", line: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parsePSLine(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("parsePSLine(%q) ok = %v, want %v", tt.line, ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Errorf("parsePSLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
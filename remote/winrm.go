@@ -0,0 +1,334 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// winrmScript 在远程 Windows 主机上执行的只读 PowerShell 脚本，每行输出 "pid<TAB>cpu%<TAB>mem%<TAB>name"
+// 性能计数器的 PercentProcessorTime 是按单核计算的，除以逻辑 CPU 数与 node-process 的本地指标保持一致
+// -f 按远程主机当前的区域设置格式化数字（de-DE 下为 12,5），数值需要用 InvariantCulture 转成字符串
+const winrmScript = `$cs = Get-CimInstance Win32_ComputerSystem
+$inv = [cultureinfo]::InvariantCulture
+Get-CimInstance Win32_PerfFormattedData_PerfProc_Process |
+  Where-Object { $_.IDProcess -ne 0 } |
+  ForEach-Object { "{0}` + "`t" + `{1}` + "`t" + `{2}` + "`t" + `{3}" -f $_.IDProcess, ($_.PercentProcessorTime / $cs.NumberOfLogicalProcessors).ToString($inv), ($_.WorkingSet * 100 / $cs.TotalPhysicalMemory).ToString($inv), $_.Name }`
+
+// WinRMConfig 是 -winrm.config.file 指向的 YAML 文件结构
+type WinRMConfig struct {
+	Hosts []WinRMHost `yaml:"hosts"`
+}
+
+// WinRMHost 一台通过 WinRM 采集的远程 Windows 主机
+type WinRMHost struct {
+	// Address 主机名，或者完整的 WinRM 地址例如 https://host:5986/wsman
+	Address  string `yaml:"address"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	// InsecureSkipVerify 不校验 HTTPS 证书，WinRM 默认使用自签名证书时需要开启
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	// Names 需要采集的进程名称，与 -names 含义相同，为空时采集所有进程
+	Names   []string      `yaml:"names"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// LoadWinRMConfig 读取并校验 WinRM 采集配置
+func LoadWinRMConfig(path string) (*WinRMConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &WinRMConfig{}
+	if err := yaml.UnmarshalStrict(content, c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i, h := range c.Hosts {
+		if h.Address == "" || h.User == "" || h.Password == "" {
+			return nil, fmt.Errorf("%s: hosts[%d] needs address, user and password", path, i)
+		}
+		if !strings.Contains(h.Address, "://") {
+			c.Hosts[i].Address = "https://" + h.Address + ":5986/wsman"
+		}
+		if _, err := url.Parse(c.Hosts[i].Address); err != nil {
+			return nil, fmt.Errorf("%s: hosts[%d]: %w", path, i, err)
+		}
+		if h.Timeout == 0 {
+			c.Hosts[i].Timeout = 10 * time.Second
+		}
+	}
+	return c, nil
+}
+
+// winrmSource 通过 WinRM（WS-Management）远程执行 PowerShell 读取进程列表
+// 只实现了 Basic 认证，远程主机需要开启 Basic 认证并使用 HTTPS 监听
+type winrmSource struct {
+	endpoint string
+	hostname string
+	user     string
+	password string
+	client   *http.Client
+}
+
+// NewWinRMSource 根据配置创建 WinRM 采集源
+func NewWinRMSource(h WinRMHost) Source {
+	u, _ := url.Parse(h.Address)
+	return &winrmSource{
+		endpoint: h.Address,
+		hostname: u.Hostname(),
+		user:     h.User,
+		password: h.Password,
+		client: &http.Client{
+			Timeout: h.Timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: h.InsecureSkipVerify},
+			},
+		},
+	}
+}
+
+func (s *winrmSource) host() string   { return s.hostname }
+func (s *winrmSource) method() string { return "WinRM" }
+
+func (s *winrmSource) processes() ([]remoteProcess, error) {
+	output, err := s.runPowerShell(winrmScript)
+	if err != nil {
+		return nil, err
+	}
+
+	procs, skipped := parseWinRMOutput(output)
+	if skipped > 0 {
+		slog.Warn("Skipped unparsable rows from remote host", "host", s.hostname, "method", s.method(), "rows", skipped)
+		if len(procs) == 0 {
+			return nil, fmt.Errorf("none of the %d rows could be parsed", skipped)
+		}
+	}
+	return procs, nil
+}
+
+// parseWinRMOutput 解析 winrmScript 的输出，返回解析出的进程和无法解析的行数
+// 性能计数器中没有进程的用户，user 标签为空（即不输出）
+func parseWinRMOutput(output string) ([]remoteProcess, int) {
+	var procs []remoteProcess
+	var skipped int
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			skipped++
+			continue
+		}
+		cpu, err1 := strconv.ParseFloat(fields[1], 64)
+		mem, err2 := strconv.ParseFloat(fields[2], 64)
+		if err1 != nil || err2 != nil {
+			skipped++
+			continue
+		}
+		// 同名进程的计数器实例名为 name#1、name#2
+		name, _, _ := strings.Cut(fields[3], "#")
+		procs = append(procs, remoteProcess{pid: fields[0], cpu: cpu, mem: mem, name: name})
+	}
+	return procs, skipped
+}
+
+// runPowerShell 创建远程 shell，执行脚本并读取全部标准输出，最后删除 shell
+func (s *winrmSource) runPowerShell(script string) (string, error) {
+	resp, err := s.call(winrmActionCreate, "", `<rsp:Shell><rsp:InputStreams>stdin</rsp:InputStreams><rsp:OutputStreams>stdout stderr</rsp:OutputStreams></rsp:Shell>`)
+	if err != nil {
+		return "", fmt.Errorf("create shell: %w", err)
+	}
+	shellID := resp.selector("ShellId")
+	if shellID == "" {
+		shellID = resp.text("ShellId")
+	}
+	if shellID == "" {
+		return "", fmt.Errorf("create shell: no ShellId in response")
+	}
+	defer s.call(winrmActionDelete, shellID, "")
+
+	// -EncodedCommand 需要 UTF-16LE 编码后的 base64，避免处理命令行转义
+	u16 := utf16.Encode([]rune(script))
+	raw := make([]byte, 2*len(u16))
+	for i, c := range u16 {
+		binary.LittleEndian.PutUint16(raw[2*i:], c)
+	}
+	resp, err = s.call(winrmActionCommand, shellID, `<rsp:CommandLine><rsp:Command>powershell.exe</rsp:Command><rsp:Arguments>-NoProfile -NonInteractive -EncodedCommand `+
+		base64.StdEncoding.EncodeToString(raw)+`</rsp:Arguments></rsp:CommandLine>`)
+	if err != nil {
+		return "", fmt.Errorf("run command: %w", err)
+	}
+	commandID := resp.text("CommandId")
+	if commandID == "" {
+		return "", fmt.Errorf("run command: no CommandId in response")
+	}
+
+	var stdout, stderr bytes.Buffer
+	for {
+		resp, err = s.call(winrmActionReceive, shellID,
+			`<rsp:Receive><rsp:DesiredStream CommandId="`+html.EscapeString(commandID)+`">stdout stderr</rsp:DesiredStream></rsp:Receive>`)
+		if err != nil {
+			return "", fmt.Errorf("receive output: %w", err)
+		}
+		for _, st := range resp.streams {
+			data, err := base64.StdEncoding.DecodeString(st.data)
+			if err != nil {
+				continue
+			}
+			if st.name == "stderr" {
+				stderr.Write(data)
+			} else {
+				stdout.Write(data)
+			}
+		}
+		if resp.done {
+			break
+		}
+	}
+	if resp.exitCode != "" && resp.exitCode != "0" {
+		return "", fmt.Errorf("powershell exited with code %s: %s", resp.exitCode, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+const (
+	winrmActionCreate  = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Create"
+	winrmActionDelete  = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Delete"
+	winrmActionCommand = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Command"
+	winrmActionReceive = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Receive"
+	winrmResourceCmd   = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/cmd"
+	winrmStateDone     = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/CommandState/Done"
+)
+
+// call 发送一个 WS-Management 请求
+func (s *winrmSource) call(action, shellID, body string) (*winrmResponse, error) {
+	var id [16]byte
+	rand.Read(id[:])
+	messageID := fmt.Sprintf("uuid:%X-%X-%X-%X-%X", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+
+	var selector string
+	if shellID != "" {
+		selector = `<w:SelectorSet><w:Selector Name="ShellId">` + html.EscapeString(shellID) + `</w:Selector></w:SelectorSet>`
+	}
+	envelope := `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:w="http://schemas.dmtf.org/wbem/wsman/1/wsman.xsd" xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell">` +
+		`<env:Header>` +
+		`<a:To>` + html.EscapeString(s.endpoint) + `</a:To>` +
+		`<a:ReplyTo><a:Address env:mustUnderstand="true">http://schemas.xmlsoap.org/ws/2004/08/addressing/role/anonymous</a:Address></a:ReplyTo>` +
+		`<w:MaxEnvelopeSize env:mustUnderstand="true">153600</w:MaxEnvelopeSize>` +
+		`<a:MessageID>` + messageID + `</a:MessageID>` +
+		`<w:Locale xml:lang="en-US" env:mustUnderstand="false"/>` +
+		`<w:OperationTimeout>PT60S</w:OperationTimeout>` +
+		`<w:ResourceURI env:mustUnderstand="true">` + winrmResourceCmd + `</w:ResourceURI>` +
+		`<a:Action env:mustUnderstand="true">` + action + `</a:Action>` +
+		selector +
+		`</env:Header><env:Body>` + body + `</env:Body></env:Envelope>`
+
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, strings.NewReader(envelope))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml;charset=UTF-8")
+	req.SetBasicAuth(s.user, s.password)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(res.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return parseWinRMResponse(content)
+}
+
+// winrmResponse 从响应中提取的字段，只关心 shell 相关的少数元素
+type winrmResponse struct {
+	texts     map[string]string
+	selectors map[string]string
+	streams   []winrmStream
+	done      bool
+	exitCode  string
+}
+
+type winrmStream struct {
+	name string
+	data string
+}
+
+func (r *winrmResponse) text(name string) string     { return r.texts[name] }
+func (r *winrmResponse) selector(name string) string { return r.selectors[name] }
+
+// parseWinRMResponse 按元素的本地名称遍历响应，忽略命名空间前缀
+func parseWinRMResponse(content []byte) (*winrmResponse, error) {
+	r := &winrmResponse{texts: make(map[string]string), selectors: make(map[string]string)}
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	var stack []xml.StartElement
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t)
+			if t.Name.Local == "CommandState" && attr(t, "State") == winrmStateDone {
+				r.done = true
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) == 0 {
+				continue
+			}
+			el := stack[len(stack)-1]
+			value := string(t)
+			switch el.Name.Local {
+			case "Selector":
+				r.selectors[attr(el, "Name")] += value
+			case "Stream":
+				r.streams = append(r.streams, winrmStream{name: attr(el, "Name"), data: value})
+			case "ExitCode":
+				r.exitCode += strings.TrimSpace(value)
+			case "Text", "Reason":
+				r.texts["Fault"] += value
+			default:
+				r.texts[el.Name.Local] += value
+			}
+		}
+	}
+	return r, nil
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
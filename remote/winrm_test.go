@@ -0,0 +1,50 @@
+package remote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWinRMOutput(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		want        []remoteProcess
+		wantSkipped int
+	}{
+		{
+			name:   "invariant culture",
+			output: "1234\t12.5\t0.75\tsqlservr\r\n88\t0\t1.5\tsvchost#2\r\n",
+			want: []remoteProcess{
+				{pid: "1234", cpu: 12.5, mem: 0.75, name: "sqlservr"},
+				{pid: "88", cpu: 0, mem: 1.5, name: "svchost"},
+			},
+		},
+		{
+			name:        "decimal comma is counted as skipped",
+			output:      "1234\t12,5\t0,75\tsqlservr\r\n88\t0\t1.5\tsvchost\r\n",
+			want:        []remoteProcess{{pid: "88", cpu: 0, mem: 1.5, name: "svchost"}},
+			wantSkipped: 1,
+		},
+		{
+			name:        "wrong number of columns",
+			output:      "1234\t12.5\tsqlservr\r\n",
+			wantSkipped: 1,
+		},
+		{
+			name:   "blank lines ignored",
+			output: "\r\n\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped := parseWinRMOutput(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseWinRMOutput() = %+v, want %+v", got, tt.want)
+			}
+			if skipped != tt.wantSkipped {
+				t.Errorf("skipped = %d, want %d", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}